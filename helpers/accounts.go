@@ -0,0 +1,134 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+/*Account is the record of an account under its `balance~[accountID]` key, every library reads & writes it through
+GetAccountRecord & PutAccountRecord (GetBalanceState & PutBalanceState for the balance only).
+The balance is a JSON number, so CouchDB compares balances numerically,
+and the class is empty for the default token rather than left out, so the queries of a class can use an index*/
+type Account struct {
+	DocType      string            `json:"docType"`
+	Class        string            `json:"class"`
+	ID           string            `json:"account"`
+	MSP          string            `json:"msp"`
+	Balance      *big.Int          `json:"balance"`
+	ActivatedAt  int64             `json:"activatedAt"`
+	ActivatedBy  string            `json:"activatedBy"`
+	Frozen       bool              `json:"frozen"`
+	Nonce        uint64            `json:"nonce"` /*the number of changes of the balance*/
+	LastActivity int64             `json:"lastActivity"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+/*NewAccount returns the record of an account activated by `activatedBy` at the time of the transaction,
+it keeps the freeze of accounts frozen before their activation*/
+func NewAccount(stub shim.ChaincodeStubInterface, accountID string, activatedBy string) (*Account, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	frozen, err := getStateOf(stub, FrozenObjectType, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		ID:          accountID,
+		Balance:     big.NewInt(0),
+		ActivatedAt: txTimestamp.GetSeconds(),
+		ActivatedBy: activatedBy,
+		Frozen:      len(frozen) != 0,
+	}, nil
+}
+
+/*GetAccountRecord returns the record of an account, nil if the account is not registered.
+Balances stored as bare strings by earlier versions are returned as records holding the balance only*/
+func GetAccountRecord(stub shim.ChaincodeStubInterface, accountID string) (*Account, error) {
+	key, err := stub.CreateCompositeKey(BalanceObjectType, []string{accountID})
+	if err != nil {
+		return nil, err
+	}
+	value, err := stub.GetState(key)
+	if err != nil || len(value) == 0 {
+		return nil, err
+	}
+	return DecodeAccountRecord(accountID, value)
+}
+
+/*DecodeAccountRecord decodes a value of the `balance~[accountID]` key, e.g. from its history,
+balances stored as bare strings by earlier versions are returned as records holding the balance only*/
+func DecodeAccountRecord(accountID string, value []byte) (*Account, error) {
+	if !bytes.HasPrefix(value, documentPrefix) {
+		return &Account{DocType: AccountDocType, ID: accountID, MSP: mspOf(accountID), Balance: BufferToBigInt(value)}, nil
+	}
+	account := &Account{}
+	if err := json.Unmarshal(value, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+/*PutAccountRecord writes the record of an account*/
+func PutAccountRecord(stub shim.ChaincodeStubInterface, account *Account) error {
+	account.DocType, account.Class, account.MSP = AccountDocType, classOf(stub), mspOf(account.ID)
+	if account.Balance == nil {
+		account.Balance = big.NewInt(0)
+	}
+	key, err := stub.CreateCompositeKey(BalanceObjectType, []string{account.ID})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, MalshalJSON(account))
+}
+
+//putAccountBalance changes the balance of an account record, counting the change & the activity, and compacts the deltas of hot accounts,
+//accounts registered without a record (e.g. by a migration) get one
+func putAccountBalance(stub shim.ChaincodeStubInterface, accountID string, value []byte) error {
+	account, err := GetAccountRecord(stub, accountID)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		if account, err = NewAccount(stub, accountID, ""); err != nil {
+			return err
+		}
+	}
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	hot, err := IsHotAccount(stub, accountID)
+	if err != nil {
+		return err
+	}
+	if hot {
+		if _, err := foldDeltas(stub, account); err != nil {
+			return err
+		}
+	}
+	account.Balance = BufferToBigInt(value)
+	account.Nonce++
+	account.LastActivity = txTimestamp.GetSeconds()
+	return PutAccountRecord(stub, account)
+}
+
+/*PutAccountFrozen flags the record of a registered account as frozen or not*/
+func PutAccountFrozen(stub shim.ChaincodeStubInterface, accountID string, frozen bool) error {
+	account, err := GetAccountRecord(stub, accountID)
+	if err != nil || account == nil {
+		return err
+	}
+	account.Frozen = frozen
+	return PutAccountRecord(stub, account)
+}
+
+//mspOf returns the MSP ID an account ID starts with
+func mspOf(accountID string) string {
+	return strings.SplitN(accountID, ",", 2)[0]
+}
@@ -1,33 +1,121 @@
-package helpers
-
-import (
-	"github.com/hyperledger/fabric/core/chaincode/shim"
-	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
-)
-
-/*In Fabric, the cert's CN is the enrollmentID of user, so this can be used as a unique identifier within an MSP.
-Each MspID must be unique within the system, so the combination of MspID and CNs produces an unique identifier
-for very single identity that participates the Hyperledger blockchain system.
-
-https://hyperledger-fabric-ca.readthedocs.io/en/release-1.4/users-guide.html#cafiles*/
-
-/*GetCallerID returns the unique ID (inside ledger) of chaicode caller.
-
-The ID is a key of: `[mspID],[SubjectCN],[IssuerCN]`; this ID is used as "key" in the world-state database to identity a token owner.
-
-- `mspID`: the MspID of the calling org
-
-- `IssuerCN`: the common name from the calling identity's certificate CA provider
-
-- `SubjectCN`: the common name from the calling identity's x509 certificate*/
-func GetCallerID(stub shim.ChaincodeStubInterface) (string, error) {
-	callerCert, err := cid.GetX509Certificate(stub)
-	if err != nil {
-		return "", err
-	}
-	orgMspID, err := cid.GetMSPID(stub)
-	if err != nil {
-		return "", err
-	}
-	return orgMspID + "," + callerCert.Issuer.CommonName + "," + callerCert.Subject.CommonName, nil
-}
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
+)
+
+/*In Fabric, the cert's CN is the enrollmentID of user, so this can be used as a unique identifier within an MSP.
+Each MspID must be unique within the system, so the combination of MspID and CNs produces an unique identifier
+for very single identity that participates the Hyperledger blockchain system.
+
+https://hyperledger-fabric-ca.readthedocs.io/en/release-1.4/users-guide.html#cafiles*/
+
+//Idemix identities have no certificate, their account ID is derived from one of the attributes disclosed by the credential
+const (
+	//IdemixIDByEnrollmentID derives a per-user ID from the enrollment ID, the default
+	IdemixIDByEnrollmentID = "enrollmentID"
+	//IdemixIDByRevocationHandle derives a per-user ID from the revocation handle, which changes with a reissued credential
+	IdemixIDByRevocationHandle = "revocationHandle"
+	//IdemixIDByOU derives the ID from the organizational unit: every member of the OU shares one account & can spend its balance
+	IdemixIDByOU = "ou"
+)
+
+//IdemixIDSchemeKey is the configuration holding the Idemix attribute account IDs are derived from
+const IdemixIDSchemeKey = "idemixIDScheme"
+
+/*CheckIdemixIDScheme checks if `scheme` is one of the supported Idemix ID attributes*/
+func CheckIdemixIDScheme(scheme string) error {
+	switch scheme {
+	case IdemixIDByEnrollmentID, IdemixIDByRevocationHandle, IdemixIDByOU:
+		return nil
+	}
+	return fmt.Errorf("unknown Idemix ID scheme %v, expected one of: %v, %v, %v", scheme, IdemixIDByEnrollmentID, IdemixIDByRevocationHandle, IdemixIDByOU)
+}
+
+/*GetIdemixAttribute reads an attribute disclosed by the Idemix credential of the chaincode caller.
+The cid library of Fabric 1.4 only reads the `ou` & `role`, the per-user schemes need a reader of the enrollment ID
+or revocation handle disclosed by the credentials of the MSP, the callers disclosing neither are rejected*/
+var GetIdemixAttribute = cid.GetAttributeValue
+
+/*GetCallerID returns the unique ID (inside ledger) of chaicode caller.
+
+The ID is a key of: `[mspID],[SubjectCN],[IssuerCN]`; this ID is used as "key" in the world-state database to identity a token owner.
+
+- `mspID`: the MspID of the calling org
+
+- `IssuerCN`: the common name from the calling identity's certificate CA provider
+
+- `SubjectCN`: the common name from the calling identity's x509 certificate
+
+Idemix callers get the pseudonymous ID `[mspID],idemix:[scheme],[hex SHA-256 of the attribute]`, where `scheme` is the attribute
+configured under `IdemixIDSchemeKey` (`enrollmentID` by default)*/
+func GetCallerID(stub shim.ChaincodeStubInterface) (string, error) {
+	callerCert, err := cid.GetX509Certificate(stub)
+	if err != nil {
+		return "", err
+	}
+	orgMspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return "", err
+	}
+	if callerCert != nil {
+		return orgMspID + "," + callerCert.Issuer.CommonName + "," + callerCert.Subject.CommonName, nil
+	}
+
+	scheme, err := GetConfig(stub, IdemixIDSchemeKey)
+	if err != nil {
+		return "", err
+	}
+	if scheme == "" {
+		scheme = IdemixIDByEnrollmentID
+	}
+	value, found, err := GetIdemixAttribute(stub, scheme)
+	if err != nil {
+		return "", err
+	}
+	if !found || value == "" {
+		return "", fmt.Errorf("Idemix attribute %v is not disclosed by the caller's credential", scheme)
+	}
+	//the ledger only holds a pseudonym of the attribute
+	hash := sha256.Sum256([]byte(value))
+	return orgMspID + ",idemix:" + scheme + "," + hex.EncodeToString(hash[:]), nil
+}
+
+/*GetCallerNodeOUs returns the lower-cased organizational units of the chaincode caller.
+
+- x509 identities: the OUs of the certificate subject, where Fabric NodeOUs such as `client`, `admin` or `peer` are found
+
+- Idemix identities: the disclosed MSP role (`member`, `admin`, `client` or `peer`) and OU*/
+func GetCallerNodeOUs(stub shim.ChaincodeStubInterface) ([]string, error) {
+	callerCert, err := cid.GetX509Certificate(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	ous := []string{}
+	if callerCert != nil {
+		for _, ou := range callerCert.Subject.OrganizationalUnit {
+			ous = append(ous, strings.ToLower(ou))
+		}
+		return ous, nil
+	}
+
+	//Idemix identities have no certificate, only the attributes disclosed by the credential
+	if role, found, err := cid.GetAttributeValue(stub, "role"); err != nil {
+		return nil, err
+	} else if found && role != "" {
+		ous = append(ous, strings.ToLower(role))
+	}
+	if ou, found, err := cid.GetAttributeValue(stub, "ou"); err != nil {
+		return nil, err
+	} else if found && ou != "" {
+		ous = append(ous, strings.ToLower(ou))
+	}
+	return ous, nil
+}
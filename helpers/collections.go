@@ -0,0 +1,144 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//ConfidentialModeKey is the config entry enabling the confidential balances held in private data collections, "true" once enabled
+const ConfidentialModeKey = "confidential"
+
+//CollectionPrefix starts the names of the private data collections holding confidential balances
+const CollectionPrefix = "confidential"
+
+//ConfidentialCollectionObjectType is the objectType of the composite keys `ConfidentialCollection~[accountID]~[collection]`
+//indexing the collections an account has held a confidential balance in, checked before the account is closed or migrated
+const ConfidentialCollectionObjectType = "ConfidentialCollection"
+
+//SaltTransientKey is the transient map entry holding the random bytes the confidential balances written by a transaction are salted with
+const SaltTransientKey = "confidentialSalt"
+
+//MinSaltEntropy is the minimum number of random bytes of SaltTransientKey
+const MinSaltEntropy = 32
+
+/*PrivateBalance is the value of a confidential balance. Its hash is public, so it carries a random salt
+keeping the balance from being guessed by hashing every possible amount*/
+type PrivateBalance struct {
+	Balance *big.Int `json:"balance"`
+	Salt    string   `json:"salt"`
+}
+
+/*IsConfidentialMode tells if the confidential balances are enabled*/
+func IsConfidentialMode(stub shim.ChaincodeStubInterface) (bool, error) {
+	mode, err := GetConfig(stub, ConfidentialModeKey)
+	return mode == "true", err
+}
+
+/*CollectionOf returns the private data collection shared by the members of the MSPs:
+`confidential-[mspID]` for a single MSP, `confidential-[mspID1]-[mspID2]` (sorted) for a bilateral one*/
+func CollectionOf(mspIDs ...string) string {
+	unique := map[string]bool{}
+	names := []string{CollectionPrefix}
+	for _, mspID := range mspIDs {
+		if !unique[mspID] {
+			unique[mspID] = true
+			names = append(names, mspID)
+		}
+	}
+	sort.Strings(names[1:])
+	return strings.Join(names, "-")
+}
+
+/*GetPrivateBalanceState returns the raw confidential balance of an account in a collection, empty if it has none*/
+func GetPrivateBalanceState(stub shim.ChaincodeStubInterface, collection string, accountID string) ([]byte, error) {
+	key, err := stub.CreateCompositeKey(BalanceObjectType, []string{accountID})
+	if err != nil {
+		return nil, err
+	}
+	return stub.GetPrivateData(collection, key)
+}
+
+/*DecodePrivateBalance returns the balance of a PrivateBalance value, 0 if it is empty*/
+func DecodePrivateBalance(value []byte) (*big.Int, error) {
+	if len(value) == 0 {
+		return big.NewInt(0), nil
+	}
+	privateBalance := PrivateBalance{}
+	if err := json.Unmarshal(value, &privateBalance); err != nil {
+		return nil, err
+	}
+	return privateBalance.Balance, nil
+}
+
+/*PutPrivateBalanceState writes the confidential balance of an account in a collection as a PrivateBalance, only its hash is written to the ledger.
+Every endorsing peer must write the same value, so the salt is derived from the secret random bytes passed by the client
+in the SaltTransientKey transient entry, the transaction ID & the key*/
+func PutPrivateBalanceState(stub shim.ChaincodeStubInterface, collection string, accountID string, balance *big.Int) error {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return err
+	}
+	entropy := transient[SaltTransientKey]
+	if len(entropy) < MinSaltEntropy {
+		return fmt.Errorf("confidential balances need at least %v random bytes in the %v transient entry", MinSaltEntropy, SaltTransientKey)
+	}
+	key, err := stub.CreateCompositeKey(BalanceObjectType, []string{accountID})
+	if err != nil {
+		return err
+	}
+	salt := sha256.Sum256(append(append(append([]byte{}, entropy...), stub.GetTxID()...), key...))
+	value, err := json.Marshal(PrivateBalance{Balance: balance, Salt: hex.EncodeToString(salt[:])})
+	if err != nil {
+		return err
+	}
+	if err := stub.PutPrivateData(collection, key, value); err != nil {
+		return err
+	}
+	indexKey, err := stub.CreateCompositeKey(ConfidentialCollectionObjectType, []string{accountID, collection})
+	if err != nil {
+		return err
+	}
+	//the value is not used, but an empty value would delete the key
+	return stub.PutState(indexKey, []byte{0x00})
+}
+
+/*CheckNoConfidentialBalance returns an error if an account holds a confidential balance in any of the collections it has used,
+or if the endorsing peer can't read one of them, the account can't be closed nor migrated before unshielding it*/
+func CheckNoConfidentialBalance(stub shim.ChaincodeStubInterface, accountID string) error {
+	iterator, err := stub.GetStateByPartialCompositeKey(ConfidentialCollectionObjectType, []string{accountID})
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		_, attributes, err := stub.SplitCompositeKey(queryResult.GetKey())
+		if err != nil {
+			return err
+		}
+		collection := attributes[1]
+		value, err := GetPrivateBalanceState(stub, collection, accountID)
+		if err != nil {
+			return fmt.Errorf("can not check the confidential balance of %v in %v: %v", accountID, collection, err)
+		}
+		balance, err := DecodePrivateBalance(value)
+		if err != nil {
+			return err
+		}
+		if balance.Sign() != 0 {
+			return fmt.Errorf("%v holds a confidential balance in %v, it must be unshielded first", accountID, collection)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//objectTypes of the composite keys `Hot~[accountID]` marking the hot accounts,
+//and `Delta~[accountID]~[txID]` holding a credit of a hot account not compacted into its record yet
+const (
+	HotObjectType   = "Hot"
+	DeltaObjectType = "Delta"
+)
+
+/*IsHotAccount tells if an account is credited with delta keys instead of read-modify-writes of its balance*/
+func IsHotAccount(stub shim.ChaincodeStubInterface, accountID string) (bool, error) {
+	hot, err := getStateOf(stub, HotObjectType, accountID)
+	return len(hot) != 0, err
+}
+
+/*PutHotAccount marks an account as hot or not, the deltas of an account must be compacted before it stops being hot*/
+func PutHotAccount(stub shim.ChaincodeStubInterface, accountID string, hot bool) error {
+	if !hot {
+		return delStateOf(stub, HotObjectType, accountID)
+	}
+	return putStateOf(stub, HotObjectType, []byte("true"), accountID)
+}
+
+/*GetCreditedBalance returns the balance of an account about to be credited with `getBalanceOf`,
+nil for hot accounts, which are credited without reading their balance so concurrent credits don't conflict*/
+func GetCreditedBalance(stub shim.ChaincodeStubInterface, accountID string, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) (*big.Int, error) {
+	hot, err := IsHotAccount(stub, accountID)
+	if err != nil || hot {
+		return nil, err
+	}
+	return getBalanceOf(stub, []string{accountID})
+}
+
+/*CreditBalance adds `amount` to the balance of an account read with GetCreditedBalance,
+hot accounts (nil `balance`) get a `Delta~[accountID]~[txID]` key instead, so an account can be credited once per transaction.
+It returns the change to pass to UpdateHolderCount, none for hot accounts as they count as holders while they are hot*/
+func CreditBalance(stub shim.ChaincodeStubInterface, accountID string, balance *big.Int, amount *big.Int) (BalanceChange, error) {
+	if balance == nil {
+		return BalanceChange{}, putStateOf(stub, DeltaObjectType, []byte(amount.String()), accountID, stub.GetTxID())
+	}
+	newBalance := Add(balance, amount)
+	return BalanceChange{Before: balance, After: newBalance}, PutBalanceState(stub, accountID, []byte(newBalance.String()))
+}
+
+/*DebitBalance subtracts `amount` from the `balance` of an account, the deltas of hot accounts are compacted into the new balance.
+Reading the balance of a hot account reads its deltas, so a debit conflicts with the concurrent credits instead of overdrawing the account.
+It returns the change to pass to UpdateHolderCount, none for hot accounts*/
+func DebitBalance(stub shim.ChaincodeStubInterface, accountID string, balance *big.Int, amount *big.Int) (BalanceChange, error) {
+	hot, err := IsHotAccount(stub, accountID)
+	if err != nil {
+		return BalanceChange{}, err
+	}
+	newBalance := Sub(balance, amount)
+	if err := PutBalanceState(stub, accountID, []byte(newBalance.String())); err != nil || hot {
+		return BalanceChange{}, err
+	}
+	return BalanceChange{Before: balance, After: newBalance}, nil
+}
+
+/*CompactDeltas folds the deltas of an account into its record and returns its balance*/
+func CompactDeltas(stub shim.ChaincodeStubInterface, accountID string) (*big.Int, error) {
+	account, err := GetAccountRecord(stub, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%v is not registered", accountID)
+	}
+	credits, err := foldDeltas(stub, account)
+	if err != nil || credits == nil {
+		return account.Balance, err
+	}
+	account.Balance = Add(account.Balance, credits)
+	return account.Balance, PutAccountRecord(stub, account)
+}
+
+//getPendingCredits returns the sum & the keys of the deltas of an account, a nil sum if it has none
+func getPendingCredits(stub shim.ChaincodeStubInterface, accountID string) (*big.Int, []string, error) {
+	iterator, err := stub.GetStateByPartialCompositeKey(DeltaObjectType, []string{accountID})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iterator.Close()
+
+	var credits *big.Int
+	keys := []string{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if credits == nil {
+			credits = big.NewInt(0)
+		}
+		credits = Add(credits, BufferToBigInt(queryResult.GetValue()))
+		keys = append(keys, queryResult.GetKey())
+	}
+	return credits, keys, nil
+}
+
+//foldDeltas deletes the deltas of an account, counting them in its nonce, and returns their sum, nil if it has none
+func foldDeltas(stub shim.ChaincodeStubInterface, account *Account) (*big.Int, error) {
+	credits, keys, err := getPendingCredits(stub, account.ID)
+	if err != nil || credits == nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if err := stub.DelState(key); err != nil {
+			return nil, err
+		}
+	}
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	account.Nonce += uint64(len(keys))
+	account.LastActivity = txTimestamp.GetSeconds()
+	return credits, nil
+}
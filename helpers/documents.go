@@ -0,0 +1,173 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//StateModeKey is the config entry holding how allowances, memos & config are stored:
+//RawStateMode (plain strings, by default) or JSONStateMode (typed JSON documents CouchDB can query), accounts are Account records either way
+const StateModeKey = "stateMode"
+
+//state modes of `StateModeKey`
+const (
+	RawStateMode  = "raw"
+	JSONStateMode = "json"
+)
+
+//docTypes of the JSON state documents
+const (
+	AccountDocType   = "account"
+	AllowanceDocType = "allowance"
+	MemoDocType      = "memo"
+	ConfigDocType    = "config"
+)
+
+//documentPrefix starts every JSON state document, `docType` being their first field
+var documentPrefix = []byte(`{"docType":`)
+
+/*CheckStateMode checks if `mode` is one of the supported state modes*/
+func CheckStateMode(mode string) error {
+	switch mode {
+	case RawStateMode, JSONStateMode:
+		return nil
+	}
+	return fmt.Errorf("unknown state mode %v, expected one of: %v, %v", mode, RawStateMode, JSONStateMode)
+}
+
+/*IsJSONStateMode tells if the values are stored as JSON documents*/
+func IsJSONStateMode(stub shim.ChaincodeStubInterface) (bool, error) {
+	mode, err := GetConfig(stub, StateModeKey)
+	return mode == JSONStateMode, err
+}
+
+/*ClassScoped is implemented by the stubs scoping the state to a token class, the documents record the class they belong to*/
+type ClassScoped interface {
+	ClassID() string
+}
+
+/*AllowanceDocument is the JSON document of an allowance*/
+type AllowanceDocument struct {
+	DocType string   `json:"docType"`
+	Class   string   `json:"class,omitempty"`
+	Owner   string   `json:"owner"`
+	Spender string   `json:"spender"`
+	Amount  *big.Int `json:"amount"`
+}
+
+/*MemoDocument is the JSON document of the last memo received by an account*/
+type MemoDocument struct {
+	DocType string `json:"docType"`
+	Class   string `json:"class,omitempty"`
+	Account string `json:"account"`
+	Memo    string `json:"memo"`
+}
+
+/*ConfigDocument is the JSON document of a token attribute or configuration*/
+type ConfigDocument struct {
+	DocType string `json:"docType"`
+	Class   string `json:"class,omitempty"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+}
+
+/*DecodeDocument returns the raw value of an `objectType` key: the balance of the Account records,
+and the value of the JSON state documents when `isJSON` (see IsJSONStateMode), other values are returned as they are.
+The values of the raw state mode are never decoded, a memo may look like a document.*/
+func DecodeDocument(objectType string, value []byte, isJSON bool) []byte {
+	if !bytes.HasPrefix(value, documentPrefix) || (!isJSON && objectType != BalanceObjectType) {
+		return value
+	}
+	document := struct {
+		DocType string   `json:"docType"`
+		Balance *big.Int `json:"balance"`
+		Amount  *big.Int `json:"amount"`
+		Memo    string   `json:"memo"`
+		Value   string   `json:"value"`
+	}{}
+	if err := json.Unmarshal(value, &document); err != nil {
+		return value
+	}
+	switch document.DocType {
+	case AccountDocType:
+		return []byte(document.Balance.String())
+	case AllowanceDocType:
+		return []byte(document.Amount.String())
+	case MemoDocType:
+		return []byte(document.Memo)
+	case ConfigDocType:
+		return []byte(document.Value)
+	}
+	return value
+}
+
+//encodeDocument returns the JSON state document of the raw `value` of `objectType~[attributes]` in JSONStateMode,
+//the raw value otherwise (accounts are always stored as Account records)
+func encodeDocument(stub shim.ChaincodeStubInterface, objectType string, attributes []string, value []byte) ([]byte, error) {
+	isJSON, err := IsJSONStateMode(stub)
+	if err != nil || !isJSON {
+		return value, err
+	}
+	return encodeDocumentOf(classOf(stub), objectType, attributes, value)
+}
+
+//encodeDocumentOf returns the JSON state document of the raw `value` of `objectType~[attributes]` in `class`
+func encodeDocumentOf(class string, objectType string, attributes []string, value []byte) ([]byte, error) {
+	switch objectType {
+	case AllowanceObjectType:
+		return json.Marshal(AllowanceDocument{DocType: AllowanceDocType, Class: class, Owner: attributes[0], Spender: attributes[1], Amount: BufferToBigInt(value)})
+	case MemoObjectType:
+		return json.Marshal(MemoDocument{DocType: MemoDocType, Class: class, Account: attributes[0], Memo: string(value)})
+	case ConfigObjectType:
+		return json.Marshal(ConfigDocument{DocType: ConfigDocType, Class: class, Name: attributes[0], Value: string(value)})
+	}
+	return value, nil
+}
+
+/*ConvertDocuments rewrites the values of the `objectType` keys of the state scoped by `stub` in the state `mode`,
+as plain strings for RawStateMode or JSON documents for JSONStateMode*/
+func ConvertDocuments(stub shim.ChaincodeStubInterface, objectType string, mode string) error {
+	iterator, err := stub.GetStateByPartialCompositeKey(objectType, []string{})
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		if err := ConvertDocument(stub, queryResult.Key, queryResult.Value, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*ConvertDocument rewrites the `value` of the composite key `key`, stored in the state mode being left, in the state `mode`*/
+func ConvertDocument(stub shim.ChaincodeStubInterface, key string, value []byte, mode string) error {
+	objectType, attributes, err := stub.SplitCompositeKey(key)
+	if err != nil {
+		return err
+	}
+	value = DecodeDocument(objectType, value, mode != JSONStateMode)
+	if mode == JSONStateMode {
+		if value, err = encodeDocumentOf(classOf(stub), objectType, attributes, value); err != nil {
+			return err
+		}
+	}
+	return stub.PutState(key, value)
+}
+
+//classOf returns the token class the state of `stub` is scoped to, empty for the default token
+func classOf(stub shim.ChaincodeStubInterface) string {
+	if scoped, ok := stub.(ClassScoped); ok {
+		return scoped.ClassID()
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//HolderCountKey is the config entry counting the accounts with a positive balance, up to the last compaction
+const HolderCountKey = "holderCount"
+
+//objectType of the composite keys `HolderCountDelta~[txID]` holding the change of the holder count by a transaction,
+//so concurrent transactions don't conflict on HolderCountKey
+const HolderCountDeltaObjectType = "HolderCountDelta"
+
+/*BalanceChange is the balance of an account before & after a transaction, a nil balance counts as zero*/
+type BalanceChange struct {
+	Before *big.Int
+	After  *big.Int
+}
+
+/*GetHolderCount returns the number of accounts with a positive balance: the compacted count plus the deltas written since*/
+func GetHolderCount(stub shim.ChaincodeStubInterface) (int64, error) {
+	count, _, err := getHolderCount(stub)
+	return count, err
+}
+
+/*UpdateHolderCount records the change of the holder count by the accounts whose balance became or stopped being positive,
+in a `HolderCountDelta~[txID]` key. A transaction must pass all its changes in a single call.*/
+func UpdateHolderCount(stub shim.ChaincodeStubInterface, changes ...BalanceChange) error {
+	var delta int64
+	for _, change := range changes {
+		wasHolder, isHolder := change.Before != nil && change.Before.Sign() > 0, change.After != nil && change.After.Sign() > 0
+		if !wasHolder && isHolder {
+			delta++
+		} else if wasHolder && !isHolder {
+			delta--
+		}
+	}
+	if delta == 0 {
+		return nil
+	}
+	return putStateOf(stub, HolderCountDeltaObjectType, []byte(strconv.FormatInt(delta, 10)), stub.GetTxID())
+}
+
+/*CompactHolderCount folds the holder count deltas into HolderCountKey and returns the count.
+It conflicts with the transfers committed meanwhile, it's best run when the token is quiet.*/
+func CompactHolderCount(stub shim.ChaincodeStubInterface) (int64, error) {
+	count, keys, err := getHolderCount(stub)
+	if err != nil || len(keys) == 0 {
+		return count, err
+	}
+	return count, putHolderCount(stub, count, keys)
+}
+
+/*ResetHolderCount sets the holder count to `count`, dropping the deltas written so far*/
+func ResetHolderCount(stub shim.ChaincodeStubInterface, count int64) error {
+	_, keys, err := getHolderCount(stub)
+	if err != nil {
+		return err
+	}
+	return putHolderCount(stub, count, keys)
+}
+
+//putHolderCount writes `count` to HolderCountKey and deletes the delta `keys` it accounts for
+func putHolderCount(stub shim.ChaincodeStubInterface, count int64, keys []string) error {
+	for _, key := range keys {
+		if err := stub.DelState(key); err != nil {
+			return err
+		}
+	}
+	return PutConfigState(stub, HolderCountKey, []byte(strconv.FormatInt(count, 10)))
+}
+
+//getHolderCount returns the holder count and the keys of its deltas
+func getHolderCount(stub shim.ChaincodeStubInterface) (int64, []string, error) {
+	value, err := GetConfigState(stub, HolderCountKey)
+	if err != nil {
+		return 0, nil, err
+	}
+	var count int64
+	if len(value) != 0 {
+		if count, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	iterator, err := stub.GetStateByPartialCompositeKey(HolderCountDeltaObjectType, []string{})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer iterator.Close()
+
+	keys := []string{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return 0, nil, err
+		}
+		delta, err := strconv.ParseInt(string(queryResult.GetValue()), 10, 64)
+		if err != nil {
+			return 0, nil, err
+		}
+		count += delta
+		keys = append(keys, queryResult.GetKey())
+	}
+	return count, keys, nil
+}
@@ -0,0 +1,175 @@
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
+	"golang.org/x/crypto/sha3"
+)
+
+//IDSchemeKey is the world-state key holding the account ID scheme picked at `Init`
+const IDSchemeKey = "idScheme"
+
+//account ID schemes, every scheme keeps the `[mspID],` prefix of the account IDs
+const (
+	//LegacyIDScheme is `[mspID],[IssuerCN],[SubjectCN]`, see GetCallerID
+	LegacyIDScheme = "legacy"
+	//EscapedIDScheme is the legacy ID with `\` and `,` escaped inside each part, so it can be split unambiguously
+	EscapedIDScheme = "escaped"
+	//PublicKeyHashIDScheme is `[mspID],[hex SHA-256 of the DER public key]`, it survives a certificate reissued by another CA
+	PublicKeyHashIDScheme = "pubkeyHash"
+	//AddressIDScheme is `[mspID],0x[last 20 bytes of the Keccak-256 of the public key]`, as Ethereum addresses
+	AddressIDScheme = "address"
+)
+
+/*IdentityResolver derives the account ID of the chaincode caller.
+
+Idemix callers have no public key, every resolver gives them the pseudonymous ID of GetCallerID*/
+type IdentityResolver interface {
+	ResolveID(stub shim.ChaincodeStubInterface) (string, error)
+}
+
+var identityResolvers = map[string]IdentityResolver{
+	LegacyIDScheme:        legacyResolver{},
+	EscapedIDScheme:       escapedResolver{},
+	PublicKeyHashIDScheme: publicKeyHashResolver{},
+	AddressIDScheme:       addressResolver{},
+}
+
+/*GetIdentityResolver returns the resolver of the `scheme` account ID scheme*/
+func GetIdentityResolver(scheme string) (IdentityResolver, error) {
+	resolver, ok := identityResolvers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown ID scheme %v, expected one of: %v, %v, %v, %v", scheme, LegacyIDScheme, EscapedIDScheme, PublicKeyHashIDScheme, AddressIDScheme)
+	}
+	return resolver, nil
+}
+
+/*ResolveCallerID returns the account ID of the chaincode caller, using the ID scheme picked at `Init` (`legacy` by default)*/
+func ResolveCallerID(stub shim.ChaincodeStubInterface) (string, error) {
+	scheme, err := GetConfig(stub, IDSchemeKey)
+	if err != nil {
+		return "", err
+	}
+	if scheme == "" {
+		scheme = LegacyIDScheme
+	}
+	resolver, err := GetIdentityResolver(scheme)
+	if err != nil {
+		return "", err
+	}
+	return resolver.ResolveID(stub)
+}
+
+type legacyResolver struct{}
+
+func (legacyResolver) ResolveID(stub shim.ChaincodeStubInterface) (string, error) {
+	return GetCallerID(stub)
+}
+
+var idPartEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`)
+
+type escapedResolver struct{}
+
+func (escapedResolver) ResolveID(stub shim.ChaincodeStubInterface) (string, error) {
+	callerCert, err := cid.GetX509Certificate(stub)
+	if err != nil || callerCert == nil {
+		return GetCallerID(stub)
+	}
+	orgMspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return "", err
+	}
+	return idPartEscaper.Replace(orgMspID) + "," + idPartEscaper.Replace(callerCert.Issuer.CommonName) + "," + idPartEscaper.Replace(callerCert.Subject.CommonName), nil
+}
+
+type publicKeyHashResolver struct{}
+
+func (publicKeyHashResolver) ResolveID(stub shim.ChaincodeStubInterface) (string, error) {
+	callerCert, err := cid.GetX509Certificate(stub)
+	if err != nil || callerCert == nil {
+		return GetCallerID(stub)
+	}
+	orgMspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKIXPublicKey(callerCert.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(der)
+	return orgMspID + "," + hex.EncodeToString(hash[:]), nil
+}
+
+type addressResolver struct{}
+
+func (addressResolver) ResolveID(stub shim.ChaincodeStubInterface) (string, error) {
+	callerCert, err := cid.GetX509Certificate(stub)
+	if err != nil || callerCert == nil {
+		return GetCallerID(stub)
+	}
+	orgMspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return "", err
+	}
+	publicKey, ok := callerCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("the %v ID scheme requires an ECDSA public key", AddressIDScheme)
+	}
+	//uncompressed point without its 0x04 prefix
+	point := elliptic.Marshal(publicKey.Curve, publicKey.X, publicKey.Y)[1:]
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(point)
+	return orgMspID + ",0x" + hex.EncodeToString(hash.Sum(nil)[12:]), nil
+}
+
+/*Configured is implemented by the stubs carrying the configuration picked by the running transaction,
+as Fabric doesn't let a transaction read its own uncommitted writes*/
+type Configured interface {
+	Config(key string) (string, bool)
+}
+
+type configuredStub struct {
+	shim.ChaincodeStubInterface
+	configs map[string]string
+}
+
+func (s *configuredStub) Config(key string) (string, bool) {
+	value, ok := s.configs[key]
+	return value, ok
+}
+
+/*WithConfigs returns `stub` with the `configs` visible to `GetConfig`, Init passes the configuration it writes
+this way to the libraries it calls*/
+func WithConfigs(stub shim.ChaincodeStubInterface, configs map[string]string) shim.ChaincodeStubInterface {
+	return &configuredStub{ChaincodeStubInterface: stub, configs: configs}
+}
+
+/*GetConfig returns the configuration value of `key`, the one carried by a `Configured` stub first*/
+func GetConfig(stub shim.ChaincodeStubInterface, key string) (string, error) {
+	if configured, ok := stub.(Configured); ok {
+		if value, ok := configured.Config(key); ok {
+			return value, nil
+		}
+	}
+	valueBytes, err := GetConfigState(stub, key)
+	if err != nil {
+		return "", err
+	}
+	//ledgers written before the keys were namespaced keep it under the bare key until their upgrade converts it
+	if len(valueBytes) == 0 {
+		valueBytes, err = stub.GetState(key)
+		if err != nil {
+			return "", err
+		}
+	}
+	return string(valueBytes), nil
+}
@@ -0,0 +1,169 @@
+package helpers
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//objectTypes of the composite keys `balance~[accountID]`, `allowance~[ownerID]~[spenderID]` & `config~[name]`,
+//so accounts can't collide with each other or with token attributes whatever their IDs
+const (
+	BalanceObjectType   = "balance"
+	AllowanceObjectType = "allowance"
+	ConfigObjectType    = "config"
+)
+
+//objectType of the composite key `AllowanceSpender~[spenderID]~[ownerID]`,
+//indexing the `allowance~[ownerID]~[spenderID]` keys by spender
+const AllowanceSpenderObjectType = "AllowanceSpender"
+
+//objectTypes of the composite keys `Memo~[accountID]` holding the last memo received by an account,
+//and `Frozen~[accountID]` marking the accounts frozen by erc20freezable
+const (
+	MemoObjectType   = "Memo"
+	FrozenObjectType = "Frozen"
+)
+
+/*GetBalanceState returns the raw balance of an account, empty if the account is not registered,
+the balance of hot accounts includes their deltas*/
+func GetBalanceState(stub shim.ChaincodeStubInterface, accountID string) ([]byte, error) {
+	balance, err := getStateOf(stub, BalanceObjectType, accountID)
+	if err != nil || len(balance) == 0 {
+		return balance, err
+	}
+	hot, err := IsHotAccount(stub, accountID)
+	if err != nil || !hot {
+		return balance, err
+	}
+	credits, _, err := getPendingCredits(stub, accountID)
+	if err != nil || credits == nil {
+		return balance, err
+	}
+	return []byte(Add(BufferToBigInt(balance), credits).String()), nil
+}
+
+/*PutBalanceState writes the raw balance of an account to its Account record,
+the deltas of hot accounts are compacted as the balance is read with them by GetBalanceState*/
+func PutBalanceState(stub shim.ChaincodeStubInterface, accountID string, value []byte) error {
+	return putAccountBalance(stub, accountID, value)
+}
+
+/*DelBalanceState removes the Account record of an account, unregistering it, hot accounts can't be unregistered*/
+func DelBalanceState(stub shim.ChaincodeStubInterface, accountID string) error {
+	hot, err := IsHotAccount(stub, accountID)
+	if err != nil {
+		return err
+	}
+	if hot {
+		return fmt.Errorf("%v is a hot account", accountID)
+	}
+	return delStateOf(stub, BalanceObjectType, accountID)
+}
+
+/*GetAllowanceState returns the raw amount `spenderID` may spend from `ownerID`*/
+func GetAllowanceState(stub shim.ChaincodeStubInterface, ownerID string, spenderID string) ([]byte, error) {
+	return getStateOf(stub, AllowanceObjectType, ownerID, spenderID)
+}
+
+/*PutAllowanceState writes the raw amount `spenderID` may spend from `ownerID`*/
+func PutAllowanceState(stub shim.ChaincodeStubInterface, ownerID string, spenderID string, value []byte) error {
+	return putStateOf(stub, AllowanceObjectType, value, ownerID, spenderID)
+}
+
+/*DelAllowanceState removes the allowance of `spenderID` from `ownerID`*/
+func DelAllowanceState(stub shim.ChaincodeStubInterface, ownerID string, spenderID string) error {
+	return delStateOf(stub, AllowanceObjectType, ownerID, spenderID)
+}
+
+/*PutAllowance writes & indexes the amount `spenderID` may spend from `ownerID`,
+or removes it when `amount` is zero so the allowance lists only hold actual approvals*/
+func PutAllowance(stub shim.ChaincodeStubInterface, ownerID string, spenderID string, amount *big.Int) error {
+	if amount.Sign() == 0 {
+		return DelAllowance(stub, ownerID, spenderID)
+	}
+	if err := PutAllowanceState(stub, ownerID, spenderID, []byte(amount.String())); err != nil {
+		return err
+	}
+	spenderKey, err := stub.CreateCompositeKey(AllowanceSpenderObjectType, []string{spenderID, ownerID})
+	if err != nil {
+		return err
+	}
+	//the value is not used, but an empty value would delete the key
+	return stub.PutState(spenderKey, []byte{0x00})
+}
+
+/*DelAllowance removes the allowance of `spenderID` from `ownerID` and its spender index*/
+func DelAllowance(stub shim.ChaincodeStubInterface, ownerID string, spenderID string) error {
+	if err := DelAllowanceState(stub, ownerID, spenderID); err != nil {
+		return err
+	}
+	spenderKey, err := stub.CreateCompositeKey(AllowanceSpenderObjectType, []string{spenderID, ownerID})
+	if err != nil {
+		return err
+	}
+	return stub.DelState(spenderKey)
+}
+
+/*GetConfigState returns a token attribute or configuration, e.g. `owner`, `decimals` or `totalSupply`*/
+func GetConfigState(stub shim.ChaincodeStubInterface, name string) ([]byte, error) {
+	return getStateOf(stub, ConfigObjectType, name)
+}
+
+/*PutConfigState writes a token attribute or configuration*/
+func PutConfigState(stub shim.ChaincodeStubInterface, name string, value []byte) error {
+	return putStateOf(stub, ConfigObjectType, value, name)
+}
+
+/*DelConfigState removes a token attribute or configuration*/
+func DelConfigState(stub shim.ChaincodeStubInterface, name string) error {
+	return delStateOf(stub, ConfigObjectType, name)
+}
+
+/*PutMemoState writes the last memo received by an account*/
+func PutMemoState(stub shim.ChaincodeStubInterface, accountID string, memo string) error {
+	return putStateOf(stub, MemoObjectType, []byte(memo), accountID)
+}
+
+/*DelMemoState removes the memo of an account*/
+func DelMemoState(stub shim.ChaincodeStubInterface, accountID string) error {
+	return delStateOf(stub, MemoObjectType, accountID)
+}
+
+func getStateOf(stub shim.ChaincodeStubInterface, objectType string, attributes ...string) ([]byte, error) {
+	key, err := stub.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	value, err := stub.GetState(key)
+	if err != nil || objectType == BalanceObjectType {
+		return DecodeDocument(objectType, value, false), err
+	}
+	//the state mode is read before the mode is known, its value can't be mistaken for a document
+	if objectType == ConfigObjectType && attributes[0] == StateModeKey {
+		return DecodeDocument(objectType, value, true), nil
+	}
+	isJSON, err := IsJSONStateMode(stub)
+	return DecodeDocument(objectType, value, isJSON), err
+}
+
+func putStateOf(stub shim.ChaincodeStubInterface, objectType string, value []byte, attributes ...string) error {
+	key, err := stub.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return err
+	}
+	document, err := encodeDocument(stub, objectType, attributes, value)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, document)
+}
+
+func delStateOf(stub shim.ChaincodeStubInterface, objectType string, attributes ...string) error {
+	key, err := stub.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return err
+	}
+	return stub.DelState(key)
+}
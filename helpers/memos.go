@@ -0,0 +1,274 @@
+package helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//objectType of the composite key `MemoKey~[accountID]` holding the public key (PKIX DER) the memos to an account are encrypted for
+const MemoKeyObjectType = "MemoKey"
+
+//EncryptedMemoPrefix starts the memos encrypted by EncryptMemo, followed by the base64 ciphertext
+const EncryptedMemoPrefix = "ecies:"
+
+//transient map entries of the memos encrypted by the chaincode: the plaintext, and at least MinMemoEntropy secret random bytes
+const (
+	MemoTransientKey        = "memo"
+	MemoEntropyTransientKey = "memoEntropy"
+)
+
+//objectTypes of the memo history: `MemoLog~[accountID]~[timestamp]~[txID]` holds the MemoRecord of a transfer for both its sender & receiver,
+//`MemoReference~[reference]~[receiverID]~[timestamp]~[txID]` indexes the records by their reference
+const (
+	MemoLogObjectType       = "MemoLog"
+	MemoReferenceObjectType = "MemoReference"
+)
+
+/*MemoRecord is the memo attached to a transfer, kept in the memo history of its sender & receiver.
+`Memo` is the ciphertext of the memos encrypted for the receiver, `Reference` is always public*/
+type MemoRecord struct {
+	TxID      string   `json:"txID"`
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Amount    *big.Int `json:"amount"`
+	Memo      string   `json:"memo"`
+	Reference string   `json:"reference,omitempty"` /*a structured reference for reconciliation, e.g. an invoice number*/
+	Timestamp int64    `json:"timestamp"`
+}
+
+/*NewMemoRecord returns the MemoRecord of the transfer of the transaction*/
+func NewMemoRecord(stub shim.ChaincodeStubInterface, from string, to string, amount *big.Int, memo string, reference string) (*MemoRecord, error) {
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	return &MemoRecord{TxID: stub.GetTxID(), From: from, To: to, Amount: amount, Memo: memo, Reference: reference, Timestamp: txTimestamp.GetSeconds()}, nil
+}
+
+/*PutMemoRecord writes a MemoRecord to the memo history of its sender & receiver, and to the reference index if it has a reference*/
+func PutMemoRecord(stub shim.ChaincodeStubInterface, record *MemoRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	timestamp := memoTimestamp(record.Timestamp)
+	for _, accountID := range []string{record.From, record.To} {
+		if err := putStateOf(stub, MemoLogObjectType, value, accountID, timestamp, record.TxID); err != nil {
+			return err
+		}
+	}
+	if record.Reference == "" {
+		return nil
+	}
+	return putStateOf(stub, MemoReferenceObjectType, []byte{0x00}, record.Reference, record.To, timestamp, record.TxID)
+}
+
+/*GetMemoRecordState returns the MemoRecord of a transaction in the memo history of an account, nil if there is none*/
+func GetMemoRecordState(stub shim.ChaincodeStubInterface, accountID string, timestamp string, txID string) (*MemoRecord, error) {
+	value, err := getStateOf(stub, MemoLogObjectType, accountID, timestamp, txID)
+	if err != nil || len(value) == 0 {
+		return nil, err
+	}
+	return UnmarshalMemoRecord(value)
+}
+
+/*GetMemoRecordOfTx returns the MemoRecord of the transaction `txID` at `timestamp` in the memo history of an account, nil if there is none*/
+func GetMemoRecordOfTx(stub shim.ChaincodeStubInterface, accountID string, timestamp int64, txID string) (*MemoRecord, error) {
+	return GetMemoRecordState(stub, accountID, memoTimestamp(timestamp), txID)
+}
+
+/*UnmarshalMemoRecord decodes a MemoRecord of the memo history*/
+func UnmarshalMemoRecord(value []byte) (*MemoRecord, error) {
+	record := &MemoRecord{}
+	if err := json.Unmarshal(value, record); err != nil {
+		return nil, fmt.Errorf("invalid memo record: %v", err)
+	}
+	return record, nil
+}
+
+/*MoveMemoHistory moves the memo history of `oldID` to `newID`, so the new ID finds the memos it sent & received under the old one.
+The records keep the accounts of the time, the reference index points the memos received by `oldID` to the history of `newID`*/
+func MoveMemoHistory(stub shim.ChaincodeStubInterface, oldID string, newID string) error {
+	iterator, err := stub.GetStateByPartialCompositeKey(MemoLogObjectType, []string{oldID})
+	if err != nil {
+		return err
+	}
+	//the records are read before they are moved, the iterator doesn't see the writes of the transaction
+	records := []*MemoRecord{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			iterator.Close()
+			return err
+		}
+		record, err := UnmarshalMemoRecord(kv.GetValue())
+		if err != nil {
+			iterator.Close()
+			return err
+		}
+		records = append(records, record)
+	}
+	iterator.Close()
+
+	for _, record := range records {
+		value, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		timestamp := memoTimestamp(record.Timestamp)
+		if err := delStateOf(stub, MemoLogObjectType, oldID, timestamp, record.TxID); err != nil {
+			return err
+		}
+		if err := putStateOf(stub, MemoLogObjectType, value, newID, timestamp, record.TxID); err != nil {
+			return err
+		}
+		if record.Reference == "" || record.To != oldID {
+			continue
+		}
+		if err := delStateOf(stub, MemoReferenceObjectType, record.Reference, oldID, timestamp, record.TxID); err != nil {
+			return err
+		}
+		if err := putStateOf(stub, MemoReferenceObjectType, []byte{0x00}, record.Reference, newID, timestamp, record.TxID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//memoTimestamp zero-pads the seconds of a memo, so the memo history keys are sorted chronologically
+func memoTimestamp(seconds int64) string {
+	return fmt.Sprintf("%019d", seconds)
+}
+
+//MinMemoEntropy is the minimum number of random bytes the ephemeral key of an encrypted memo is derived from
+const MinMemoEntropy = 32
+
+/*GetMemoKeyState returns the public key (PKIX DER) the memos to an account are encrypted for, empty if it registered none*/
+func GetMemoKeyState(stub shim.ChaincodeStubInterface, accountID string) ([]byte, error) {
+	return getStateOf(stub, MemoKeyObjectType, accountID)
+}
+
+/*PutMemoKeyState writes the public key (PKIX DER) the memos to an account are encrypted for*/
+func PutMemoKeyState(stub shim.ChaincodeStubInterface, accountID string, der []byte) error {
+	return putStateOf(stub, MemoKeyObjectType, der, accountID)
+}
+
+/*DelMemoKeyState removes the public key the memos to an account are encrypted for*/
+func DelMemoKeyState(stub shim.ChaincodeStubInterface, accountID string) error {
+	return delStateOf(stub, MemoKeyObjectType, accountID)
+}
+
+/*EncryptMemoFor encrypts a memo for the public key registered by an account.
+Every endorsing peer must produce the same ciphertext, so the ephemeral key is derived from the secret `entropy`
+passed by the client in the transient map, and the transaction ID so that reused entropy doesn't reuse a key*/
+func EncryptMemoFor(stub shim.ChaincodeStubInterface, accountID string, plaintext []byte, entropy []byte) (string, error) {
+	if len(entropy) < MinMemoEntropy {
+		return "", fmt.Errorf("encrypted memos need at least %v random bytes in the %v transient entry", MinMemoEntropy, MemoEntropyTransientKey)
+	}
+	der, err := GetMemoKeyState(stub, accountID)
+	if err != nil {
+		return "", err
+	}
+	if len(der) == 0 {
+		return "", fmt.Errorf("%v has not registered a memo key", accountID)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return "", err
+	}
+	ecdsaKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("the memo key of %v is not an ECDSA key", accountID)
+	}
+	return EncryptMemo(ecdsaKey, plaintext, append(append([]byte{}, entropy...), stub.GetTxID()...))
+}
+
+/*EncryptMemo encrypts a memo for an ECDSA public key (ECIES: ECDH with an ephemeral key derived from `entropy`,
+ANSI X9.63 SHA-256 key derivation & AES-256-GCM) and returns it prefixed with EncryptedMemoPrefix.
+`entropy` must be secret & random, anyone knowing it can decrypt the memo*/
+func EncryptMemo(publicKey *ecdsa.PublicKey, plaintext []byte, entropy []byte) (string, error) {
+	curve := publicKey.Curve
+	n := new(big.Int).Sub(curve.Params().N, big.NewInt(1))
+	ephemeral := new(big.Int).SetBytes(deriveKey(entropy, []byte("ephemeral"), curve.Params().BitSize/8+8))
+	ephemeral.Mod(ephemeral, n).Add(ephemeral, big.NewInt(1))
+
+	ephemeralX, ephemeralY := curve.ScalarBaseMult(ephemeral.Bytes())
+	sharedX, _ := curve.ScalarMult(publicKey.X, publicKey.Y, ephemeral.Bytes())
+	ephemeralPoint := elliptic.Marshal(curve, ephemeralX, ephemeralY)
+
+	aead, err := memoCipher(curve, sharedX)
+	if err != nil {
+		return "", err
+	}
+	//each key encrypts a single memo, so the nonce can be fixed
+	ciphertext := aead.Seal(ephemeralPoint, make([]byte, aead.NonceSize()), plaintext, ephemeralPoint)
+	return EncryptedMemoPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+/*DecryptMemo decrypts a memo returned by GetMemo with the private key of the recipient's enrollment certificate*/
+func DecryptMemo(privateKey *ecdsa.PrivateKey, memo string) ([]byte, error) {
+	if !strings.HasPrefix(memo, EncryptedMemoPrefix) {
+		return nil, fmt.Errorf("the memo is not encrypted")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(memo, EncryptedMemoPrefix))
+	if err != nil {
+		return nil, err
+	}
+	curve := privateKey.Curve
+	pointLength := 1 + 2*((curve.Params().BitSize+7)/8)
+	if len(ciphertext) < pointLength {
+		return nil, fmt.Errorf("invalid encrypted memo")
+	}
+	ephemeralPoint := ciphertext[:pointLength]
+	ephemeralX, ephemeralY := elliptic.Unmarshal(curve, ephemeralPoint)
+	if ephemeralX == nil {
+		return nil, fmt.Errorf("invalid encrypted memo")
+	}
+	sharedX, _ := curve.ScalarMult(ephemeralX, ephemeralY, privateKey.D.Bytes())
+
+	aead, err := memoCipher(curve, sharedX)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext[pointLength:], ephemeralPoint)
+}
+
+//memoCipher returns the AES-256-GCM cipher keyed by the shared secret of a memo
+func memoCipher(curve elliptic.Curve, sharedX *big.Int) (cipher.AEAD, error) {
+	shared := make([]byte, (curve.Params().BitSize+7)/8)
+	sharedBytes := sharedX.Bytes()
+	copy(shared[len(shared)-len(sharedBytes):], sharedBytes)
+
+	block, err := aes.NewCipher(deriveKey(shared, []byte("erc20 memo"), 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+//deriveKey is the ANSI X9.63 key derivation function with SHA-256
+func deriveKey(secret []byte, sharedInfo []byte, length int) []byte {
+	key := []byte{}
+	counter := make([]byte, 4)
+	for i := uint32(1); len(key) < length; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		hash := sha256.New()
+		hash.Write(secret)
+		hash.Write(counter)
+		hash.Write(sharedInfo)
+		key = hash.Sum(key)
+	}
+	return key[:length]
+}
@@ -0,0 +1,63 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//DefaultPageSize & MaxPageSize bound the number of entries returned by a page of a paginated query
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+/*ParsePageSize returns the page size of a paginated query, DefaultPageSize if `sPageSize` is empty*/
+func ParsePageSize(sPageSize string) (int, error) {
+	if sPageSize == "" {
+		return DefaultPageSize, nil
+	}
+	pageSize, err := strconv.Atoi(sPageSize)
+	if err != nil || pageSize <= 0 || pageSize > MaxPageSize {
+		return 0, fmt.Errorf("the page size should be between 1 and %v", MaxPageSize)
+	}
+	return pageSize, nil
+}
+
+/*QueryPage iterates the page of `pageSize` composite keys `objectType~[attributes]~...` starting at `bookmark`,
+passing `visit` their attributes & raw values (of JSON state documents), and returns the bookmark of the next page, empty after the last key.
+The `*WithPagination` queries of Fabric are only allowed in read-only transactions, so the paginated methods must be queried, not invoked.
+`visit` may leave keys out, so a page can hold fewer entries than `pageSize` while more pages follow.*/
+func QueryPage(stub shim.ChaincodeStubInterface,
+	objectType string,
+	attributes []string,
+	pageSize int,
+	bookmark string,
+	visit func(keyAttributes []string, value []byte) error,
+) (string, error) {
+	isJSON, err := IsJSONStateMode(stub)
+	if err != nil {
+		return "", err
+	}
+	iterator, metadata, err := stub.GetStateByPartialCompositeKeyWithPagination(objectType, attributes, int32(pageSize), bookmark)
+	if err != nil {
+		return "", err
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return "", err
+		}
+		_, keyAttributes, err := stub.SplitCompositeKey(queryResult.Key)
+		if err != nil {
+			return "", err
+		}
+		if err := visit(keyAttributes, DecodeDocument(objectType, queryResult.Value, isJSON)); err != nil {
+			return "", err
+		}
+	}
+	return metadata.GetBookmark(), nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math/big"
 	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
 )
 
 /*CheckArgsLength compares length of string with `expectedLength`*/
@@ -23,9 +25,9 @@ func CheckGreaterThanZero(value string) error {
 	return err
 }
 
-/*CheckBalance checks if sender's balance is > 0*/
+/*CheckBalance checks if sender's balance is > 0, the nil balances of the hot accounts credited without reading them pass*/
 func CheckBalance(balance *big.Int, mspID string) error {
-	if balance.Cmp(big.NewInt(0)) == -1 {
+	if balance != nil && balance.Cmp(big.NewInt(0)) == -1 {
 		return fmt.Errorf("Balance of sender %v is %v", mspID, balance)
 	}
 	return nil
@@ -62,3 +64,25 @@ func CheckCallerIsOwner(caller string, owner string) error {
 	}
 	return nil
 }
+
+/*CheckCallerHasRole returns an error if the caller is not a member of `role`*/
+func CheckCallerHasRole(hasRole bool, caller string, role string) error {
+	if !hasRole {
+		return fmt.Errorf("Function only accessible to members of role %v, %v is not one of them", role, caller)
+	}
+	return nil
+}
+
+/*CheckCallerIsMember returns the ID of the chaincode caller, and an error if it is not a member of `role`*/
+func CheckCallerIsMember(stub shim.ChaincodeStubInterface, role string, hasRole func(shim.ChaincodeStubInterface, []string) (bool, error)) (string, error) {
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return "", err
+	}
+
+	isMember, err := hasRole(stub, []string{role, callerID})
+	if err != nil {
+		return "", err
+	}
+	return callerID, CheckCallerHasRole(isMember, callerID, role)
+}
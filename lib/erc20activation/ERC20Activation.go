@@ -0,0 +1,484 @@
+package erc20activation
+
+import (
+	"encoding/json"
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"erc20/lib/erc20roles"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("activation-logger")
+
+/*enums for activation policies*/
+const (
+	OPEN     = "open"     /*anyone activates any account*/
+	SELF     = "self"     /*callers only activate their own account*/
+	APPROVAL = "approval" /*callers request the activation of their own account, the owner or a REGISTRAR approves it*/
+)
+
+/*enums for activation request status*/
+const (
+	PENDING  = "pending"
+	APPROVED = "approved"
+	REJECTED = "rejected"
+)
+
+//world-state key of the activation policy
+const policyKey = "activationPolicy"
+
+//objectType of the composite keys `Activation~[accountID]`
+const activationObjectType = "Activation"
+
+/*objectTypes of the composite keys `ActivationRequest~[accountID]`, and of the index `PendingActivationRequest~[accountID]`
+of the requests waiting in the approval queue*/
+const (
+	ActivationRequestObjectType = "ActivationRequest"
+	PendingRequestObjectType    = "PendingActivationRequest"
+)
+
+/*ActivationRecord tells who activated an account and when*/
+type ActivationRecord struct {
+	Account     string `json:"account"`
+	ApprovedBy  string `json:"approvedBy"`
+	ApprovedAt  int64  `json:"approvedAt"`
+	TxID        string `json:"txID"`
+	RequestedAt int64  `json:"requestedAt,omitempty"` /*set when the activation went through the approval queue*/
+}
+
+/*ActivationRequest is an account waiting in the approval queue, kept once decided (the index of the pending requests drops it then)*/
+type ActivationRequest struct {
+	Account     string `json:"account"`
+	RequestedAt int64  `json:"requestedAt"`
+	Status      string `json:"status"`
+	DecidedBy   string `json:"decidedBy,omitempty"`
+	DecidedAt   int64  `json:"decidedAt,omitempty"`
+}
+
+/*ActivationRequestsPage is a page of the pending requests, ordered by account ID,
+`Bookmark` is passed to get the next page and is empty on the last one*/
+type ActivationRequestsPage struct {
+	Requests []ActivationRequest `json:"requests"`
+	Bookmark string              `json:"bookmark"`
+}
+
+/*Token activation implements ActivationTokenInterface*/
+type Token struct{}
+
+/*GetActivationPolicy returns the activation policy, `open` by default*/
+func (t *Token) GetActivationPolicy(stub shim.ChaincodeStubInterface) (string, error) {
+	policy, err := GetConfigState(stub, policyKey)
+	if err != nil {
+		return "", err
+	}
+	if len(policy) == 0 {
+		return OPEN, nil
+	}
+	return string(policy), nil
+}
+
+/*GetActivationRecord returns who activated an account and when, nil if the account was activated before records existed.
+
+* `args[0]` - the account ID.*/
+func (t *Token) GetActivationRecord(stub shim.ChaincodeStubInterface, args []string) (*ActivationRecord, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+
+	recordKey, err := stub.CreateCompositeKey(activationObjectType, []string{args[0]})
+	if err != nil {
+		return nil, err
+	}
+	recordBytes, err := stub.GetState(recordKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(recordBytes) == 0 {
+		return nil, nil
+	}
+
+	record := &ActivationRecord{}
+	if err := json.Unmarshal(recordBytes, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+/*GetActivationRequests returns a page of the requests waiting in the approval queue, every arg is optional.
+
+* `args[0]` - the page size, DefaultPageSize if empty.
+
+* `args[1]` - the bookmark returned with the previous page, empty for the first page.*/
+func (t *Token) GetActivationRequests(stub shim.ChaincodeStubInterface, args []string) (*ActivationRequestsPage, error) {
+	args = append(args, make([]string, 2)...)[:2]
+	pageSize, err := ParsePageSize(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ActivationRequestsPage{Requests: []ActivationRequest{}}
+	page.Bookmark, err = QueryPage(stub, PendingRequestObjectType, []string{}, pageSize, args[1], func(keyAttributes []string, value []byte) error {
+		request, err := getRequest(stub, keyAttributes[0])
+		if err != nil {
+			return err
+		}
+		if request == nil {
+			return fmt.Errorf("the pending activation request of %v is missing", keyAttributes[0])
+		}
+		page.Requests = append(page.Requests, *request)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+/*IndexActivationRequest adds the request of an account to the index of the pending requests if it's pending,
+for the requests written before the index existed.
+
+* `args[0]` - the account ID.*/
+func (t *Token) IndexActivationRequest(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	request, err := getRequest(stub, args[0])
+	if err != nil || request == nil {
+		return err
+	}
+	return putRequest(stub, request)
+}
+
+/*CheckActivation returns an error if the activation policy does not let the chaincode caller activate an account directly.
+
+* `args[0]` - the account ID.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.
+
+* `getOwner` - specifies the function of getting the token owner.*/
+func (t *Token) CheckActivation(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	getOwner func(shim.ChaincodeStubInterface) (string, error),
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	policy, err := t.GetActivationPolicy(stub)
+	if err != nil {
+		return err
+	}
+
+	switch policy {
+	case SELF:
+		callerID, err := ResolveCallerID(stub)
+		if err != nil {
+			return err
+		}
+		if args[0] != callerID {
+			return fmt.Errorf("the activation policy only lets %v activate its own account", callerID)
+		}
+	case APPROVAL:
+		//activating directly counts as an approval
+		if _, err := checkCallerIsRegistrar(stub, hasRole, getOwner); err != nil {
+			return fmt.Errorf("the activation policy requires an approved RequestActivation: %v", err)
+		}
+	}
+	return nil
+}
+
+/*RecordActivation stores the chaincode caller as the approver of an account activation.
+
+* `args[0]` - the account ID.*/
+func (t *Token) RecordActivation(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	accountID := args[0]
+
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	record := ActivationRecord{
+		Account:    accountID,
+		ApprovedBy: callerID,
+		ApprovedAt: txTimestamp.GetSeconds(),
+		TxID:       stub.GetTxID(),
+	}
+
+	request, err := getRequest(stub, accountID)
+	if err != nil {
+		return err
+	}
+	if request != nil && request.Status == PENDING {
+		record.RequestedAt = request.RequestedAt
+		//activating directly counts as an approval
+		request.Status, request.DecidedBy, request.DecidedAt = APPROVED, callerID, record.ApprovedAt
+		if err := putRequest(stub, request); err != nil {
+			return err
+		}
+	}
+
+	recordKey, err := stub.CreateCompositeKey(activationObjectType, []string{accountID})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(recordKey, MalshalJSON(record))
+}
+
+/*MoveActivationRecord moves the activation record of an account to another account, keeping who approved it and when.
+
+* `args[0]` - the account ID.
+
+* `args[1]` - the ID of the account receiving the record.*/
+func (t *Token) MoveActivationRecord(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	oldID, newID := args[0], args[1]
+
+	record, err := t.GetActivationRecord(stub, []string{oldID})
+	if err != nil || record == nil {
+		return err
+	}
+	oldKey, err := stub.CreateCompositeKey(activationObjectType, []string{oldID})
+	if err != nil {
+		return err
+	}
+	if err := stub.DelState(oldKey); err != nil {
+		return err
+	}
+
+	record.Account = newID
+	newKey, err := stub.CreateCompositeKey(activationObjectType, []string{newID})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(newKey, MalshalJSON(record))
+}
+
+/*SetActivationPolicy changes the activation policy, callable by members of the ADMIN role.
+
+* `args[0]` - `open`, `self` or `approval`.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) SetActivationPolicy(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	policy := args[0]
+	switch policy {
+	case OPEN, SELF, APPROVAL:
+	default:
+		return fmt.Errorf("unknown activation policy %v, expected one of: %v, %v, %v", policy, OPEN, SELF, APPROVAL)
+	}
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("SetActivationPolicy: setting %v by %v", policy, callerID)
+	return PutConfigState(stub, policyKey, []byte(policy))
+}
+
+/*RequestActivation queues the activation of the chaincode caller's own account, for the owner or a REGISTRAR to approve.
+
+* `getBalanceOf` - specifies the function of getting the balance of an account, failing for unregistered accounts.*/
+func (t *Token) RequestActivation(stub shim.ChaincodeStubInterface,
+	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+) error {
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	if _, err := getBalanceOf(stub, []string{callerID}); err == nil {
+		return fmt.Errorf("the account %v is already activated", callerID)
+	}
+
+	request, err := getRequest(stub, callerID)
+	if err != nil {
+		return err
+	}
+	if request != nil && request.Status == PENDING {
+		return fmt.Errorf("the activation of %v is already requested", callerID)
+	}
+
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("RequestActivation: queueing %v", callerID)
+
+	err = putRequest(stub, &ActivationRequest{Account: callerID, RequestedAt: txTimestamp.GetSeconds(), Status: PENDING})
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.ActivationPayload{Account: callerID}})
+	return stub.SetEvent(erc20events.ACTIVATION_REQUESTED, json)
+}
+
+/*ApproveActivation activates a requested account, callable by the token owner or members of the REGISTRAR role.
+
+* `args[0]` - the account ID.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.
+
+* `getOwner` - specifies the function of getting the token owner.
+
+* `activate` - specifies the function activating the account.*/
+func (t *Token) ApproveActivation(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	getOwner func(shim.ChaincodeStubInterface) (string, error),
+	activate func(shim.ChaincodeStubInterface, []string) error,
+) error {
+	request, callerID, err := decideRequest(stub, args, APPROVED, hasRole, getOwner)
+	if err != nil {
+		return err
+	}
+
+	//the record is written by `activate` while the request is still pending, so it keeps the request time
+	if err := activate(stub, []string{request.Account}); err != nil {
+		return err
+	}
+	if err := putRequest(stub, request); err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.ActivationPayload{Account: request.Account}})
+	return stub.SetEvent(erc20events.ACTIVATION_APPROVED, json)
+}
+
+/*RejectActivation turns down a requested account, callable by the token owner or members of the REGISTRAR role.
+
+* `args[0]` - the account ID.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.
+
+* `getOwner` - specifies the function of getting the token owner.*/
+func (t *Token) RejectActivation(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	getOwner func(shim.ChaincodeStubInterface) (string, error),
+) error {
+	request, callerID, err := decideRequest(stub, args, REJECTED, hasRole, getOwner)
+	if err != nil {
+		return err
+	}
+	if err := putRequest(stub, request); err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.ActivationPayload{Account: request.Account}})
+	return stub.SetEvent(erc20events.ACTIVATION_REJECTED, json)
+}
+
+//decideRequest checks the caller may decide on the pending request of `args[0]`, and returns the decided (unsaved) request
+func decideRequest(stub shim.ChaincodeStubInterface,
+	args []string,
+	status string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	getOwner func(shim.ChaincodeStubInterface) (string, error),
+) (*ActivationRequest, string, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, "", err
+	}
+	accountID := args[0]
+
+	callerID, err := checkCallerIsRegistrar(stub, hasRole, getOwner)
+	if err != nil {
+		return nil, "", err
+	}
+
+	request, err := getRequest(stub, accountID)
+	if err != nil {
+		return nil, "", err
+	}
+	if request == nil || request.Status != PENDING {
+		return nil, "", fmt.Errorf("there is no pending activation request for %v", accountID)
+	}
+
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, "", err
+	}
+
+	logger.Infof("%v activation of %v by %v", status, accountID, callerID)
+
+	request.Status = status
+	request.DecidedBy = callerID
+	request.DecidedAt = txTimestamp.GetSeconds()
+	return request, callerID, nil
+}
+
+//checkCallerIsRegistrar returns the caller's ID if the caller is the token owner or a member of the REGISTRAR role
+func checkCallerIsRegistrar(stub shim.ChaincodeStubInterface,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	getOwner func(shim.ChaincodeStubInterface) (string, error),
+) (string, error) {
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return "", err
+	}
+	owner, err := getOwner(stub)
+	if err != nil {
+		return "", err
+	}
+	if callerID == owner {
+		return callerID, nil
+	}
+	return CheckCallerIsMember(stub, erc20roles.REGISTRAR, hasRole)
+}
+
+func getRequest(stub shim.ChaincodeStubInterface, accountID string) (*ActivationRequest, error) {
+	requestKey, err := stub.CreateCompositeKey(ActivationRequestObjectType, []string{accountID})
+	if err != nil {
+		return nil, err
+	}
+	requestBytes, err := stub.GetState(requestKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(requestBytes) == 0 {
+		return nil, nil
+	}
+
+	request := &ActivationRequest{}
+	if err := json.Unmarshal(requestBytes, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+//putRequest stores a request, and keeps it in the index of the pending requests while it's pending
+func putRequest(stub shim.ChaincodeStubInterface, request *ActivationRequest) error {
+	requestKey, err := stub.CreateCompositeKey(ActivationRequestObjectType, []string{request.Account})
+	if err != nil {
+		return err
+	}
+	if err := stub.PutState(requestKey, MalshalJSON(request)); err != nil {
+		return err
+	}
+	pendingKey, err := stub.CreateCompositeKey(PendingRequestObjectType, []string{request.Account})
+	if err != nil {
+		return err
+	}
+	if request.Status == PENDING {
+		return stub.PutState(pendingKey, []byte{0x00})
+	}
+	return stub.DelState(pendingKey)
+}
@@ -0,0 +1,52 @@
+package erc20activation
+
+import (
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+/*ActivationTokenInterface consists of SetActivationPolicy, ApproveActivation & RejectActivation (methods should be restricted),
+RequestActivation for the caller's own account, CheckActivation & RecordActivation used by `Activate`,
+MoveActivationRecord used by account migrations, IndexActivationRequest used by schema migrations, and getters to check state*/
+type ActivationTokenInterface interface {
+	GetActivationPolicy(stub shim.ChaincodeStubInterface) (string, error)
+
+	GetActivationRecord(stub shim.ChaincodeStubInterface, args []string) (*ActivationRecord, error)
+
+	GetActivationRequests(stub shim.ChaincodeStubInterface, args []string) (*ActivationRequestsPage, error)
+
+	IndexActivationRequest(stub shim.ChaincodeStubInterface, args []string) error
+
+	CheckActivation(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+		getOwner func(shim.ChaincodeStubInterface) (string, error),
+	) error
+
+	RecordActivation(stub shim.ChaincodeStubInterface, args []string) error
+
+	MoveActivationRecord(stub shim.ChaincodeStubInterface, args []string) error
+
+	SetActivationPolicy(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+
+	RequestActivation(stub shim.ChaincodeStubInterface,
+		getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+	) error
+
+	ApproveActivation(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+		getOwner func(shim.ChaincodeStubInterface) (string, error),
+		activate func(shim.ChaincodeStubInterface, []string) error,
+	) error
+
+	RejectActivation(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+		getOwner func(shim.ChaincodeStubInterface) (string, error),
+	) error
+}
@@ -0,0 +1,183 @@
+package erc20alias
+
+import (
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"fmt"
+	"regexp"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("alias-logger")
+
+//objectType of the composite key `Alias~[alias]`, holding the account ID
+const aliasObjectType = "Alias"
+
+//objectType of the composite key `AccountAlias~[accountID]~[alias]`, the reverse index
+const accountAliasObjectType = "AccountAlias"
+
+//aliases look like `alice@org1`, they never contain a comma so they can't be mistaken for an account ID
+var aliasPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}@[a-z0-9][a-z0-9.-]{0,63}$`)
+
+/*Token alias implements AliasTokenInterface*/
+type Token struct{}
+
+/*RegisterAlias claims an unused alias for the chaincode caller's account.
+
+* `args[0]` - the alias, e.g. `alice@org1`.*/
+func (t *Token) RegisterAlias(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	alias := args[0]
+	if err := CheckAlias(alias); err != nil {
+		return err
+	}
+
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+
+	aliasKey, err := stub.CreateCompositeKey(aliasObjectType, []string{alias})
+	if err != nil {
+		return err
+	}
+	accountID, err := stub.GetState(aliasKey)
+	if err != nil {
+		return err
+	}
+	if len(accountID) != 0 {
+		return fmt.Errorf("alias %v is already registered", alias)
+	}
+
+	logger.Infof("RegisterAlias: registering %v for %v...", alias, callerID)
+
+	err = stub.PutState(aliasKey, []byte(callerID))
+	if err != nil {
+		return err
+	}
+	reverseKey, err := stub.CreateCompositeKey(accountAliasObjectType, []string{callerID, alias})
+	if err != nil {
+		return err
+	}
+	//the value is not used, but an empty value would delete the key
+	err = stub.PutState(reverseKey, []byte{0x00})
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.AliasPayload{Alias: alias, Account: callerID}})
+	return stub.SetEvent(erc20events.ALIAS_REGISTERED, json)
+}
+
+/*ReleaseAlias frees an alias of the chaincode caller's account, so it can be claimed again.
+
+* `args[0]` - the alias.*/
+func (t *Token) ReleaseAlias(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	alias := args[0]
+
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+
+	aliasKey, err := stub.CreateCompositeKey(aliasObjectType, []string{alias})
+	if err != nil {
+		return err
+	}
+	accountID, err := stub.GetState(aliasKey)
+	if err != nil {
+		return err
+	}
+	if string(accountID) != callerID {
+		return fmt.Errorf("alias %v is not registered by %v", alias, callerID)
+	}
+
+	logger.Infof("ReleaseAlias: releasing %v of %v...", alias, callerID)
+
+	err = stub.DelState(aliasKey)
+	if err != nil {
+		return err
+	}
+	reverseKey, err := stub.CreateCompositeKey(accountAliasObjectType, []string{callerID, alias})
+	if err != nil {
+		return err
+	}
+	err = stub.DelState(reverseKey)
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.AliasPayload{Alias: alias, Account: callerID}})
+	return stub.SetEvent(erc20events.ALIAS_RELEASED, json)
+}
+
+/*ResolveAlias returns the account ID an alias points to.
+Values that can't be aliases (not of the `name@domain` form) are account IDs, returned as is, so callers can pass either;
+an alias that is not registered is an error, so tokens are never sent to a name no certificate can sign for.
+
+* `args[0]` - the alias or account ID.*/
+func (t *Token) ResolveAlias(stub shim.ChaincodeStubInterface, args []string) (string, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return "", err
+	}
+	aliasOrID := args[0]
+	if !aliasPattern.MatchString(aliasOrID) {
+		return aliasOrID, nil
+	}
+
+	aliasKey, err := stub.CreateCompositeKey(aliasObjectType, []string{aliasOrID})
+	if err != nil {
+		return "", err
+	}
+	accountID, err := stub.GetState(aliasKey)
+	if err != nil {
+		return "", err
+	}
+	if len(accountID) == 0 {
+		return "", fmt.Errorf("alias %v is not registered", aliasOrID)
+	}
+	return string(accountID), nil
+}
+
+/*GetAliases returns every alias registered by an account.
+
+* `args[0]` - the account ID.*/
+func (t *Token) GetAliases(stub shim.ChaincodeStubInterface, args []string) ([]string, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+
+	iterator, err := stub.GetStateByPartialCompositeKey(accountAliasObjectType, []string{args[0]})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	aliases := []string{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := stub.SplitCompositeKey(queryResult.GetKey())
+		if err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, attributes[1])
+	}
+	return aliases, nil
+}
+
+/*CheckAlias returns an error if `alias` is not of the `name@domain` form (lower-case letters, digits, `.`, `_` & `-`)*/
+func CheckAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("invalid alias %v, expected the form name@domain", alias)
+	}
+	return nil
+}
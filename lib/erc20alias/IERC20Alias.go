@@ -0,0 +1,14 @@
+package erc20alias
+
+import "github.com/hyperledger/fabric/core/chaincode/shim"
+
+/*AliasTokenInterface consists of RegisterAlias & ReleaseAlias (for the caller's own account), ResolveAlias & GetAliases to look up the registry*/
+type AliasTokenInterface interface {
+	RegisterAlias(stub shim.ChaincodeStubInterface, args []string) error
+
+	ReleaseAlias(stub shim.ChaincodeStubInterface, args []string) error
+
+	ResolveAlias(stub shim.ChaincodeStubInterface, args []string) (string, error)
+
+	GetAliases(stub shim.ChaincodeStubInterface, args []string) ([]string, error)
+}
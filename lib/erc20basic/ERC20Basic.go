@@ -11,6 +11,19 @@ import (
 
 var logger = shim.NewLogger("trans-logger")
 
+/*Allowance is the amount `Spender` may transfer or burn on behalf of `Owner`*/
+type Allowance struct {
+	Owner   string   `json:"owner"`
+	Spender string   `json:"spender"`
+	Amount  *big.Int `json:"amount"`
+}
+
+/*AllowancesPage is a page of allowances, `Bookmark` is passed to get the next page and is empty on the last one*/
+type AllowancesPage struct {
+	Allowances []Allowance `json:"allowances"`
+	Bookmark   string      `json:"bookmark"`
+}
+
 /*Token basic implementation of BasicTokenInterface*/
 type Token struct{}
 
@@ -18,14 +31,14 @@ type Token struct{}
 
 * `args[0]` - the ID of user.*/
 func (t *Token) GetBalanceOf(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error) {
-	tokenBalance, err := stub.GetState(args[0])
+	tokenBalance, err := GetBalanceState(stub, args[0])
 	logger.Infof("GetBalanceOf: getting balance of %v...", args[0])
 	return BufferToBigInt(DefaultToZeroIfEmpty(tokenBalance)), err
 }
 
 /*GetTotalSupply returns total number of tokens in existence*/
 func (t *Token) GetTotalSupply(stub shim.ChaincodeStubInterface) (*big.Int, error) {
-	totalSupply, err := stub.GetState("totalSupply")
+	totalSupply, err := GetConfigState(stub, "totalSupply")
 	logger.Infof("GetTotalSupply: %v", totalSupply)
 	return BufferToBigInt(DefaultToZeroIfEmpty(totalSupply)), err
 }
@@ -38,22 +51,89 @@ func (t *Token) GetTotalSupply(stub shim.ChaincodeStubInterface) (*big.Int, erro
 func (t *Token) GetAllowance(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error) {
 	ownerID, spenderID := args[0], args[1]
 
-	allowance, err := stub.GetState(ownerID + "-" + spenderID)
+	allowance, err := GetAllowanceState(stub, ownerID, spenderID)
 
 	return BufferToBigInt(DefaultToZeroIfEmpty(allowance)), err
 }
 
+/*GetAllowancesOf returns a page of the allowances an owner gave, ordered by spender ID.
+
+* `args[0]` - the ID of owner.
+
+* `args[1]` - the page size, DefaultPageSize if empty or missing.
+
+* `args[2]` - the bookmark returned with the previous page, empty or missing for the first page.*/
+func (t *Token) GetAllowancesOf(stub shim.ChaincodeStubInterface, args []string) (*AllowancesPage, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("the ID of owner is required")
+	}
+	args = append(args, make([]string, 3)...)[:3]
+	ownerID := args[0]
+	pageSize, err := ParsePageSize(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("GetAllowancesOf: getting %v allowances of %v after %q", pageSize, ownerID, args[2])
+
+	page := &AllowancesPage{Allowances: []Allowance{}}
+	page.Bookmark, err = QueryPage(stub, AllowanceObjectType, []string{ownerID}, pageSize, args[2], func(keyAttributes []string, value []byte) error {
+		page.Allowances = append(page.Allowances, Allowance{Owner: ownerID, Spender: keyAttributes[1], Amount: BufferToBigInt(value)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+/*GetApprovalsFor returns a page of the allowances a spender was given, ordered by owner ID.
+
+* `args[0]` - the ID of spender.
+
+* `args[1]` - the page size, DefaultPageSize if empty or missing.
+
+* `args[2]` - the bookmark returned with the previous page, empty or missing for the first page.*/
+func (t *Token) GetApprovalsFor(stub shim.ChaincodeStubInterface, args []string) (*AllowancesPage, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("the ID of spender is required")
+	}
+	args = append(args, make([]string, 3)...)[:3]
+	spenderID := args[0]
+	pageSize, err := ParsePageSize(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("GetApprovalsFor: getting %v allowances given to %v after %q", pageSize, spenderID, args[2])
+
+	page := &AllowancesPage{Allowances: []Allowance{}}
+	page.Bookmark, err = QueryPage(stub, AllowanceSpenderObjectType, []string{spenderID}, pageSize, args[2], func(keyAttributes []string, value []byte) error {
+		ownerID := keyAttributes[1]
+		allowance, err := GetAllowanceState(stub, ownerID, spenderID)
+		if err != nil || len(allowance) == 0 {
+			return err
+		}
+		page.Allowances = append(page.Allowances, Allowance{Owner: ownerID, Spender: spenderID, Amount: BufferToBigInt(allowance)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
 /*Transfer token from current caller to a specified address.
 
 * `args[0]` - the ID of receiver.
 
 * `args[1]` - the transfer amount.
 
-* `getBalanceOf` - specifies the function of getting the initial balances of token sender & receiver.*/
+* `getBalanceOf` - specifies the function of getting the initial balances of token sender & receiver, hot receivers are credited without reading their balance.*/
 func (t *Token) Transfer(stub shim.ChaincodeStubInterface, args []string, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error {
 	receiverID, sValue := args[0], args[1]
 
-	senderID, err := GetCallerID(stub)
+	senderID, err := ResolveCallerID(stub)
 	if err != nil {
 		return err
 	}
@@ -70,7 +150,7 @@ func (t *Token) Transfer(stub shim.ChaincodeStubInterface, args []string, getBal
 	if err != nil {
 		return err
 	}
-	balanceOfReceiver, err := getBalanceOf(stub, []string{receiverID})
+	balanceOfReceiver, err := GetCreditedBalance(stub, receiverID, getBalanceOf)
 	if err != nil {
 		return err
 	}
@@ -85,11 +165,15 @@ func (t *Token) Transfer(stub shim.ChaincodeStubInterface, args []string, getBal
 		return fmt.Errorf("transfer amount should be less than balance of sender (%v): %v", senderID, err)
 	}
 
-	err = stub.PutState(senderID, []byte(Sub(balanceOfSender, transferAmount).String()))
+	senderChange, err := DebitBalance(stub, senderID, balanceOfSender, transferAmount)
+	if err != nil {
+		return err
+	}
+	receiverChange, err := CreditBalance(stub, receiverID, balanceOfReceiver, transferAmount)
 	if err != nil {
 		return err
 	}
-	err = stub.PutState(receiverID, []byte(Add(balanceOfReceiver, transferAmount).String()))
+	err = UpdateHolderCount(stub, senderChange, receiverChange)
 	if err != nil {
 		return err
 	}
@@ -106,7 +190,7 @@ func (t *Token) Transfer(stub shim.ChaincodeStubInterface, args []string, getBal
 
 * `args[2]` - the transfer amount.
 
-* `getBalanceOf` - defines the function of getting the initial balances of token owner & receiver.
+* `getBalanceOf` - defines the function of getting the initial balances of token owner & receiver, hot receivers are credited without reading their balance.
 
 * `getAllowance` - defines the function of getting the allowance that the current chaincode invoker can spend from the token owner, to transfer to the receiver.*/
 func (t *Token) TransferFrom(stub shim.ChaincodeStubInterface,
@@ -120,7 +204,7 @@ func (t *Token) TransferFrom(stub shim.ChaincodeStubInterface,
 		return err
 	}
 
-	spenderID, err := GetCallerID(stub)
+	spenderID, err := ResolveCallerID(stub)
 	if err != nil {
 		return err
 	}
@@ -133,7 +217,7 @@ func (t *Token) TransferFrom(stub shim.ChaincodeStubInterface,
 	if err != nil {
 		return err
 	}
-	balanceOfReceiver, err := getBalanceOf(stub, []string{receiverID})
+	balanceOfReceiver, err := GetCreditedBalance(stub, receiverID, getBalanceOf)
 	if err != nil {
 		return err
 	}
@@ -158,15 +242,19 @@ func (t *Token) TransferFrom(stub shim.ChaincodeStubInterface,
 		return fmt.Errorf("transfer amount should be less than approved spending amount of %v: %v", spenderID, err)
 	}
 
-	err = stub.PutState(tokenOwnerID, []byte(Sub(balanceOfTokenOwner, transferAmount).String()))
+	tokenOwnerChange, err := DebitBalance(stub, tokenOwnerID, balanceOfTokenOwner, transferAmount)
 	if err != nil {
 		return err
 	}
-	err = stub.PutState(tokenOwnerID+"-"+spenderID, []byte(Sub(approvedAmount, transferAmount).String()))
+	err = PutAllowance(stub, tokenOwnerID, spenderID, Sub(approvedAmount, transferAmount))
 	if err != nil {
 		return err
 	}
-	err = stub.PutState(receiverID, []byte(Add(balanceOfReceiver, transferAmount).String()))
+	receiverChange, err := CreditBalance(stub, receiverID, balanceOfReceiver, transferAmount)
+	if err != nil {
+		return err
+	}
+	err = UpdateHolderCount(stub, tokenOwnerChange, receiverChange)
 	if err != nil {
 		return err
 	}
@@ -179,25 +267,157 @@ func (t *Token) TransferFrom(stub shim.ChaincodeStubInterface,
 
 * `args[0]` - the ID of approved user.
 
-* `args[1]` - the maximum approved amount.*/
+* `args[1]` - the maximum approved amount, 0 removes the approval.*/
 func (t *Token) UpdateApproval(stub shim.ChaincodeStubInterface, args []string) error {
 	spenderID, newAllowance := args[0], args[1]
 
-	if err := CheckGreaterThanZero(newAllowance); err != nil {
-		return err
+	approvedAmount, ok := new(big.Int).SetString(newAllowance, 10)
+	if !ok || approvedAmount.Sign() < 0 {
+		return fmt.Errorf("invalid allowance %v, expected an integer >= 0", newAllowance)
 	}
 
-	callerID, err := GetCallerID(stub)
+	callerID, err := ResolveCallerID(stub)
 	if err != nil {
 		return err
 	}
 
-	err = stub.PutState(callerID+"-"+spenderID, []byte(newAllowance))
+	//PutAllowance removes the approvals of 0 instead of writing them
+	err = PutAllowance(stub, callerID, spenderID, approvedAmount)
 	if err != nil {
 		return err
 	}
 
-	approvedAmount := StringToBigInt(newAllowance)
 	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.Payload{From: callerID, To: spenderID, Amount: approvedAmount}})
 	return stub.SetEvent(erc20events.APPROVAL, json)
 }
+
+/*ClearAllowances removes every allowance given by or to an account.
+
+* `args[0]` - the account ID.*/
+func (t *Token) ClearAllowances(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	accountID := args[0]
+
+	pairs, err := allowancesOf(stub, accountID)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		ownerID, spenderID := pair[0], pair[1]
+
+		logger.Infof("ClearAllowances: clearing allowance of %v from %v", spenderID, ownerID)
+
+		if err := DelAllowance(stub, ownerID, spenderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*MoveAllowances moves every allowance given by or to an account to another account,
+adding to the allowances the other account already has.
+
+* `args[0]` - the account ID.
+
+* `args[1]` - the ID of the account receiving the allowances.*/
+func (t *Token) MoveAllowances(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	oldID, newID := args[0], args[1]
+
+	//collect the allowances first, moving them while iterating would change the indexes being read
+	pairs, err := allowancesOf(stub, oldID)
+	if err != nil {
+		return err
+	}
+
+	//the sums are made in memory, the peer doesn't read the writes of the transaction being simulated:
+	//an allowance the account gives itself appears in both indexes,
+	//and several allowances can land on the same pair, e.g. between the old & the new account
+	moved, sums := [][2]string{}, map[[2]string]*big.Int{}
+	seen := map[[2]string]bool{}
+	for _, pair := range pairs {
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		ownerID, spenderID := pair[0], pair[1]
+		allowance, err := GetAllowanceState(stub, ownerID, spenderID)
+		if err != nil {
+			return err
+		}
+
+		newPair := [2]string{ownerID, spenderID}
+		if ownerID == oldID {
+			newPair[0] = newID
+		}
+		if spenderID == oldID {
+			newPair[1] = newID
+		}
+
+		logger.Infof("MoveAllowances: moving allowance of %v from %v to %v from %v", spenderID, ownerID, newPair[1], newPair[0])
+
+		if err := DelAllowance(stub, ownerID, spenderID); err != nil {
+			return err
+		}
+		if _, found := sums[newPair]; !found {
+			//the moved pairs involve `newID` only, none of them is deleted above
+			existing, err := GetAllowanceState(stub, newPair[0], newPair[1])
+			if err != nil {
+				return err
+			}
+			moved, sums[newPair] = append(moved, newPair), BufferToBigInt(DefaultToZeroIfEmpty(existing))
+		}
+		sums[newPair] = Add(sums[newPair], BufferToBigInt(DefaultToZeroIfEmpty(allowance)))
+	}
+
+	for _, pair := range moved {
+		if err := PutAllowance(stub, pair[0], pair[1], sums[pair]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//allowancesOf returns the [owner, spender] pairs of the allowances given by an account, then of those given to it
+func allowancesOf(stub shim.ChaincodeStubInterface, accountID string) ([][2]string, error) {
+	pairs := [][2]string{}
+	for _, objectType := range []string{AllowanceObjectType, AllowanceSpenderObjectType} {
+		indexed, err := allowancesIndexedBy(stub, objectType, accountID)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, indexed...)
+	}
+	return pairs, nil
+}
+
+//allowancesIndexedBy returns the [owner, spender] pairs of the `objectType~[accountID]~*` keys
+func allowancesIndexedBy(stub shim.ChaincodeStubInterface, objectType string, accountID string) ([][2]string, error) {
+	iterator, err := stub.GetStateByPartialCompositeKey(objectType, []string{accountID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	pairs := [][2]string{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := stub.SplitCompositeKey(queryResult.GetKey())
+		if err != nil {
+			return nil, err
+		}
+		if objectType == AllowanceSpenderObjectType {
+			pairs = append(pairs, [2]string{attributes[1], attributes[0]})
+		} else {
+			pairs = append(pairs, [2]string{attributes[0], attributes[1]})
+		}
+	}
+	return pairs, nil
+}
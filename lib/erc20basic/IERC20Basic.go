@@ -14,6 +14,10 @@ type BasicTokenInterface interface {
 
 	GetAllowance(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error)
 
+	GetAllowancesOf(stub shim.ChaincodeStubInterface, args []string) (*AllowancesPage, error)
+
+	GetApprovalsFor(stub shim.ChaincodeStubInterface, args []string) (*AllowancesPage, error)
+
 	Transfer(stub shim.ChaincodeStubInterface, args []string, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error
 
 	TransferFrom(stub shim.ChaincodeStubInterface,
@@ -23,4 +27,8 @@ type BasicTokenInterface interface {
 	) error
 
 	UpdateApproval(stub shim.ChaincodeStubInterface, args []string) error
+
+	ClearAllowances(stub shim.ChaincodeStubInterface, args []string) error
+
+	MoveAllowances(stub shim.ChaincodeStubInterface, args []string) error
 }
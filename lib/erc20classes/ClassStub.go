@@ -0,0 +1,117 @@
+package erc20classes
+
+import (
+	"encoding/json"
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//objectType of the composite keys `class~[classID]~[objectType]~[attributes...]` scoping the state of a class
+const classObjectType = "class"
+
+//sharedObjectTypes are the composite keys shared by every class: accounts are identified the same way in all of them,
+//and the identity & compliance controls (freezes, MSP list, migrations, method policies) apply to all of them
+var sharedObjectTypes = map[string]bool{
+	"Alias":           true,
+	"AccountAlias":    true,
+	MemoKeyObjectType: true,
+	FrozenObjectType:  true,
+	"FreezeLog":       true,
+	"MSP":             true,
+	"Migration":       true,
+	"Forwarding":      true,
+	"MethodPolicy":    true,
+}
+
+//sharedConfigs are the `config~[name]` entries shared by every class
+var sharedConfigs = map[string]bool{
+	IDSchemeKey:           true,
+	IdemixIDSchemeKey:     true,
+	"schemaVersion":       true,
+	"migrationBookmark":   true,
+	"stateModeConversion": true,
+	StateModeKey:          true,
+	ConfidentialModeKey:   true,
+	"mspListMode":         true,
+	"multiSigPolicy":      true,
+}
+
+/*classStub scopes the composite keys of a ChaincodeStubInterface to a token class,
+so the libraries keep their own key layout while every class gets its own balances, allowances, config & roles.
+Raw keys (composite keys created by classStub included) are passed through.*/
+type classStub struct {
+	shim.ChaincodeStubInterface
+	classID string
+}
+
+func (s *classStub) isShared(objectType string, attributes []string) bool {
+	if sharedObjectTypes[objectType] {
+		return true
+	}
+	return objectType == ConfigObjectType && len(attributes) > 0 && sharedConfigs[attributes[0]]
+}
+
+func (s *classStub) scope(objectType string, attributes []string) (string, []string) {
+	if s.isShared(objectType, attributes) {
+		return objectType, attributes
+	}
+	return classObjectType, append([]string{s.classID, objectType}, attributes...)
+}
+
+/*ClassID returns the class the state is scoped to, recorded by the JSON state documents*/
+func (s *classStub) ClassID() string {
+	return s.classID
+}
+
+/*CreateCompositeKey creates the composite key of the class*/
+func (s *classStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	objectType, attributes = s.scope(objectType, attributes)
+	return s.ChaincodeStubInterface.CreateCompositeKey(objectType, attributes)
+}
+
+/*SplitCompositeKey splits a composite key of the class as if it was not scoped*/
+func (s *classStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	objectType, attributes, err := s.ChaincodeStubInterface.SplitCompositeKey(compositeKey)
+	if err != nil || objectType != classObjectType {
+		return objectType, attributes, err
+	}
+	if len(attributes) < 2 || attributes[0] != s.classID {
+		return "", nil, fmt.Errorf("key %v does not belong to class %v", compositeKey, s.classID)
+	}
+	return attributes[1], attributes[2:], nil
+}
+
+/*GetStateByPartialCompositeKey queries the composite keys of the class*/
+func (s *classStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	objectType, keys = s.scope(objectType, keys)
+	return s.ChaincodeStubInterface.GetStateByPartialCompositeKey(objectType, keys)
+}
+
+/*GetStateByPartialCompositeKeyWithPagination queries the composite keys of the class*/
+func (s *classStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string,
+	pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	objectType, keys = s.scope(objectType, keys)
+	return s.ChaincodeStubInterface.GetStateByPartialCompositeKeyWithPagination(objectType, keys, pageSize, bookmark)
+}
+
+/*GetPrivateDataByPartialCompositeKey queries the composite keys of the class in a private data collection*/
+func (s *classStub) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	objectType, keys = s.scope(objectType, keys)
+	return s.ChaincodeStubInterface.GetPrivateDataByPartialCompositeKey(collection, objectType, keys)
+}
+
+/*SetEvent records the class in the events emitted by the libraries*/
+func (s *classStub) SetEvent(name string, payload []byte) error {
+	event := struct {
+		Origin  string          `json:"origin"`
+		Payload json.RawMessage `json:"payload"`
+	}{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return s.ChaincodeStubInterface.SetEvent(name, payload)
+	}
+	return s.ChaincodeStubInterface.SetEvent(name, MalshalJSON(erc20events.Event{Origin: event.Origin, Class: s.classID, Payload: event.Payload}))
+}
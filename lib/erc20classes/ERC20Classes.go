@@ -0,0 +1,196 @@
+package erc20classes
+
+import (
+	"encoding/json"
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"erc20/lib/erc20roles"
+	"fmt"
+	"math/big"
+	"regexp"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("classes-logger")
+
+//ClassPrefix marks the leading Invoke param naming the class a method runs on, e.g. `class:points`
+const ClassPrefix = "class:"
+
+//objectType of the composite key `TokenClass~[classID]`
+const tokenClassObjectType = "TokenClass"
+
+//class IDs are short lower-case names, they never contain `:` or a comma
+var classIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+/*TokenClass is a token living next to the default token of the chaincode, with its own metadata, owner, supply, pause state & balances*/
+type TokenClass struct {
+	ID        string `json:"id"`
+	CreatedBy string `json:"createdBy"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+/*Token classes implements ClassesTokenInterface, refer to https://eips.ethereum.org/EIPS/eip-1155 for multi-token contracts*/
+type Token struct{}
+
+/*GetClass returns a token class, nil if it does not exist.
+
+* `args[0]` - the class ID.*/
+func (t *Token) GetClass(stub shim.ChaincodeStubInterface, args []string) (*TokenClass, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+	classKey, err := stub.CreateCompositeKey(tokenClassObjectType, []string{args[0]})
+	if err != nil {
+		return nil, err
+	}
+	classBytes, err := stub.GetState(classKey)
+	if err != nil || len(classBytes) == 0 {
+		return nil, err
+	}
+	class := &TokenClass{}
+	if err := json.Unmarshal(classBytes, class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+/*GetClasses returns every token class*/
+func (t *Token) GetClasses(stub shim.ChaincodeStubInterface) ([]TokenClass, error) {
+	iterator, err := stub.GetStateByPartialCompositeKey(tokenClassObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	classes := []TokenClass{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		class := TokenClass{}
+		if err := json.Unmarshal(queryResult.GetValue(), &class); err != nil {
+			return nil, err
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+/*ClassStub returns a stub scoping the state to a token class, the stub itself for the default token.
+
+* `args[0]` - the class ID, empty for the default token.*/
+func (t *Token) ClassStub(stub shim.ChaincodeStubInterface, args []string) (shim.ChaincodeStubInterface, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+	if args[0] == "" {
+		return stub, nil
+	}
+	class, err := t.GetClass(stub, args)
+	if err != nil {
+		return nil, err
+	}
+	if class == nil {
+		return nil, fmt.Errorf("token class %v does not exist", args[0])
+	}
+	return &classStub{ChaincodeStubInterface: stub, classID: class.ID}, nil
+}
+
+/*CreateClass creates a token class, callable by members of the ADMIN role of the default token.
+The chaincode caller becomes the owner of the class.
+
+* `args[0]` - the class ID, e.g. `points`.
+
+* `args[1]` - the JSON-formatted class configuration, as the Init one.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.
+
+* `initialize` - specifies the function initializing a token with a configuration.*/
+func (t *Token) CreateClass(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	initialize func(shim.ChaincodeStubInterface, string) error,
+) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	classID, config := args[0], args[1]
+	if !classIDPattern.MatchString(classID) {
+		return fmt.Errorf("invalid class ID %v, expected lower-case letters, digits, `_` & `-`", classID)
+	}
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	class, err := t.GetClass(stub, []string{classID})
+	if err != nil {
+		return err
+	}
+	if class != nil {
+		return fmt.Errorf("token class %v already exists", classID)
+	}
+
+	logger.Infof("CreateClass: creating %v by %v", classID, callerID)
+
+	if err := initialize(&classStub{ChaincodeStubInterface: stub, classID: classID}, config); err != nil {
+		return err
+	}
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	classKey, err := stub.CreateCompositeKey(tokenClassObjectType, []string{classID})
+	if err != nil {
+		return err
+	}
+	err = stub.PutState(classKey, MalshalJSON(TokenClass{ID: classID, CreatedBy: callerID, CreatedAt: txTimestamp.GetSeconds()}))
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.ClassPayload{Class: classID, Owner: callerID}})
+	return stub.SetEvent(erc20events.CLASS_CREATED, json)
+}
+
+/*GetBalanceOfBatch returns the balances of accounts in token classes, ERC-1155's balanceOfBatch.
+
+* `args[0]` - the JSON array of account IDs.
+
+* `args[1]` - the JSON array of their class IDs, an empty ID is the default token.
+
+* `getBalanceOf` - specifies the function of getting the balance of an account.*/
+func (t *Token) GetBalanceOfBatch(stub shim.ChaincodeStubInterface,
+	args []string,
+	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+) ([]*big.Int, error) {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return nil, err
+	}
+	accountIDs, classIDs := []string{}, []string{}
+	if err := json.Unmarshal([]byte(args[0]), &accountIDs); err != nil {
+		return nil, fmt.Errorf("invalid account IDs: %v", err)
+	}
+	if err := json.Unmarshal([]byte(args[1]), &classIDs); err != nil {
+		return nil, fmt.Errorf("invalid class IDs: %v", err)
+	}
+	if len(accountIDs) != len(classIDs) {
+		return nil, fmt.Errorf("got %v account IDs for %v class IDs", len(accountIDs), len(classIDs))
+	}
+
+	balances := make([]*big.Int, len(accountIDs))
+	for i, accountID := range accountIDs {
+		classStub, err := t.ClassStub(stub, []string{classIDs[i]})
+		if err != nil {
+			return nil, err
+		}
+		balances[i], err = getBalanceOf(classStub, []string{accountID})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return balances, nil
+}
@@ -0,0 +1,28 @@
+package erc20classes
+
+import (
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+/*ClassesTokenInterface consists of CreateClass (should be restricted), ClassStub to run methods on a class,
+GetClass, GetClasses & GetBalanceOfBatch to check state*/
+type ClassesTokenInterface interface {
+	GetClass(stub shim.ChaincodeStubInterface, args []string) (*TokenClass, error)
+
+	GetClasses(stub shim.ChaincodeStubInterface) ([]TokenClass, error)
+
+	ClassStub(stub shim.ChaincodeStubInterface, args []string) (shim.ChaincodeStubInterface, error)
+
+	CreateClass(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+		initialize func(shim.ChaincodeStubInterface, string) error,
+	) error
+
+	GetBalanceOfBatch(stub shim.ChaincodeStubInterface,
+		args []string,
+		getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+	) ([]*big.Int, error)
+}
@@ -0,0 +1,262 @@
+package erc20confidential
+
+import (
+	"encoding/json"
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("confidential-logger")
+
+//TransientKey is the transient map entry holding the Input of the confidential transactions
+const TransientKey = "confidential"
+
+/*Input of the confidential transactions, passed as JSON in the transient map under TransientKey*/
+type Input struct {
+	Receiver     string `json:"receiver,omitempty"`     /*the receiver of ConfidentialTransfer*/
+	Counterparty string `json:"counterparty,omitempty"` /*the other MSP of the bilateral collection of Shield & Unshield, none for the caller's own MSP collection*/
+	Amount       string `json:"amount"`
+}
+
+/*Token confidential implements ConfidentialTokenInterface.
+Confidential balances are held in private data collections (see `collections_config.json`), only their hashes are written to the ledger:
+the collection of a single MSP for the transfers between its accounts, the bilateral collection of 2 MSPs for the trades between them.
+The confidential balances are not counted in the holders nor the account records, the total supply includes them.*/
+type Token struct{}
+
+/*GetInput returns the Input of a confidential transaction from the transient map*/
+func GetInput(stub shim.ChaincodeStubInterface) (*Input, error) {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := transient[TransientKey]
+	if !ok {
+		return nil, fmt.Errorf("the %v transient input is missing", TransientKey)
+	}
+	input := &Input{}
+	if err := json.Unmarshal(value, input); err != nil {
+		return nil, fmt.Errorf("invalid %v transient input: %v", TransientKey, err)
+	}
+	return input, nil
+}
+
+/*GetConfidentialBalance returns the confidential balance of an account, readable by the peers of the collection's MSPs.
+
+* `args[0]` - the account ID.
+
+* `args[1]` - the other MSP ID of a bilateral collection, optional.*/
+func (t *Token) GetConfidentialBalance(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("invalid number of arguments. Expected 1 or 2, got %v", len(args))
+	}
+	if err := checkConfidentialMode(stub); err != nil {
+		return nil, err
+	}
+	args = append(args, "")[:2]
+	accountID, counterparty := args[0], args[1]
+	return getPrivateBalance(stub, collectionOf(accountID, counterparty), accountID)
+}
+
+/*Shield moves tokens from the public balance of the chaincode caller to its confidential balance,
+the amount is public as the public balance changes.
+
+* `transient[TransientKey]` - the Input: `amount`, and the `counterparty` MSP of a bilateral collection.
+
+* `transient[SaltTransientKey]` - the random bytes the confidential balance is salted with.
+
+* `getBalanceOf` - specifies the function of getting the public balance of the caller.*/
+func (t *Token) Shield(stub shim.ChaincodeStubInterface, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error {
+	callerID, input, amount, err := checkInput(stub)
+	if err != nil {
+		return err
+	}
+	collection := collectionOf(callerID, input.Counterparty)
+
+	logger.Infof("Shield: moving %v tokens of %v to %v", amount, callerID, collection)
+
+	balance, err := getBalanceOf(stub, []string{callerID})
+	if err != nil {
+		return err
+	}
+	if err := IsSmallerOrEqual(amount, balance); err != nil {
+		return fmt.Errorf("shield amount should be less than balance of %v: %v", callerID, err)
+	}
+	privateBalance, err := getPrivateBalance(stub, collection, callerID)
+	if err != nil {
+		return err
+	}
+
+	change, err := DebitBalance(stub, callerID, balance, amount)
+	if err != nil {
+		return err
+	}
+	err = UpdateHolderCount(stub, change)
+	if err != nil {
+		return err
+	}
+	err = PutPrivateBalanceState(stub, collection, callerID, Add(privateBalance, amount))
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.ConfidentialPayload{Account: callerID, Collection: collection, Amount: amount}})
+	return stub.SetEvent(erc20events.SHIELDED, json)
+}
+
+/*Unshield moves tokens from the confidential balance of the chaincode caller back to its public balance,
+the amount is public as the public balance changes.
+
+* `transient[TransientKey]` - the Input: `amount`, and the `counterparty` MSP of a bilateral collection.
+
+* `transient[SaltTransientKey]` - the random bytes the confidential balance is salted with.
+
+* `getBalanceOf` - specifies the function of getting the public balance of the caller.*/
+func (t *Token) Unshield(stub shim.ChaincodeStubInterface, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error {
+	callerID, input, amount, err := checkInput(stub)
+	if err != nil {
+		return err
+	}
+	collection := collectionOf(callerID, input.Counterparty)
+
+	logger.Infof("Unshield: moving %v tokens of %v from %v", amount, callerID, collection)
+
+	privateBalance, err := getPrivateBalance(stub, collection, callerID)
+	if err != nil {
+		return err
+	}
+	if err := IsSmallerOrEqual(amount, privateBalance); err != nil {
+		return fmt.Errorf("unshield amount should be less than confidential balance of %v: %v", callerID, err)
+	}
+	balance, err := GetCreditedBalance(stub, callerID, getBalanceOf)
+	if err != nil {
+		return err
+	}
+
+	err = PutPrivateBalanceState(stub, collection, callerID, Sub(privateBalance, amount))
+	if err != nil {
+		return err
+	}
+	change, err := CreditBalance(stub, callerID, balance, amount)
+	if err != nil {
+		return err
+	}
+	err = UpdateHolderCount(stub, change)
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.ConfidentialPayload{Account: callerID, Collection: collection, Amount: amount}})
+	return stub.SetEvent(erc20events.UNSHIELDED, json)
+}
+
+/*ConfidentialTransfer transfers tokens between the confidential balances of the chaincode caller & a registered receiver,
+in the collection of their MSP, or the bilateral collection of their MSPs. Its event only discloses the collection.
+
+* `transient[TransientKey]` - the Input: `receiver` & `amount`.
+
+* `transient[SaltTransientKey]` - the random bytes the confidential balances are salted with.
+
+* `getBalanceOf` - specifies the function of getting the public balance of an account, failing for unregistered accounts.*/
+func (t *Token) ConfidentialTransfer(stub shim.ChaincodeStubInterface, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error {
+	senderID, input, amount, err := checkInput(stub)
+	if err != nil {
+		return err
+	}
+	receiverID := input.Receiver
+	if receiverID == "" || receiverID == senderID {
+		return fmt.Errorf("invalid receiver %q", receiverID)
+	}
+	if _, err := getBalanceOf(stub, []string{receiverID}); err != nil {
+		return err
+	}
+	collection := collectionOf(senderID, strings.SplitN(receiverID, ",", 2)[0])
+
+	logger.Infof("ConfidentialTransfer: transferring in %v", collection)
+
+	balanceOfSender, err := getPrivateBalance(stub, collection, senderID)
+	if err != nil {
+		return err
+	}
+	balanceOfReceiver, err := getPrivateBalance(stub, collection, receiverID)
+	if err != nil {
+		return err
+	}
+	if err := IsSmallerOrEqual(amount, balanceOfSender); err != nil {
+		return fmt.Errorf("transfer amount should be less than confidential balance of sender (%v): %v", senderID, err)
+	}
+
+	err = PutPrivateBalanceState(stub, collection, senderID, Sub(balanceOfSender, amount))
+	if err != nil {
+		return err
+	}
+	err = PutPrivateBalanceState(stub, collection, receiverID, Add(balanceOfReceiver, amount))
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: senderID, Payload: erc20events.ConfidentialPayload{Collection: collection}})
+	return stub.SetEvent(erc20events.CONFIDENTIAL_TRANSFER, json)
+}
+
+//checkConfidentialMode returns an error unless the confidential balances are enabled
+func checkConfidentialMode(stub shim.ChaincodeStubInterface) error {
+	enabled, err := IsConfidentialMode(stub)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return fmt.Errorf("confidential balances are not enabled")
+	}
+	return nil
+}
+
+//checkInput returns the chaincode caller, the Input & its amount of a confidential transaction
+func checkInput(stub shim.ChaincodeStubInterface) (string, *Input, *big.Int, error) {
+	if err := checkConfidentialMode(stub); err != nil {
+		return "", nil, nil, err
+	}
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	input, err := GetInput(stub)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	//checked before any balance changes, PutPrivateBalanceState needs it
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(transient[SaltTransientKey]) < MinSaltEntropy {
+		return "", nil, nil, fmt.Errorf("confidential balances need at least %v random bytes in the %v transient entry", MinSaltEntropy, SaltTransientKey)
+	}
+	amount, ok := new(big.Int).SetString(input.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return "", nil, nil, fmt.Errorf("invalid amount %v, expected an integer > 0", input.Amount)
+	}
+	return callerID, input, amount, nil
+}
+
+//collectionOf returns the collection of the MSP of `accountID`, or its bilateral collection with `counterparty` if any
+func collectionOf(accountID string, counterparty string) string {
+	mspIDs := []string{strings.SplitN(accountID, ",", 2)[0]}
+	if counterparty != "" {
+		mspIDs = append(mspIDs, counterparty)
+	}
+	return CollectionOf(mspIDs...)
+}
+
+func getPrivateBalance(stub shim.ChaincodeStubInterface, collection string, accountID string) (*big.Int, error) {
+	balance, err := GetPrivateBalanceState(stub, collection, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return DecodePrivateBalance(balance)
+}
@@ -0,0 +1,20 @@
+package erc20confidential
+
+import (
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+/*ConfidentialTokenInterface consists of Shield & Unshield to move tokens between the public & confidential balances,
+ConfidentialTransfer between confidential balances, and GetConfidentialBalance to check state.
+The inputs of the transactions are passed in the transient map, so they are not written to the ledger*/
+type ConfidentialTokenInterface interface {
+	GetConfidentialBalance(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error)
+
+	Shield(stub shim.ChaincodeStubInterface, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error
+
+	Unshield(stub shim.ChaincodeStubInterface, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error
+
+	ConfidentialTransfer(stub shim.ChaincodeStubInterface, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error
+}
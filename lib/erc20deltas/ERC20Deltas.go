@@ -0,0 +1,102 @@
+package erc20deltas
+
+import (
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"erc20/lib/erc20roles"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("deltas-logger")
+
+/*Token deltas implements DeltasTokenInterface.
+The credits of a hot account are written to `Delta~[accountID]~[txID]` keys without reading its balance,
+so concurrent transfers to e.g. the treasury don't fail with MVCC_READ_CONFLICT. Its balance is the sum of its record & deltas,
+debits read both and compact the deltas, so they conflict with concurrent credits instead of overdrawing the account.
+Hot accounts count as holders while they are hot, whatever their balance.*/
+type Token struct{}
+
+/*IsHotAccount checks if an account is credited with delta keys.
+
+* `args[0]` - the account ID.*/
+func (t *Token) IsHotAccount(stub shim.ChaincodeStubInterface, args []string) (bool, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return false, err
+	}
+	return IsHotAccount(stub, args[0])
+}
+
+/*SetHotAccount makes a registered account hot or not, callable by members of the ADMIN role.
+The deltas of an account are compacted when it stops being hot.
+
+* `args[0]` - the account ID.
+
+* `args[1]` - `true` to credit the account with delta keys, `false` to go back to read-modify-writes of its balance.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) SetHotAccount(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	accountID := args[0]
+	hot, err := strconv.ParseBool(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid hot flag %v, expected true or false", args[1])
+	}
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	isHot, err := IsHotAccount(stub, accountID)
+	if err != nil {
+		return err
+	}
+	if isHot == hot {
+		return fmt.Errorf("account %v is already set to hot: %v", accountID, hot)
+	}
+	balance, err := CompactDeltas(stub, accountID)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("SetHotAccount: setting %v to hot: %v by %v", accountID, hot, callerID)
+
+	err = PutHotAccount(stub, accountID, hot)
+	if err != nil {
+		return err
+	}
+	//an empty hot account counts as a holder until it stops being hot
+	if balance.Sign() == 0 {
+		change := BalanceChange{After: big.NewInt(1)}
+		if !hot {
+			change = BalanceChange{Before: big.NewInt(1)}
+		}
+		if err := UpdateHolderCount(stub, change); err != nil {
+			return err
+		}
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.HotAccountPayload{Account: accountID, Hot: hot}})
+	return stub.SetEvent(erc20events.HOT_ACCOUNT_SET, json)
+}
+
+/*CompactBalance folds the deltas of an account into its record and returns its balance, callable by anyone as the balance doesn't change.
+Compacting conflicts with the credits of the account committed meanwhile, it's best run when the account is quiet.
+
+* `args[0]` - the account ID.*/
+func (t *Token) CompactBalance(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+	logger.Infof("CompactBalance: compacting the deltas of %v", args[0])
+	return CompactDeltas(stub, args[0])
+}
@@ -0,0 +1,20 @@
+package erc20deltas
+
+import (
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+/*DeltasTokenInterface consists of SetHotAccount (method should be restricted) to credit accounts with delta keys,
+IsHotAccount to check state, and CompactBalance to fold the deltas of an account into its record*/
+type DeltasTokenInterface interface {
+	IsHotAccount(stub shim.ChaincodeStubInterface, args []string) (bool, error)
+
+	SetHotAccount(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+
+	CompactBalance(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error)
+}
@@ -1,6 +1,8 @@
 package erc20detailed
 
 import (
+	. "erc20/helpers"
+
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 )
 
@@ -9,13 +11,13 @@ type Token struct{}
 
 /*GetName returns the name of the token*/
 func (t *Token) GetName(stub shim.ChaincodeStubInterface) (string, error) {
-	tokenNameBytes, err := stub.GetState("name")
+	tokenNameBytes, err := GetConfigState(stub, "name")
 	return string(tokenNameBytes), err
 }
 
 /*GetSymbol returns the symbol of the token*/
 func (t *Token) GetSymbol(stub shim.ChaincodeStubInterface) (string, error) {
-	tokenSymbolBytes, err := stub.GetState("symbol")
+	tokenSymbolBytes, err := GetConfigState(stub, "symbol")
 	return string(tokenSymbolBytes), err
 }
 
@@ -25,6 +27,6 @@ The decimals are only for visualization purposes.
 All the operations are done using the smallest and indivisible token unit,
 just as on Ethereum all the operations are done in wei.*/
 func (t *Token) GetDecimals(stub shim.ChaincodeStubInterface) (string, error) {
-	tokenDecimalsBytes, err := stub.GetState("decimals")
+	tokenDecimalsBytes, err := GetConfigState(stub, "decimals")
 	return string(tokenDecimalsBytes), err
 }
@@ -4,8 +4,50 @@ import "math/big"
 
 /*enums for event names*/
 const (
-	TRANSFER = "transfer"
-	APPROVAL = "approval"
+	TRANSFER     = "transfer"
+	APPROVAL     = "approval"
+	ROLE_GRANTED = "roleGranted"
+	ROLE_REVOKED = "roleRevoked"
+
+	PROPOSAL_CREATED  = "proposalCreated"
+	PROPOSAL_APPROVED = "proposalApproved"
+	PROPOSAL_REVOKED  = "proposalRevoked"
+	PROPOSAL_EXECUTED = "proposalExecuted"
+
+	OWNERSHIP_TRANSFER_STARTED  = "ownershipTransferStarted"
+	OWNERSHIP_TRANSFER_CANCELED = "ownershipTransferCanceled"
+	OWNERSHIP_TRANSFERRED       = "ownershipTransferred"
+
+	ALIAS_REGISTERED = "aliasRegistered"
+	ALIAS_RELEASED   = "aliasReleased"
+
+	MSP_LIST_MODE_CHANGED = "mspListModeChanged"
+	MSP_ADDED             = "mspAdded"
+	MSP_REMOVED           = "mspRemoved"
+
+	ACCOUNT_FROZEN   = "accountFrozen"
+	ACCOUNT_UNFROZEN = "accountUnfrozen"
+	ACCOUNT_CLOSED   = "accountClosed"
+
+	ACTIVATION_REQUESTED = "activationRequested"
+	ACTIVATION_APPROVED  = "activationApproved"
+	ACTIVATION_REJECTED  = "activationRejected"
+
+	MIGRATION_APPROVED = "migrationApproved"
+	ACCOUNT_MIGRATED   = "accountMigrated"
+
+	AUTHORIZED_OPERATOR = "authorizedOperator"
+	REVOKED_OPERATOR    = "revokedOperator"
+	SENT                = "sent"
+	BURNED              = "burned"
+
+	CLASS_CREATED = "classCreated"
+
+	HOT_ACCOUNT_SET = "hotAccountSet"
+
+	SHIELDED              = "shielded"
+	UNSHIELDED            = "unshielded"
+	CONFIDENTIAL_TRANSFER = "confidentialTransfer"
 )
 
 /*Payload of the event*/
@@ -15,8 +57,97 @@ type Payload struct {
 	Amount *big.Int `json:"amount"`
 }
 
+/*RolePayload of the role-change events*/
+type RolePayload struct {
+	Role    string `json:"role"`
+	Account string `json:"account"`
+}
+
+/*ProposalPayload of the multi-signature proposal events*/
+type ProposalPayload struct {
+	ID        string `json:"id"`
+	Method    string `json:"method"`
+	Approvals int    `json:"approvals"`
+}
+
+/*OwnershipPayload of the ownership events*/
+type OwnershipPayload struct {
+	PreviousOwner string `json:"previousOwner"`
+	NewOwner      string `json:"newOwner"`
+}
+
+/*AliasPayload of the alias registry events*/
+type AliasPayload struct {
+	Alias   string `json:"alias"`
+	Account string `json:"account"`
+}
+
+/*MSPListPayload of the MSP list events*/
+type MSPListPayload struct {
+	Mode  string `json:"mode,omitempty"`
+	MSPID string `json:"mspID,omitempty"`
+}
+
+/*FreezePayload of the account freeze events*/
+type FreezePayload struct {
+	Account string `json:"account"`
+	Reason  string `json:"reason"`
+}
+
+/*ClosurePayload of the account closure event*/
+type ClosurePayload struct {
+	Account string   `json:"account"`
+	SweptTo string   `json:"sweptTo"`
+	Amount  *big.Int `json:"amount"`
+}
+
+/*ActivationPayload of the activation request events*/
+type ActivationPayload struct {
+	Account string `json:"account"`
+}
+
+/*MigrationPayload of the account migration events*/
+type MigrationPayload struct {
+	OldID string `json:"oldID"`
+	NewID string `json:"newID"`
+}
+
+/*OperatorPayload of the operator authorization events*/
+type OperatorPayload struct {
+	Operator string `json:"operator"`
+	Holder   string `json:"holder"`
+}
+
+/*OperatorTransferPayload of the events of tokens sent or burnt by an operator*/
+type OperatorTransferPayload struct {
+	Operator string   `json:"operator"`
+	From     string   `json:"from"`
+	To       string   `json:"to,omitempty"`
+	Amount   *big.Int `json:"amount"`
+}
+
+/*ClassPayload of the token class events*/
+type ClassPayload struct {
+	Class string `json:"class"`
+	Owner string `json:"owner"`
+}
+
+/*HotAccountPayload of the event of an account becoming hot or not*/
+type HotAccountPayload struct {
+	Account string `json:"account"`
+	Hot     bool   `json:"hot"`
+}
+
+/*ConfidentialPayload of the confidential balance events, confidential transfers only disclose their collection*/
+type ConfidentialPayload struct {
+	Account    string   `json:"account,omitempty"`
+	Collection string   `json:"collection"`
+	Amount     *big.Int `json:"amount,omitempty"`
+}
+
 /*Event object to emit to clients, will be sent as JSON format*/
 type Event struct {
-	Origin  string  `json:"origin"` /*transaction invoker's ID*/
-	Payload Payload `json:"payload"`
-}
+	Origin  string      `json:"origin"`          /*transaction invoker's ID*/
+	Class   string      `json:"class,omitempty"` /*token class the event belongs to, empty for the default token*/
+	Payload interface{} `json:"payload"`
+}
\ No newline at end of file
@@ -0,0 +1,238 @@
+package erc20freezable
+
+import (
+	"encoding/json"
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"erc20/lib/erc20roles"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("freezable-logger")
+
+/*enums for freeze actions*/
+const (
+	FREEZE   = "FREEZE"
+	UNFREEZE = "UNFREEZE"
+)
+
+//objectType of the composite key `FreezeLog~[accountID]~[timestamp]~[txID]`, the history of every freeze action,
+//the timestamp is zero-padded so the actions are sorted chronologically
+const freezeLogObjectType = "FreezeLog"
+
+/*FreezeAction is held by the composite key `Frozen~[accountID]` (FrozenObjectType) of the accounts it froze,
+it is a freeze or unfreeze of an account by a member of the COMPLIANCE role*/
+type FreezeAction struct {
+	Action    string `json:"action"`
+	Account   string `json:"account"`
+	Reason    string `json:"reason"` /*reason code, e.g. SANCTIONS or COURT_ORDER*/
+	By        string `json:"by"`
+	TxID      string `json:"txID"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+/*Token freezable implements FreezableTokenInterface*/
+type Token struct{}
+
+/*IsFrozen checks if an account is frozen.
+
+* `args[0]` - the account ID.*/
+func (t *Token) IsFrozen(stub shim.ChaincodeStubInterface, args []string) (bool, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return false, err
+	}
+
+	frozenKey, err := stub.CreateCompositeKey(FrozenObjectType, []string{args[0]})
+	if err != nil {
+		return false, err
+	}
+	frozen, err := stub.GetState(frozenKey)
+	if err != nil {
+		return false, err
+	}
+	return len(frozen) != 0, nil
+}
+
+/*CheckNotFrozen returns an error if any of the accounts is frozen.
+
+* `args` - the account IDs.*/
+func (t *Token) CheckNotFrozen(stub shim.ChaincodeStubInterface, args []string) error {
+	for _, accountID := range args {
+		isFrozen, err := t.IsFrozen(stub, []string{accountID})
+		if err != nil {
+			return err
+		}
+		if isFrozen {
+			return fmt.Errorf("account %v is frozen", accountID)
+		}
+	}
+	return nil
+}
+
+/*GetFreezeLog returns every freeze & unfreeze action taken on an account, oldest first.
+
+* `args[0]` - the account ID.*/
+func (t *Token) GetFreezeLog(stub shim.ChaincodeStubInterface, args []string) ([]FreezeAction, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+
+	iterator, err := stub.GetStateByPartialCompositeKey(freezeLogObjectType, []string{args[0]})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	actions := []FreezeAction{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		action := FreezeAction{}
+		if err := json.Unmarshal(queryResult.GetValue(), &action); err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+/*FreezeAccount blocks an account from sending, receiving, burning & approving, callable by members of the COMPLIANCE role.
+
+* `args[0]` - the account ID.
+
+* `args[1]` - the reason code, e.g. SANCTIONS or COURT_ORDER.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.
+
+* `putFrozen` - specifies the function flagging the records of the account as frozen or not.*/
+func (t *Token) FreezeAccount(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	putFrozen func(stub shim.ChaincodeStubInterface, accountID string, frozen bool) error,
+) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	accountID := args[0]
+
+	isFrozen, err := t.IsFrozen(stub, []string{accountID})
+	if err != nil {
+		return err
+	}
+	if isFrozen {
+		return fmt.Errorf("account %v is already frozen", accountID)
+	}
+
+	action, err := logFreezeAction(stub, FREEZE, args, hasRole)
+	if err != nil {
+		return err
+	}
+
+	frozenKey, err := stub.CreateCompositeKey(FrozenObjectType, []string{accountID})
+	if err != nil {
+		return err
+	}
+	err = stub.PutState(frozenKey, MalshalJSON(action))
+	if err != nil {
+		return err
+	}
+	err = putFrozen(stub, accountID, true)
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: action.By, Payload: erc20events.FreezePayload{Account: accountID, Reason: action.Reason}})
+	return stub.SetEvent(erc20events.ACCOUNT_FROZEN, json)
+}
+
+/*UnfreezeAccount lifts the freeze of an account, callable by members of the COMPLIANCE role.
+
+* `args[0]` - the account ID.
+
+* `args[1]` - the reason code, e.g. CLEARED.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.
+
+* `putFrozen` - specifies the function flagging the records of the account as frozen or not.*/
+func (t *Token) UnfreezeAccount(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	putFrozen func(stub shim.ChaincodeStubInterface, accountID string, frozen bool) error,
+) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	accountID := args[0]
+
+	isFrozen, err := t.IsFrozen(stub, []string{accountID})
+	if err != nil {
+		return err
+	}
+	if !isFrozen {
+		return fmt.Errorf("account %v is not frozen", accountID)
+	}
+
+	action, err := logFreezeAction(stub, UNFREEZE, args, hasRole)
+	if err != nil {
+		return err
+	}
+
+	frozenKey, err := stub.CreateCompositeKey(FrozenObjectType, []string{accountID})
+	if err != nil {
+		return err
+	}
+	err = stub.DelState(frozenKey)
+	if err != nil {
+		return err
+	}
+	err = putFrozen(stub, accountID, false)
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: action.By, Payload: erc20events.FreezePayload{Account: accountID, Reason: action.Reason}})
+	return stub.SetEvent(erc20events.ACCOUNT_UNFROZEN, json)
+}
+
+//logFreezeAction checks the caller is a compliance officer and appends the action to the account's freeze log
+func logFreezeAction(stub shim.ChaincodeStubInterface,
+	actionName string,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) (*FreezeAction, error) {
+	accountID, reason := args[0], strings.TrimSpace(args[1])
+	if reason == "" {
+		return nil, fmt.Errorf("a reason code is required")
+	}
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.COMPLIANCE, hasRole)
+	if err != nil {
+		return nil, err
+	}
+
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("%v: %v by %v, reason %v", actionName, accountID, callerID, reason)
+
+	action := &FreezeAction{
+		Action:    actionName,
+		Account:   accountID,
+		Reason:    reason,
+		By:        callerID,
+		TxID:      stub.GetTxID(),
+		Timestamp: txTimestamp.GetSeconds(),
+	}
+	logKey, err := stub.CreateCompositeKey(freezeLogObjectType, []string{accountID, fmt.Sprintf("%019d", action.Timestamp), action.TxID})
+	if err != nil {
+		return nil, err
+	}
+	return action, stub.PutState(logKey, MalshalJSON(action))
+}
@@ -0,0 +1,24 @@
+package erc20freezable
+
+import "github.com/hyperledger/fabric/core/chaincode/shim"
+
+/*FreezableTokenInterface consists of FreezeAccount & UnfreezeAccount (methods should be restricted), IsFrozen, CheckNotFrozen & GetFreezeLog to check state*/
+type FreezableTokenInterface interface {
+	IsFrozen(stub shim.ChaincodeStubInterface, args []string) (bool, error)
+
+	CheckNotFrozen(stub shim.ChaincodeStubInterface, args []string) error
+
+	GetFreezeLog(stub shim.ChaincodeStubInterface, args []string) ([]FreezeAction, error)
+
+	FreezeAccount(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+		putFrozen func(stub shim.ChaincodeStubInterface, accountID string, frozen bool) error,
+	) error
+
+	UnfreezeAccount(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+		putFrozen func(stub shim.ChaincodeStubInterface, accountID string, frozen bool) error,
+	) error
+}
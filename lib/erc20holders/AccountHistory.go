@@ -0,0 +1,146 @@
+package erc20holders
+
+import (
+	"encoding/base64"
+	. "erc20/helpers"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+/*HistoryEntry is a change of the balance of an account: the balance after the transaction & the change,
+with the memo of the transaction if any*/
+type HistoryEntry struct {
+	TxID      string      `json:"txID"`
+	Timestamp int64       `json:"timestamp"`
+	Balance   *big.Int    `json:"balance"`
+	Delta     *big.Int    `json:"delta"`
+	Deleted   bool        `json:"deleted,omitempty"` /*the account was closed*/
+	Memo      *MemoRecord `json:"memo,omitempty"`
+}
+
+/*HistoryPage is a page of the balance history of an account, oldest first,
+`Bookmark` is passed to get the next page and is empty on the last one*/
+type HistoryPage struct {
+	History  []HistoryEntry `json:"history"`
+	Bookmark string         `json:"bookmark"`
+}
+
+/*GetAccountHistory returns the changes of the balance of an account from the history database of the peer,
+which must be enabled (`core.ledger.history.enableHistoryDatabase`). The changes of the record leaving the balance as is
+(freezes, metadata) are left out but for the closing of the account, and the credits of hot accounts show up when their deltas are compacted.
+The history is kept under the account ID of the time: a migrated account keeps its history up to the migration, with the memos
+moved to its new account, and the history of the new account starts with the migration.
+
+* `args[0]` - the account ID.
+
+* `args[1]` - the page size, DefaultPageSize if empty or missing.
+
+* `args[2]` - the bookmark returned with the previous page, empty or missing for the first page.
+
+* `args[3]` - the start of the time range in seconds since the epoch (inclusive), empty or missing for the first change.
+
+* `args[4]` - the end of the time range in seconds since the epoch (inclusive), empty or missing for the last change.
+
+* `resolveMigratedID` - specifies the function of getting the account the records of a migrated account were moved to.*/
+func (t *Token) GetAccountHistory(stub shim.ChaincodeStubInterface,
+	args []string,
+	resolveMigratedID func(shim.ChaincodeStubInterface, []string) (string, error),
+) (*HistoryPage, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("the account ID is required")
+	}
+	args = append(args, make([]string, 5)...)[:5]
+	accountID := args[0]
+	pageSize, err := ParsePageSize(args[1])
+	if err != nil {
+		return nil, err
+	}
+	lastTxID, err := base64.RawURLEncoding.DecodeString(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid bookmark %v", args[2])
+	}
+	from, to, err := parseTimeRange(args[3], args[4])
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("GetAccountHistory: getting %v changes of %v from %v to %v after %q", pageSize, accountID, from, to, args[2])
+
+	//the memos of a migrated account are moved to its new account
+	memoHolderID, err := resolveMigratedID(stub, []string{accountID})
+	if err != nil {
+		return nil, err
+	}
+	key, err := stub.CreateCompositeKey(BalanceObjectType, []string{accountID})
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := stub.GetHistoryForKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	page := &HistoryPage{History: []HistoryEntry{}}
+	//the deltas are computed from the start, the entries up to the bookmark are skipped
+	balance, skipping := big.NewInt(0), len(lastTxID) != 0
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		after := big.NewInt(0)
+		if !modification.GetIsDelete() {
+			account, err := DecodeAccountRecord(accountID, modification.GetValue())
+			if err != nil {
+				return nil, err
+			}
+			after = account.Balance
+		}
+		delta := Sub(after, balance)
+		balance = after
+
+		txID, timestamp := modification.GetTxId(), modification.GetTimestamp().GetSeconds()
+		if skipping {
+			skipping = txID != string(lastTxID)
+			continue
+		}
+		if (delta.Sign() == 0 && !modification.GetIsDelete()) || timestamp < from || timestamp > to {
+			continue
+		}
+		if len(page.History) == pageSize {
+			page.Bookmark = base64.RawURLEncoding.EncodeToString(lastTxID)
+			break
+		}
+		memo, err := GetMemoRecordOfTx(stub, memoHolderID, timestamp, txID)
+		if err != nil {
+			return nil, err
+		}
+		page.History = append(page.History, HistoryEntry{TxID: txID, Timestamp: timestamp, Balance: after, Delta: delta, Deleted: modification.GetIsDelete(), Memo: memo})
+		lastTxID = []byte(txID)
+	}
+	return page, nil
+}
+
+//parseTimeRange returns the bounds of a time range in seconds since the epoch, unbounded when empty
+func parseTimeRange(sFrom string, sTo string) (int64, int64, error) {
+	from, to := int64(0), int64(1<<63-1)
+	var err error
+	if sFrom != "" {
+		if from, err = strconv.ParseInt(sFrom, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid start time %v", sFrom)
+		}
+	}
+	if sTo != "" {
+		if to, err = strconv.ParseInt(sTo, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid end time %v", sTo)
+		}
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("the time range ends before it starts")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,207 @@
+package erc20holders
+
+import (
+	"bytes"
+	"encoding/json"
+	. "erc20/helpers"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//accountFields are the fields of Account a selector may match, with the JSON kind of their values
+var accountFields = map[string]string{
+	"account":      "string",
+	"msp":          "string",
+	"balance":      "number",
+	"activatedAt":  "number",
+	"activatedBy":  "string",
+	"frozen":       "bool",
+	"nonce":        "number",
+	"lastActivity": "number",
+}
+
+//selector operators applying to a field value, and combining selectors
+var (
+	valueOperators   = map[string]bool{"$eq": true, "$ne": true, "$gt": true, "$gte": true, "$lt": true, "$lte": true, "$in": true, "$nin": true, "$exists": true}
+	combineOperators = map[string]bool{"$and": true, "$or": true, "$nor": true}
+)
+
+//maxSelectorDepth & maxSelectorValues bound the size of the selectors
+const (
+	maxSelectorDepth  = 8
+	maxSelectorValues = 100
+)
+
+/*AccountsPage is a page of account records, `Bookmark` is passed to get the next page and is empty on the last one*/
+type AccountsPage struct {
+	Accounts []Account `json:"accounts"`
+	Bookmark string    `json:"bookmark"`
+}
+
+/*QueryAccounts runs a CouchDB rich query on the account records.
+
+* `args[0]` - the Mango selector, restricted to the fields of Account but `metadata`
+and to comparison, `$in`, `$nin`, `$exists`, `$and`, `$or` & `$nor` operators,
+e.g. `{"balance": {"$gte": 1000}}` or `{"frozen": true}`.
+CouchDB compares the balances, stored as JSON numbers, as 64-bit floats: selectors on `balance` are exact up to 2^53 only.
+
+* `args[1]` - the page size, DefaultPageSize if empty or missing.
+
+* `args[2]` - the bookmark returned with the previous page, empty or missing for the first page.*/
+func (t *Token) QueryAccounts(stub shim.ChaincodeStubInterface, args []string) (*AccountsPage, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("a selector is required")
+	}
+	args = append(args, make([]string, 3)...)[:3]
+	pageSize, err := ParsePageSize(args[1])
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(args[0])))
+	decoder.UseNumber()
+	selector := map[string]interface{}{}
+	if err := decoder.Decode(&selector); err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+	if err := checkSelector(selector, "", 0); err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+
+	//the documents of the other classes (or of the default token, in a class) are left out,
+	//the records of the default token have an empty class
+	scope := map[string]interface{}{"docType": AccountDocType, "class": ""}
+	if scoped, ok := stub.(ClassScoped); ok {
+		scope["class"] = scoped.ClassID()
+	}
+	query := MalshalJSON(map[string]interface{}{"selector": map[string]interface{}{"$and": []interface{}{scope, selector}}})
+
+	logger.Infof("QueryAccounts: querying %v accounts with %s after %q", pageSize, query, args[2])
+
+	iterator, metadata, err := stub.GetQueryResultWithPagination(string(query), int32(pageSize), args[2])
+	if err != nil {
+		return nil, err
+	}
+	if iterator == nil {
+		return nil, fmt.Errorf("rich queries are not supported by the state database")
+	}
+	defer iterator.Close()
+
+	page := &AccountsPage{Accounts: []Account{}}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		account := Account{}
+		if err := json.Unmarshal(queryResult.GetValue(), &account); err != nil {
+			return nil, err
+		}
+		page.Accounts = append(page.Accounts, account)
+	}
+	//CouchDB returns a bookmark for the last page too, it is cleared once a page is not full
+	if metadata != nil && len(page.Accounts) == pageSize {
+		page.Bookmark = metadata.GetBookmark()
+	}
+	return page, nil
+}
+
+//checkSelector checks that `selector` only matches the fields of Account with supported operators,
+//`field` being the field the selector applies to, empty at the top level & in combinations
+func checkSelector(selector map[string]interface{}, field string, depth int) error {
+	if depth > maxSelectorDepth {
+		return fmt.Errorf("selectors can not be nested more than %v levels", maxSelectorDepth)
+	}
+	for key, value := range selector {
+		switch {
+		case combineOperators[key]:
+			selectors, ok := value.([]interface{})
+			if !ok || len(selectors) == 0 || len(selectors) > maxSelectorValues {
+				return fmt.Errorf("%v expects from 1 to %v selectors", key, maxSelectorValues)
+			}
+			for _, s := range selectors {
+				nested, ok := s.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%v expects selectors", key)
+				}
+				if err := checkSelector(nested, field, depth+1); err != nil {
+					return err
+				}
+			}
+		case key == "$not":
+			nested, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("$not expects a selector")
+			}
+			if err := checkSelector(nested, field, depth+1); err != nil {
+				return err
+			}
+		case valueOperators[key]:
+			if field == "" {
+				return fmt.Errorf("%v applies to a field", key)
+			}
+			if err := checkOperand(key, field, value); err != nil {
+				return err
+			}
+		case accountFields[key] != "":
+			if field != "" {
+				return fmt.Errorf("field %v is nested in field %v", key, field)
+			}
+			if nested, ok := value.(map[string]interface{}); ok {
+				if err := checkSelector(nested, key, depth+1); err != nil {
+					return err
+				}
+			} else if err := checkValue(key, value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported field or operator %v", key)
+		}
+	}
+	return nil
+}
+
+//checkOperand checks the operand of a value operator on `field`
+func checkOperand(operator string, field string, operand interface{}) error {
+	switch operator {
+	case "$exists":
+		if _, ok := operand.(bool); !ok {
+			return fmt.Errorf("$exists expects a boolean")
+		}
+		return nil
+	case "$in", "$nin":
+		values, ok := operand.([]interface{})
+		if !ok || len(values) > maxSelectorValues {
+			return fmt.Errorf("%v expects up to %v values", operator, maxSelectorValues)
+		}
+		for _, value := range values {
+			if err := checkValue(field, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return checkValue(field, operand)
+}
+
+//checkValue checks that `value` has the JSON kind of `field`
+func checkValue(field string, value interface{}) error {
+	kind := accountFields[field]
+	switch v := value.(type) {
+	case string:
+		if kind == "string" {
+			return nil
+		}
+	case json.Number:
+		//balances are integers, CouchDB compares them as 64-bit floats, exactly up to 2^53 only
+		if _, ok := new(big.Int).SetString(v.String(), 10); ok && kind == "number" {
+			return nil
+		}
+	case bool:
+		if kind == "bool" {
+			return nil
+		}
+	}
+	return fmt.Errorf("field %v expects a %v value, got %v", field, kind, value)
+}
@@ -0,0 +1,162 @@
+package erc20holders
+
+import (
+	. "erc20/helpers"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("holders-logger")
+
+//bounds of the free-form metadata of the account records
+const (
+	maxMetadataEntries     = 32
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 256
+)
+
+/*Holder is an account with a positive balance*/
+type Holder struct {
+	Account string   `json:"account"`
+	Balance *big.Int `json:"balance"`
+}
+
+/*HoldersPage is a page of holders, ordered by account ID, `Bookmark` is passed to get the next page and is empty on the last one*/
+type HoldersPage struct {
+	Holders  []Holder `json:"holders"`
+	Bookmark string   `json:"bookmark"`
+}
+
+/*Token holders implements HoldersTokenInterface*/
+type Token struct{}
+
+/*GetHolders returns a page of the accounts with a positive balance, every arg is optional.
+The page is read from `pageSize` balances, the filters may leave fewer holders in it while more pages follow.
+
+* `args[0]` - the page size, DefaultPageSize if empty.
+
+* `args[1]` - the bookmark returned with the previous page, empty for the first page.
+
+* `args[2]` - the minimum balance of the holders.
+
+* `args[3]` - the MSP ID of the holders.*/
+func (t *Token) GetHolders(stub shim.ChaincodeStubInterface, args []string) (*HoldersPage, error) {
+	args = append(args, make([]string, 4)...)[:4]
+	pageSize, err := ParsePageSize(args[0])
+	if err != nil {
+		return nil, err
+	}
+	bookmark, mspID := args[1], args[3]
+	minBalance := big.NewInt(1)
+	if args[2] != "" {
+		if err := CheckGreaterThanZero(args[2]); err != nil {
+			return nil, fmt.Errorf("invalid minimum balance: %v", err)
+		}
+		minBalance = StringToBigInt(args[2])
+	}
+
+	logger.Infof("GetHolders: getting %v holders of MSP %q with at least %v tokens after %q", pageSize, mspID, minBalance, bookmark)
+
+	page := &HoldersPage{Holders: []Holder{}}
+	page.Bookmark, err = QueryPage(stub, BalanceObjectType, []string{}, pageSize, bookmark, func(keyAttributes []string, value []byte) error {
+		accountID, balance := keyAttributes[0], BufferToBigInt(value)
+		//hot accounts also hold their deltas
+		hot, err := IsHotAccount(stub, accountID)
+		if err != nil {
+			return err
+		}
+		if hot {
+			total, err := GetBalanceState(stub, accountID)
+			if err != nil {
+				return err
+			}
+			balance = BufferToBigInt(total)
+		}
+		if balance.Cmp(minBalance) < 0 {
+			return nil
+		}
+		if mspID != "" && strings.SplitN(accountID, ",", 2)[0] != mspID {
+			return nil
+		}
+		page.Holders = append(page.Holders, Holder{Account: accountID, Balance: balance})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+/*GetAccount returns the record of an account: balance, activation, freeze, nonce, last activity & metadata.
+
+* `args[0]` - the account ID.*/
+func (t *Token) GetAccount(stub shim.ChaincodeStubInterface, args []string) (*Account, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+	account, err := GetAccountRecord(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%v is not registered", args[0])
+	}
+	return account, nil
+}
+
+/*SetAccountMetadata sets a free-form metadata entry of the chaincode caller's own account.
+
+* `args[0]` - the metadata key.
+
+* `args[1]` - the value, an empty value removes the entry.*/
+func (t *Token) SetAccountMetadata(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	key, value := args[0], args[1]
+	if key == "" || len(key) > maxMetadataKeyLength || len(value) > maxMetadataValueLength {
+		return fmt.Errorf("metadata keys should have 1 to %v characters and values up to %v", maxMetadataKeyLength, maxMetadataValueLength)
+	}
+
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	account, err := GetAccountRecord(stub, callerID)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return fmt.Errorf("%v is not registered", callerID)
+	}
+
+	logger.Infof("SetAccountMetadata: setting %v of %v to %q", key, callerID, value)
+
+	if value == "" {
+		delete(account.Metadata, key)
+	} else {
+		if account.Metadata == nil {
+			account.Metadata = map[string]string{}
+		}
+		account.Metadata[key] = value
+		if len(account.Metadata) > maxMetadataEntries {
+			return fmt.Errorf("accounts can not have more than %v metadata entries", maxMetadataEntries)
+		}
+	}
+	return PutAccountRecord(stub, account)
+}
+
+/*GetHolderCount returns the number of accounts with a positive balance*/
+func (t *Token) GetHolderCount(stub shim.ChaincodeStubInterface) (int64, error) {
+	return GetHolderCount(stub)
+}
+
+/*CompactHolderCount folds the changes of the holder count recorded by each transaction into the count and returns it,
+callable by anyone as the count doesn't change*/
+func (t *Token) CompactHolderCount(stub shim.ChaincodeStubInterface) (int64, error) {
+	logger.Infof("CompactHolderCount: compacting the holder count")
+	return CompactHolderCount(stub)
+}
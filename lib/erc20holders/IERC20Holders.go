@@ -0,0 +1,29 @@
+package erc20holders
+
+import (
+	. "erc20/helpers"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+/*HoldersTokenInterface consists of GetHolders to page through the accounts holding tokens,
+GetHolderCount, maintained by the transfers, mints & burns, and CompactHolderCount, GetAccount & QueryAccounts to read the account records,
+GetAccountHistory for the changes of their balance, and SetAccountMetadata for the caller's own account*/
+type HoldersTokenInterface interface {
+	GetHolders(stub shim.ChaincodeStubInterface, args []string) (*HoldersPage, error)
+
+	GetHolderCount(stub shim.ChaincodeStubInterface) (int64, error)
+
+	CompactHolderCount(stub shim.ChaincodeStubInterface) (int64, error)
+
+	GetAccount(stub shim.ChaincodeStubInterface, args []string) (*Account, error)
+
+	SetAccountMetadata(stub shim.ChaincodeStubInterface, args []string) error
+
+	QueryAccounts(stub shim.ChaincodeStubInterface, args []string) (*AccountsPage, error)
+
+	GetAccountHistory(stub shim.ChaincodeStubInterface,
+		args []string,
+		resolveMigratedID func(shim.ChaincodeStubInterface, []string) (string, error),
+	) (*HistoryPage, error)
+}
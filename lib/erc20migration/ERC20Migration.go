@@ -0,0 +1,311 @@
+package erc20migration
+
+import (
+	"encoding/json"
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
+)
+
+var logger = shim.NewLogger("migration-logger")
+
+/*enums for what happens to transfers sent to a migrated account*/
+const (
+	REDIRECT = "redirect" /*transfers are sent to the new account*/
+	REJECT   = "reject"   /*transfers fail, naming the new account*/
+)
+
+//objectTypes of the composite keys `Migration~[oldID]` (pending approvals) & `Forwarding~[oldID]`
+const (
+	migrationObjectType  = "Migration"
+	forwardingObjectType = "Forwarding"
+)
+
+/*Migration is a migration waiting for both the MSP admin of the old account and the token owner*/
+type Migration struct {
+	OldID      string `json:"oldID"`
+	NewID      string `json:"newID"`
+	Mode       string `json:"mode"`
+	MSPAdminID string `json:"mspAdminID,omitempty"`
+	OwnerID    string `json:"ownerID,omitempty"`
+}
+
+/*Forwarding is left behind by a migrated account*/
+type Forwarding struct {
+	OldID      string `json:"oldID"`
+	NewID      string `json:"newID"`
+	Mode       string `json:"mode"`
+	MigratedBy string `json:"migratedBy"`
+	MigratedAt int64  `json:"migratedAt"`
+}
+
+/*Token migration implements MigrationTokenInterface*/
+type Token struct{}
+
+/*GetMigration returns the migration of an account waiting for approvals, nil if there is none.
+
+* `args[0]` - the old account ID.*/
+func (t *Token) GetMigration(stub shim.ChaincodeStubInterface, args []string) (*Migration, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+	migration := &Migration{}
+	found, err := getJSON(stub, migrationObjectType, args[0], migration)
+	if err != nil || !found {
+		return nil, err
+	}
+	return migration, nil
+}
+
+/*GetForwarding returns the forwarding record of a migrated account, nil if the account was not migrated.
+
+* `args[0]` - the old account ID.*/
+func (t *Token) GetForwarding(stub shim.ChaincodeStubInterface, args []string) (*Forwarding, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+	forwarding := &Forwarding{}
+	found, err := getJSON(stub, forwardingObjectType, args[0], forwarding)
+	if err != nil || !found {
+		return nil, err
+	}
+	return forwarding, nil
+}
+
+/*ResolveForwarding returns the account transfers to an account should be sent to:
+the account itself, or the new account of a migrated account in `redirect` mode.
+Migrated accounts in `reject` mode, and forwardings looping back to an account of the chain, return an error.
+
+* `args[0]` - the account ID.*/
+func (t *Token) ResolveForwarding(stub shim.ChaincodeStubInterface, args []string) (string, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return "", err
+	}
+	return t.followForwardings(stub, args[0], true)
+}
+
+/*ResolveMigratedID returns the account an account finally migrated to whatever the modes of the forwardings,
+the account itself if it did not migrate: the records of the account, e.g. its memos, are moved there.
+Forwardings looping back to an account of the chain return an error.
+
+* `args[0]` - the account ID.*/
+func (t *Token) ResolveMigratedID(stub shim.ChaincodeStubInterface, args []string) (string, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return "", err
+	}
+	return t.followForwardings(stub, args[0], false)
+}
+
+//followForwardings follows the chained migrations of `accountID`, failing on the ones in `reject` mode if `rejects`
+func (t *Token) followForwardings(stub shim.ChaincodeStubInterface, accountID string, rejects bool) (string, error) {
+	startID := accountID
+	visited := map[string]bool{accountID: true}
+	for {
+		forwarding, err := t.GetForwarding(stub, []string{accountID})
+		if err != nil {
+			return "", err
+		}
+		if forwarding == nil {
+			return accountID, nil
+		}
+		if rejects && forwarding.Mode == REJECT {
+			return "", fmt.Errorf("account %v has migrated to %v", forwarding.OldID, forwarding.NewID)
+		}
+		if visited[forwarding.NewID] {
+			return "", fmt.Errorf("the forwarding of %v loops back to %v", startID, forwarding.NewID)
+		}
+		visited[forwarding.NewID] = true
+		accountID = forwarding.NewID
+	}
+}
+
+/*MigrateAccount moves an account to a new ID, e.g. after its certificate is reissued by another CA.
+The new ID can't be an account that migrated already.
+It is authorized by the old identity itself, to an account activated already (so the activation policy applies to it),
+or by both the MSP admin of the old account and the token owner, two different identities
+(the migration runs once the second of them calls MigrateAccount with the same arguments).
+
+* `args[0]` - the old account ID.
+
+* `args[1]` - the new account ID.
+
+* `args[2]` - optional, `redirect` (default) or `reject`: what happens to transfers sent to the old account.
+
+* `getOwner` - specifies the function of getting the token owner.
+
+* `getBalanceOf` - specifies the function of getting the balance of an account, failing for unregistered accounts.
+
+* `migrate` - specifies the function moving the balance, allowances & other records of the account.*/
+func (t *Token) MigrateAccount(stub shim.ChaincodeStubInterface,
+	args []string,
+	getOwner func(shim.ChaincodeStubInterface) (string, error),
+	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+	migrate func(stub shim.ChaincodeStubInterface, oldID string, newID string) error,
+) error {
+	if len(args) == 2 {
+		args = append(args, REDIRECT)
+	}
+	if err := CheckArgsLength(args, 3); err != nil {
+		return err
+	}
+	oldID, newID, mode := args[0], args[1], args[2]
+	if mode != REDIRECT && mode != REJECT {
+		return fmt.Errorf("unknown forwarding mode %v, expected one of: %v, %v", mode, REDIRECT, REJECT)
+	}
+	if oldID == newID {
+		return fmt.Errorf("can not migrate %v to itself", oldID)
+	}
+	forwarding, err := t.GetForwarding(stub, []string{oldID})
+	if err != nil {
+		return err
+	}
+	if forwarding != nil {
+		return fmt.Errorf("account %v has already migrated to %v", oldID, forwarding.NewID)
+	}
+	//a migrated account can't be given another one, its forwarding would loop back
+	forwarding, err = t.GetForwarding(stub, []string{newID})
+	if err != nil {
+		return err
+	}
+	if forwarding != nil {
+		return fmt.Errorf("account %v has migrated to %v, it can not be migrated to", newID, forwarding.NewID)
+	}
+
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+
+	if callerID != oldID {
+		migration, err := t.approve(stub, oldID, newID, mode, callerID, getOwner)
+		if err != nil {
+			return err
+		}
+		if migration.MSPAdminID == "" || migration.OwnerID == "" {
+			json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.MigrationPayload{OldID: oldID, NewID: newID}})
+			return stub.SetEvent(erc20events.MIGRATION_APPROVED, json)
+		}
+	} else if _, err := getBalanceOf(stub, []string{newID}); err != nil {
+		return fmt.Errorf("%v must be activated before %v migrates to it: %v", newID, oldID, err)
+	}
+	//the old identity may migrate itself while approvals are pending
+	migrationKey, err := stub.CreateCompositeKey(migrationObjectType, []string{oldID})
+	if err != nil {
+		return err
+	}
+	if err := stub.DelState(migrationKey); err != nil {
+		return err
+	}
+
+	logger.Infof("MigrateAccount: migrating %v to %v by %v", oldID, newID, callerID)
+
+	if err := migrate(stub, oldID, newID); err != nil {
+		return err
+	}
+
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	forwardingKey, err := stub.CreateCompositeKey(forwardingObjectType, []string{oldID})
+	if err != nil {
+		return err
+	}
+	err = stub.PutState(forwardingKey, MalshalJSON(Forwarding{OldID: oldID, NewID: newID, Mode: mode, MigratedBy: callerID, MigratedAt: txTimestamp.GetSeconds()}))
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.MigrationPayload{OldID: oldID, NewID: newID}})
+	return stub.SetEvent(erc20events.ACCOUNT_MIGRATED, json)
+}
+
+//approve records the caller's approval as token owner or MSP admin of the old account, and returns the pending migration.
+//A caller filling both roles approves as one of them, the other role takes another identity
+func (t *Token) approve(stub shim.ChaincodeStubInterface,
+	oldID string,
+	newID string,
+	mode string,
+	callerID string,
+	getOwner func(shim.ChaincodeStubInterface) (string, error),
+) (*Migration, error) {
+	migration, err := t.GetMigration(stub, []string{oldID})
+	if err != nil {
+		return nil, err
+	}
+	if migration == nil {
+		migration = &Migration{OldID: oldID, NewID: newID, Mode: mode}
+	}
+	if migration.NewID != newID || migration.Mode != mode {
+		return nil, fmt.Errorf("a migration of %v to %v (%v) is already pending", oldID, migration.NewID, migration.Mode)
+	}
+
+	owner, err := getOwner(stub)
+	if err != nil {
+		return nil, err
+	}
+	isMSPAdmin, err := isCallerMSPAdmin(stub, strings.SplitN(oldID, ",", 2)[0])
+	if err != nil {
+		return nil, err
+	}
+	if callerID != owner && !isMSPAdmin {
+		return nil, fmt.Errorf("%v is neither %v, its MSP admin nor the token owner", callerID, oldID)
+	}
+	switch {
+	case migration.OwnerID == callerID || migration.MSPAdminID == callerID:
+		return nil, fmt.Errorf("%v has already approved the migration of %v", callerID, oldID)
+	case callerID == owner && migration.OwnerID == "":
+		migration.OwnerID = callerID
+	case isMSPAdmin && migration.MSPAdminID == "":
+		migration.MSPAdminID = callerID
+	default:
+		return nil, fmt.Errorf("the migration of %v is already approved in the role of %v", oldID, callerID)
+	}
+
+	migrationKey, err := stub.CreateCompositeKey(migrationObjectType, []string{oldID})
+	if err != nil {
+		return nil, err
+	}
+	return migration, stub.PutState(migrationKey, MalshalJSON(migration))
+}
+
+//isCallerMSPAdmin checks if the chaincode caller belongs to `mspID` with the `admin` NodeOU (or Idemix role)
+func isCallerMSPAdmin(stub shim.ChaincodeStubInterface, mspID string) (bool, error) {
+	callerMSPID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return false, err
+	}
+	if callerMSPID != mspID {
+		return false, nil
+	}
+	ous, err := GetCallerNodeOUs(stub)
+	if err != nil {
+		return false, err
+	}
+	for _, ou := range ous {
+		if ou == "admin" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func getJSON(stub shim.ChaincodeStubInterface, objectType string, id string, v interface{}) (bool, error) {
+	key, err := stub.CreateCompositeKey(objectType, []string{id})
+	if err != nil {
+		return false, err
+	}
+	valueBytes, err := stub.GetState(key)
+	if err != nil {
+		return false, err
+	}
+	if len(valueBytes) == 0 {
+		return false, nil
+	}
+	return true, json.Unmarshal(valueBytes, v)
+}
@@ -0,0 +1,26 @@
+package erc20migration
+
+import (
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+/*MigrationTokenInterface consists of MigrateAccount (restricted to the old identity, or its MSP admin plus another identity owning the token),
+GetMigration, GetForwarding, ResolveForwarding & ResolveMigratedID to check state*/
+type MigrationTokenInterface interface {
+	GetMigration(stub shim.ChaincodeStubInterface, args []string) (*Migration, error)
+
+	GetForwarding(stub shim.ChaincodeStubInterface, args []string) (*Forwarding, error)
+
+	ResolveForwarding(stub shim.ChaincodeStubInterface, args []string) (string, error)
+
+	ResolveMigratedID(stub shim.ChaincodeStubInterface, args []string) (string, error)
+
+	MigrateAccount(stub shim.ChaincodeStubInterface,
+		args []string,
+		getOwner func(shim.ChaincodeStubInterface) (string, error),
+		getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+		migrate func(stub shim.ChaincodeStubInterface, oldID string, newID string) error,
+	) error
+}
@@ -3,6 +3,7 @@ package erc20mintable
 import (
 	. "erc20/helpers"
 	"erc20/lib/erc20events"
+	"erc20/lib/erc20roles"
 	"math/big"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
@@ -13,20 +14,20 @@ var logger = shim.NewLogger("init-logger")
 /*Token mintable implements MintableTokenInterface*/
 type Token struct{}
 
-/*Mint tokens, add to the `total supply` and `balance` of minter. This function call only be called by token owner.
+/*Mint tokens, add to the `total supply` and `balance` of minter. This function call only be called by members of the MINTER role.
 
 * `args[0]` - the ID of minter.
 
 * `args[1]` - the mint amount.
 
-* `getOwner` - specifies the function of getting the current owner of token.
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.
 
-* `getBalanceOf` - specifies the function of getting the initial balance of minter.
+* `getBalanceOf` - specifies the function of getting the initial balance of minter, hot minters are credited without reading their balance.
 
 * `getTotalSupply` - specifies the function of getting the current total supply of tokens.*/
 func (t *Token) Mint(stub shim.ChaincodeStubInterface,
 	args []string,
-	getOwner func(shim.ChaincodeStubInterface) (string, error),
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
 	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
 	getTotalSupply func(shim.ChaincodeStubInterface) (*big.Int, error),
 ) error {
@@ -39,21 +40,12 @@ func (t *Token) Mint(stub shim.ChaincodeStubInterface,
 	}
 	transferAmount := StringToBigInt(value)
 
-	callerID, err := GetCallerID(stub)
+	callerID, err := CheckCallerIsMember(stub, erc20roles.MINTER, hasRole)
 	if err != nil {
 		return err
 	}
 
-	tokenOwnerID, err := getOwner(stub)
-	if err != nil {
-		return err
-	}
-
-	if err := CheckCallerIsOwner(callerID, tokenOwnerID); err != nil {
-		return err
-	}
-
-	balanceMinter, err := getBalanceOf(stub, []string{minterID})
+	balanceMinter, err := GetCreditedBalance(stub, minterID, getBalanceOf)
 	if err != nil {
 		return err
 	}
@@ -69,11 +61,15 @@ func (t *Token) Mint(stub shim.ChaincodeStubInterface,
 		return err
 	}
 
-	err = stub.PutState("totalSupply", []byte(Add(totalSupplyAmount, transferAmount).String()))
+	err = PutConfigState(stub, "totalSupply", []byte(Add(totalSupplyAmount, transferAmount).String()))
+	if err != nil {
+		return err
+	}
+	minterChange, err := CreditBalance(stub, minterID, balanceMinter, transferAmount)
 	if err != nil {
 		return err
 	}
-	err = stub.PutState(minterID, []byte(Add(balanceMinter, transferAmount).String()))
+	err = UpdateHolderCount(stub, minterChange)
 	if err != nil {
 		return err
 	}
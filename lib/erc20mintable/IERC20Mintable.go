@@ -10,7 +10,7 @@ import (
 type MintableTokenInterface interface {
 	Mint(stub shim.ChaincodeStubInterface,
 		args []string,
-		getOwner func(shim.ChaincodeStubInterface) (string, error),
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
 		getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
 		getTotalSupply func(shim.ChaincodeStubInterface) (*big.Int, error),
 	) error
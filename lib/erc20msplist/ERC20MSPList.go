@@ -0,0 +1,209 @@
+package erc20msplist
+
+import (
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"erc20/lib/erc20roles"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("msplist-logger")
+
+/*enums for list modes*/
+const (
+	NONE  = "none"  /*every MSP may hold tokens*/
+	ALLOW = "allow" /*only the listed MSPs may hold tokens*/
+	DENY  = "deny"  /*the listed MSPs may not hold tokens*/
+)
+
+//world-state key of the list mode
+const modeKey = "mspListMode"
+
+//objectType of the composite key `MSP~[mspID]`
+const mspObjectType = "MSP"
+
+/*MSPList is the list of MSP IDs and how it applies*/
+type MSPList struct {
+	Mode   string   `json:"mode"`
+	MSPIDs []string `json:"mspIDs"`
+}
+
+/*Token msplist implements MSPListTokenInterface*/
+type Token struct{}
+
+/*GetMSPList returns the list mode (`none` by default) and the listed MSP IDs*/
+func (t *Token) GetMSPList(stub shim.ChaincodeStubInterface) (*MSPList, error) {
+	mode, err := GetConfigState(stub, modeKey)
+	if err != nil {
+		return nil, err
+	}
+	list := &MSPList{Mode: string(mode), MSPIDs: []string{}}
+	if list.Mode == "" {
+		list.Mode = NONE
+	}
+
+	iterator, err := stub.GetStateByPartialCompositeKey(mspObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := stub.SplitCompositeKey(queryResult.GetKey())
+		if err != nil {
+			return nil, err
+		}
+		list.MSPIDs = append(list.MSPIDs, attributes[0])
+	}
+	return list, nil
+}
+
+/*CheckAccountMSPs returns an error if the MSP (the ID prefix before the first comma) of any account is not allowed to hold tokens.
+
+* `args` - the account IDs.*/
+func (t *Token) CheckAccountMSPs(stub shim.ChaincodeStubInterface, args []string) error {
+	mode, err := GetConfigState(stub, modeKey)
+	if err != nil {
+		return err
+	}
+	if len(mode) == 0 || string(mode) == NONE {
+		return nil
+	}
+
+	for _, accountID := range args {
+		mspID := strings.SplitN(accountID, ",", 2)[0]
+		mspKey, err := stub.CreateCompositeKey(mspObjectType, []string{mspID})
+		if err != nil {
+			return err
+		}
+		listed, err := stub.GetState(mspKey)
+		if err != nil {
+			return err
+		}
+		if (string(mode) == ALLOW) != (len(listed) != 0) {
+			return fmt.Errorf("accounts of MSP %v are not allowed to hold tokens", mspID)
+		}
+	}
+	return nil
+}
+
+/*SetMSPListMode changes how the MSP list applies, callable by members of the ADMIN role.
+
+* `args[0]` - `allow` (allowlist), `deny` (denylist) or `none` (disabled).
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) SetMSPListMode(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	mode := args[0]
+	switch mode {
+	case NONE, ALLOW, DENY:
+	default:
+		return fmt.Errorf("unknown MSP list mode %v, expected one of: %v, %v, %v", mode, NONE, ALLOW, DENY)
+	}
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("SetMSPListMode: setting mode %v by %v", mode, callerID)
+
+	err = PutConfigState(stub, modeKey, []byte(mode))
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.MSPListPayload{Mode: mode}})
+	return stub.SetEvent(erc20events.MSP_LIST_MODE_CHANGED, json)
+}
+
+/*AddMSP puts an MSP ID on the list, callable by members of the ADMIN role.
+
+* `args[0]` - the MSP ID.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) AddMSP(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	mspID := args[0]
+	if strings.TrimSpace(mspID) == "" || strings.Contains(mspID, ",") {
+		return fmt.Errorf("invalid MSP ID %v", mspID)
+	}
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("AddMSP: listing %v by %v", mspID, callerID)
+
+	mspKey, err := stub.CreateCompositeKey(mspObjectType, []string{mspID})
+	if err != nil {
+		return err
+	}
+	err = stub.PutState(mspKey, []byte(callerID))
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.MSPListPayload{MSPID: mspID}})
+	return stub.SetEvent(erc20events.MSP_ADDED, json)
+}
+
+/*RemoveMSP takes an MSP ID off the list, callable by members of the ADMIN role.
+
+* `args[0]` - the MSP ID.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) RemoveMSP(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	mspID := args[0]
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	mspKey, err := stub.CreateCompositeKey(mspObjectType, []string{mspID})
+	if err != nil {
+		return err
+	}
+	listed, err := stub.GetState(mspKey)
+	if err != nil {
+		return err
+	}
+	if len(listed) == 0 {
+		return fmt.Errorf("MSP %v is not listed", mspID)
+	}
+
+	logger.Infof("RemoveMSP: unlisting %v by %v", mspID, callerID)
+
+	err = stub.DelState(mspKey)
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.MSPListPayload{MSPID: mspID}})
+	return stub.SetEvent(erc20events.MSP_REMOVED, json)
+}
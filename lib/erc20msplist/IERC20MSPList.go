@@ -0,0 +1,25 @@
+package erc20msplist
+
+import "github.com/hyperledger/fabric/core/chaincode/shim"
+
+/*MSPListTokenInterface consists of SetMSPListMode, AddMSP & RemoveMSP (methods should be restricted), GetMSPList & CheckAccountMSPs to check state*/
+type MSPListTokenInterface interface {
+	GetMSPList(stub shim.ChaincodeStubInterface) (*MSPList, error)
+
+	CheckAccountMSPs(stub shim.ChaincodeStubInterface, args []string) error
+
+	SetMSPListMode(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+
+	AddMSP(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+
+	RemoveMSP(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+}
@@ -0,0 +1,415 @@
+package erc20multisig
+
+import (
+	"encoding/json"
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"erc20/lib/erc20roles"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("multisig-logger")
+
+//DefaultTTL is the number of seconds a proposal stays open when the policy does not specify one (7 days)
+const DefaultTTL int64 = 7 * 24 * 60 * 60
+
+/*enums for proposal status*/
+const (
+	PENDING  = "pending"
+	APPROVED = "approved"
+	EXECUTED = "executed"
+	EXPIRED  = "expired"
+)
+
+//objectType of the composite key `Proposal~[proposalID]`
+const proposalObjectType = "Proposal"
+
+/*Policy of the M-of-N approval, stored as JSON under the "multiSigPolicy" key*/
+type Policy struct {
+	Signers   []string `json:"signers"`
+	Threshold int      `json:"threshold"`
+	TTL       int64    `json:"ttl"` /*seconds before an open proposal expires*/
+}
+
+/*Proposal is a pending owner-only function call, waiting for enough signers' approvals*/
+type Proposal struct {
+	ID        string   `json:"id"`
+	Method    string   `json:"method"`
+	Args      []string `json:"args"`
+	Proposer  string   `json:"proposer"`
+	Approvals []string `json:"approvals"`
+	CreatedAt int64    `json:"createdAt"`
+	ExpiresAt int64    `json:"expiresAt"`
+	Executed  bool     `json:"executed"`
+	Status    string   `json:"status,omitempty"` /*computed when read, not stored*/
+}
+
+/*Token multisig implements MultiSigTokenInterface*/
+type Token struct{}
+
+/*IsMultiSigEnabled returns true when a policy with a threshold is set*/
+func (t *Token) IsMultiSigEnabled(stub shim.ChaincodeStubInterface) (bool, error) {
+	policy, err := t.GetMultiSigPolicy(stub)
+	if err != nil {
+		return false, err
+	}
+	return policy.Threshold > 0, nil
+}
+
+/*GetMultiSigPolicy returns the current policy, or an empty (disabled) policy if never set*/
+func (t *Token) GetMultiSigPolicy(stub shim.ChaincodeStubInterface) (*Policy, error) {
+	policyBytes, err := GetConfigState(stub, "multiSigPolicy")
+	if err != nil {
+		return nil, err
+	}
+	policy := &Policy{Signers: []string{}}
+	if len(policyBytes) == 0 {
+		return policy, nil
+	}
+	if err := json.Unmarshal(policyBytes, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+/*SetMultiSigPolicy sets the signers & threshold of owner-only functions, callable by members of the ADMIN role.
+Once enabled, changing the policy again must go through an approved proposal itself.
+A threshold of 0 disables the multi-signature approval.
+
+* `args[0]` - JSON-formatted policy, e.g: `{"signers": ["id1", "id2", "id3"], "threshold": 2, "ttl": 86400}`
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) SetMultiSigPolicy(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	policy := &Policy{}
+	if err := json.Unmarshal([]byte(args[0]), policy); err != nil {
+		return fmt.Errorf("invalid multi-signature policy: %v", err)
+	}
+	if err := checkPolicy(policy); err != nil {
+		return err
+	}
+	if policy.TTL == 0 {
+		policy.TTL = DefaultTTL
+	}
+
+	logger.Infof("SetMultiSigPolicy: %v of %v signers by %v", policy.Threshold, len(policy.Signers), callerID)
+
+	return PutConfigState(stub, "multiSigPolicy", MalshalJSON(policy))
+}
+
+/*Propose stores a new proposal for an owner-only function, the proposer's approval is counted right away.
+Returns the ID of the proposal, which is the ID of the proposing transaction.
+
+* `args[0]` - the name of the function to call, e.g. "Mint".
+
+* `args[1:]` - the arguments of that function.
+
+* `proposable` - the function names that can be proposed.*/
+func (t *Token) Propose(stub shim.ChaincodeStubInterface, args []string, proposable []string) (string, error) {
+	if len(args) < 1 {
+		return "", fmt.Errorf("invalid number of arguments. Expected at least 1, got %v", len(args))
+	}
+	method, methodArgs := args[0], args[1:]
+
+	if !contains(proposable, method) {
+		return "", fmt.Errorf("%v can not be proposed, expected one of %v", method, proposable)
+	}
+
+	policy, callerID, err := t.getSigner(stub)
+	if err != nil {
+		return "", err
+	}
+
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+
+	proposal := &Proposal{
+		ID:        stub.GetTxID(),
+		Method:    method,
+		Args:      methodArgs,
+		Proposer:  callerID,
+		Approvals: []string{callerID},
+		CreatedAt: txTimestamp.GetSeconds(),
+		ExpiresAt: txTimestamp.GetSeconds() + policy.TTL,
+	}
+
+	logger.Infof("Propose: %v proposes %v %v as %v", callerID, method, methodArgs, proposal.ID)
+
+	if err := putProposal(stub, proposal); err != nil {
+		return "", err
+	}
+	return proposal.ID, setProposalEvent(stub, erc20events.PROPOSAL_CREATED, callerID, proposal)
+}
+
+/*Approve adds the caller's approval to an open proposal, callable by signers of the policy.
+
+* `args[0]` - the ID of the proposal.*/
+func (t *Token) Approve(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+
+	_, callerID, err := t.getSigner(stub)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := t.getOpenProposal(stub, args[0])
+	if err != nil {
+		return err
+	}
+	if contains(proposal.Approvals, callerID) {
+		return fmt.Errorf("%v has already approved proposal %v", callerID, proposal.ID)
+	}
+
+	logger.Infof("Approve: %v approves proposal %v", callerID, proposal.ID)
+
+	proposal.Approvals = append(proposal.Approvals, callerID)
+	if err := putProposal(stub, proposal); err != nil {
+		return err
+	}
+	return setProposalEvent(stub, erc20events.PROPOSAL_APPROVED, callerID, proposal)
+}
+
+/*Revoke withdraws the caller's approval from an open proposal.
+
+* `args[0]` - the ID of the proposal.*/
+func (t *Token) Revoke(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+
+	_, callerID, err := t.getSigner(stub)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := t.getOpenProposal(stub, args[0])
+	if err != nil {
+		return err
+	}
+	if !contains(proposal.Approvals, callerID) {
+		return fmt.Errorf("%v has not approved proposal %v", callerID, proposal.ID)
+	}
+
+	logger.Infof("Revoke: %v revokes approval of proposal %v", callerID, proposal.ID)
+
+	approvals := []string{}
+	for _, approver := range proposal.Approvals {
+		if approver != callerID {
+			approvals = append(approvals, approver)
+		}
+	}
+	proposal.Approvals = approvals
+	if err := putProposal(stub, proposal); err != nil {
+		return err
+	}
+	return setProposalEvent(stub, erc20events.PROPOSAL_REVOKED, callerID, proposal)
+}
+
+/*Execute runs a proposal once enough signers have approved it, callable by signers of the policy.
+Only approvals of the current signers count towards the current threshold.
+
+* `args[0]` - the ID of the proposal.
+
+* `execute` - specifies the function that calls the proposed function with its arguments.*/
+func (t *Token) Execute(stub shim.ChaincodeStubInterface,
+	args []string,
+	execute func(shim.ChaincodeStubInterface, string, []string) error,
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+
+	policy, callerID, err := t.getSigner(stub)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := t.getOpenProposal(stub, args[0])
+	if err != nil {
+		return err
+	}
+	if approvals := countApprovals(policy, proposal); approvals < policy.Threshold {
+		return fmt.Errorf("proposal %v has %v of %v required approvals", proposal.ID, approvals, policy.Threshold)
+	}
+
+	logger.Infof("Execute: %v executes proposal %v (%v %v)", callerID, proposal.ID, proposal.Method, proposal.Args)
+
+	proposal.Executed = true
+	if err := putProposal(stub, proposal); err != nil {
+		return err
+	}
+	//only one event is kept per transaction, so the event of the executed function (if any) replaces this one
+	if err := setProposalEvent(stub, erc20events.PROPOSAL_EXECUTED, callerID, proposal); err != nil {
+		return err
+	}
+	if err := execute(stub, proposal.Method, proposal.Args); err != nil {
+		return fmt.Errorf("failed to execute proposal %v: %v", proposal.ID, err)
+	}
+	return nil
+}
+
+/*GetProposal returns a proposal with its current status.
+
+* `args[0]` - the ID of the proposal.*/
+func (t *Token) GetProposal(stub shim.ChaincodeStubInterface, args []string) (*Proposal, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+
+	proposal, err := getProposal(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	policy, err := t.GetMultiSigPolicy(stub)
+	if err != nil {
+		return nil, err
+	}
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case proposal.Executed:
+		proposal.Status = EXECUTED
+	case txTimestamp.GetSeconds() > proposal.ExpiresAt:
+		proposal.Status = EXPIRED
+	case countApprovals(policy, proposal) >= policy.Threshold:
+		proposal.Status = APPROVED
+	default:
+		proposal.Status = PENDING
+	}
+	return proposal, nil
+}
+
+//getSigner returns the current policy and the caller's ID, if the caller is one of its signers
+func (t *Token) getSigner(stub shim.ChaincodeStubInterface) (*Policy, string, error) {
+	policy, err := t.GetMultiSigPolicy(stub)
+	if err != nil {
+		return nil, "", err
+	}
+	if policy.Threshold == 0 {
+		return nil, "", fmt.Errorf("multi-signature approval is not enabled")
+	}
+
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return nil, "", err
+	}
+	if !contains(policy.Signers, callerID) {
+		return nil, "", fmt.Errorf("%v is not a signer of the multi-signature policy", callerID)
+	}
+	return policy, callerID, nil
+}
+
+//getOpenProposal returns a proposal that is neither executed nor expired
+func (t *Token) getOpenProposal(stub shim.ChaincodeStubInterface, proposalID string) (*Proposal, error) {
+	proposal, err := getProposal(stub, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Executed {
+		return nil, fmt.Errorf("proposal %v is already executed", proposalID)
+	}
+
+	txTimestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	if txTimestamp.GetSeconds() > proposal.ExpiresAt {
+		return nil, fmt.Errorf("proposal %v has expired", proposalID)
+	}
+	return proposal, nil
+}
+
+func getProposal(stub shim.ChaincodeStubInterface, proposalID string) (*Proposal, error) {
+	proposalKey, err := stub.CreateCompositeKey(proposalObjectType, []string{proposalID})
+	if err != nil {
+		return nil, err
+	}
+	proposalBytes, err := stub.GetState(proposalKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(proposalBytes) == 0 {
+		return nil, fmt.Errorf("proposal %v not found", proposalID)
+	}
+
+	proposal := &Proposal{}
+	if err := json.Unmarshal(proposalBytes, proposal); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+func putProposal(stub shim.ChaincodeStubInterface, proposal *Proposal) error {
+	proposalKey, err := stub.CreateCompositeKey(proposalObjectType, []string{proposal.ID})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(proposalKey, MalshalJSON(proposal))
+}
+
+func setProposalEvent(stub shim.ChaincodeStubInterface, eventName string, callerID string, proposal *Proposal) error {
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.ProposalPayload{
+		ID:        proposal.ID,
+		Method:    proposal.Method,
+		Approvals: len(proposal.Approvals),
+	}})
+	return stub.SetEvent(eventName, json)
+}
+
+//countApprovals counts the approvals given by current signers of `policy`
+func countApprovals(policy *Policy, proposal *Proposal) int {
+	count := 0
+	for _, approver := range proposal.Approvals {
+		if contains(policy.Signers, approver) {
+			count++
+		}
+	}
+	return count
+}
+
+//checkPolicy validates that signers are distinct and the threshold is reachable
+func checkPolicy(policy *Policy) error {
+	seen := map[string]bool{}
+	for _, signer := range policy.Signers {
+		if signer == "" || seen[signer] {
+			return fmt.Errorf("signers must be distinct non-empty IDs, got %v", policy.Signers)
+		}
+		seen[signer] = true
+	}
+	if policy.Threshold < 0 || policy.Threshold > len(policy.Signers) {
+		return fmt.Errorf("threshold should be between 0 and %v, got %v", len(policy.Signers), policy.Threshold)
+	}
+	if policy.TTL < 0 {
+		return fmt.Errorf("ttl should be >= 0, got %v", policy.TTL)
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
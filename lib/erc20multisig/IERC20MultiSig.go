@@ -0,0 +1,28 @@
+package erc20multisig
+
+import "github.com/hyperledger/fabric/core/chaincode/shim"
+
+/*MultiSigTokenInterface consists of the M-of-N proposal flow (Propose, Approve, Revoke & Execute) guarding owner-only functions*/
+type MultiSigTokenInterface interface {
+	IsMultiSigEnabled(stub shim.ChaincodeStubInterface) (bool, error)
+
+	GetMultiSigPolicy(stub shim.ChaincodeStubInterface) (*Policy, error)
+
+	SetMultiSigPolicy(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+
+	Propose(stub shim.ChaincodeStubInterface, args []string, proposable []string) (string, error)
+
+	Approve(stub shim.ChaincodeStubInterface, args []string) error
+
+	Revoke(stub shim.ChaincodeStubInterface, args []string) error
+
+	Execute(stub shim.ChaincodeStubInterface,
+		args []string,
+		execute func(shim.ChaincodeStubInterface, string, []string) error,
+	) error
+
+	GetProposal(stub shim.ChaincodeStubInterface, args []string) (*Proposal, error)
+}
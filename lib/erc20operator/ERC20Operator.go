@@ -0,0 +1,305 @@
+package erc20operator
+
+import (
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"erc20/lib/erc20roles"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("operator-logger")
+
+//objectType of the composite key `Operator~[holderID]~[operatorID]`
+const operatorObjectType = "Operator"
+
+/*Token operator implements OperatorTokenInterface, refer to https://eips.ethereum.org/EIPS/eip-777 for the operator semantics*/
+type Token struct{}
+
+/*IsOperatorFor tells whether an account can send & burn the tokens of a holder, a holder is always an operator for itself.
+
+* `args[0]` - the ID of the operator.
+
+* `args[1]` - the ID of the holder.*/
+func (t *Token) IsOperatorFor(stub shim.ChaincodeStubInterface, args []string) (bool, error) {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return false, err
+	}
+	operatorID, holderID := args[0], args[1]
+	if operatorID == holderID {
+		return true, nil
+	}
+
+	operatorKey, err := stub.CreateCompositeKey(operatorObjectType, []string{holderID, operatorID})
+	if err != nil {
+		return false, err
+	}
+	value, err := stub.GetState(operatorKey)
+	if err != nil {
+		return false, err
+	}
+	return len(value) != 0, nil
+}
+
+/*AuthorizeOperator makes an account an operator of the chaincode caller, both accounts must be activated.
+
+* `args[0]` - the ID of the operator.
+
+* `getBalanceOf` - specifies the function of getting the balance of an account, failing for unregistered accounts.*/
+func (t *Token) AuthorizeOperator(stub shim.ChaincodeStubInterface,
+	args []string,
+	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	operatorID := args[0]
+
+	holderID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	if operatorID == holderID {
+		return fmt.Errorf("%v can not authorize itself as operator", holderID)
+	}
+	for _, accountID := range []string{holderID, operatorID} {
+		if _, err := getBalanceOf(stub, []string{accountID}); err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("AuthorizeOperator: authorizing %v for %v", operatorID, holderID)
+
+	operatorKey, err := stub.CreateCompositeKey(operatorObjectType, []string{holderID, operatorID})
+	if err != nil {
+		return err
+	}
+	//the value is not used, but an empty value would delete the key
+	if err := stub.PutState(operatorKey, []byte{0x00}); err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: holderID, Payload: erc20events.OperatorPayload{Operator: operatorID, Holder: holderID}})
+	return stub.SetEvent(erc20events.AUTHORIZED_OPERATOR, json)
+}
+
+/*RevokeOperator removes an operator of the chaincode caller.
+
+* `args[0]` - the ID of the operator.*/
+func (t *Token) RevokeOperator(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	operatorID := args[0]
+
+	holderID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	if operatorID == holderID {
+		return fmt.Errorf("%v can not revoke itself as operator", holderID)
+	}
+	isOperator, err := t.IsOperatorFor(stub, []string{operatorID, holderID})
+	if err != nil {
+		return err
+	}
+	if !isOperator {
+		return fmt.Errorf("%v is not an operator for %v", operatorID, holderID)
+	}
+
+	logger.Infof("RevokeOperator: revoking %v for %v", operatorID, holderID)
+
+	operatorKey, err := stub.CreateCompositeKey(operatorObjectType, []string{holderID, operatorID})
+	if err != nil {
+		return err
+	}
+	if err := stub.DelState(operatorKey); err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: holderID, Payload: erc20events.OperatorPayload{Operator: operatorID, Holder: holderID}})
+	return stub.SetEvent(erc20events.REVOKED_OPERATOR, json)
+}
+
+/*OperatorSend transfers tokens of a holder, the chaincode caller must be an operator for the holder.
+
+* `args[0]` - the ID of the holder.
+
+* `args[1]` - the ID of the receiver.
+
+* `args[2]` - the transfer amount.
+
+* `getBalanceOf` - specifies the function of getting the balance of an account, failing for unregistered accounts, hot receivers are credited without reading their balance.*/
+func (t *Token) OperatorSend(stub shim.ChaincodeStubInterface,
+	args []string,
+	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+) error {
+	if err := CheckArgsLength(args, 3); err != nil {
+		return err
+	}
+	holderID, receiverID, sValue := args[0], args[1], args[2]
+
+	sendAmount, ok := new(big.Int).SetString(sValue, 10)
+	if !ok || sendAmount.Sign() <= 0 {
+		return fmt.Errorf("invalid send amount %v, expected an integer > 0", sValue)
+	}
+	if holderID == receiverID {
+		return fmt.Errorf("can not send from %v to itself", holderID)
+	}
+	operatorID, err := t.checkCallerIsOperatorFor(stub, holderID)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("OperatorSend: sending %v tokens from %v to %v by %v", sendAmount, holderID, receiverID, operatorID)
+
+	balanceOfHolder, err := getBalanceOf(stub, []string{holderID})
+	if err != nil {
+		return err
+	}
+	balanceOfReceiver, err := GetCreditedBalance(stub, receiverID, getBalanceOf)
+	if err != nil {
+		return err
+	}
+	if err := IsSmallerOrEqual(sendAmount, balanceOfHolder); err != nil {
+		return fmt.Errorf("send amount should be less than balance of holder (%v): %v", holderID, err)
+	}
+
+	holderChange, err := DebitBalance(stub, holderID, balanceOfHolder, sendAmount)
+	if err != nil {
+		return err
+	}
+	receiverChange, err := CreditBalance(stub, receiverID, balanceOfReceiver, sendAmount)
+	if err != nil {
+		return err
+	}
+	err = UpdateHolderCount(stub, holderChange, receiverChange)
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: operatorID, Payload: erc20events.OperatorTransferPayload{Operator: operatorID, From: holderID, To: receiverID, Amount: sendAmount}})
+	return stub.SetEvent(erc20events.SENT, json)
+}
+
+/*OperatorBurn destroys tokens of a holder and total supply, the chaincode caller must be an operator for the holder
+and a member of the BURNER role.
+
+* `args[0]` - the ID of the holder.
+
+* `args[1]` - the burn amount.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.
+
+* `getBalanceOf` - specifies the function of getting the balance of an account, failing for unregistered accounts.*/
+func (t *Token) OperatorBurn(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+	getTotalSupply func(shim.ChaincodeStubInterface) (*big.Int, error),
+) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	holderID, sValue := args[0], args[1]
+
+	burnAmount, ok := new(big.Int).SetString(sValue, 10)
+	if !ok || burnAmount.Sign() <= 0 {
+		return fmt.Errorf("invalid burn amount %v, expected an integer > 0", sValue)
+	}
+	operatorID, err := t.checkCallerIsOperatorFor(stub, holderID)
+	if err != nil {
+		return err
+	}
+	if _, err := CheckCallerIsMember(stub, erc20roles.BURNER, hasRole); err != nil {
+		return err
+	}
+
+	logger.Infof("OperatorBurn: burning %v tokens from %v by %v", burnAmount, holderID, operatorID)
+
+	balanceOfHolder, err := getBalanceOf(stub, []string{holderID})
+	if err != nil {
+		return err
+	}
+	if err := IsSmallerOrEqual(burnAmount, balanceOfHolder); err != nil {
+		return fmt.Errorf("burn amount should be less than balance of holder (%v): %v", holderID, err)
+	}
+
+	holderChange, err := DebitBalance(stub, holderID, balanceOfHolder, burnAmount)
+	if err != nil {
+		return err
+	}
+	err = UpdateHolderCount(stub, holderChange)
+	if err != nil {
+		return err
+	}
+
+	totalSupply, err := getTotalSupply(stub)
+	if err != nil {
+		return err
+	}
+	if err := IsSmallerOrEqual(burnAmount, totalSupply); err != nil {
+		return fmt.Errorf("burn amount should be less than total supply (%v): %v", totalSupply, err)
+	}
+
+	err = PutConfigState(stub, "totalSupply", []byte(Sub(totalSupply, burnAmount).String()))
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: operatorID, Payload: erc20events.OperatorTransferPayload{Operator: operatorID, From: holderID, Amount: burnAmount}})
+	return stub.SetEvent(erc20events.BURNED, json)
+}
+
+/*ClearOperators removes every operator authorized by a holder.
+
+* `args[0]` - the ID of the holder.*/
+func (t *Token) ClearOperators(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	holderID := args[0]
+
+	iterator, err := stub.GetStateByPartialCompositeKey(operatorObjectType, []string{holderID})
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+
+	//collect the keys first, deleting them while iterating would change the range being read
+	operatorKeys := []string{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		operatorKeys = append(operatorKeys, queryResult.GetKey())
+	}
+
+	for _, operatorKey := range operatorKeys {
+		logger.Infof("ClearOperators: removing %v", operatorKey)
+
+		if err := stub.DelState(operatorKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//checkCallerIsOperatorFor returns the chaincode caller ID if it is an operator for the holder
+func (t *Token) checkCallerIsOperatorFor(stub shim.ChaincodeStubInterface, holderID string) (string, error) {
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return "", err
+	}
+	isOperator, err := t.IsOperatorFor(stub, []string{callerID, holderID})
+	if err != nil {
+		return "", err
+	}
+	if !isOperator {
+		return "", fmt.Errorf("%v is not an operator for %v", callerID, holderID)
+	}
+	return callerID, nil
+}
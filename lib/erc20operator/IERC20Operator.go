@@ -0,0 +1,35 @@
+package erc20operator
+
+import (
+	"math/big"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+/*OperatorTokenInterface consists of AuthorizeOperator & RevokeOperator for the caller's own account,
+OperatorSend & OperatorBurn restricted to the operators of the holder (and the burners for OperatorBurn),
+ClearOperators for closed & migrated accounts, and IsOperatorFor to check state*/
+type OperatorTokenInterface interface {
+	IsOperatorFor(stub shim.ChaincodeStubInterface, args []string) (bool, error)
+
+	AuthorizeOperator(stub shim.ChaincodeStubInterface,
+		args []string,
+		getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+	) error
+
+	RevokeOperator(stub shim.ChaincodeStubInterface, args []string) error
+
+	OperatorSend(stub shim.ChaincodeStubInterface,
+		args []string,
+		getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+	) error
+
+	OperatorBurn(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+		getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+		getTotalSupply func(shim.ChaincodeStubInterface) (*big.Int, error),
+	) error
+
+	ClearOperators(stub shim.ChaincodeStubInterface, args []string) error
+}
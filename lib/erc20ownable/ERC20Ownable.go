@@ -2,40 +2,222 @@ package erc20ownable
 
 import (
 	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"fmt"
+	"strings"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 )
 
+var logger = shim.NewLogger("ownable-logger")
+
 /*Token ownable implements OwnableTokenInterface*/
 type Token struct{}
 
-/*GetOwner returns the owner ID of token*/
+/*GetOwner returns the owner ID of token, empty if the ownership has been renounced*/
 func (t *Token) GetOwner(stub shim.ChaincodeStubInterface) (string, error) {
-	owner, err := stub.GetState("owner")
+	owner, err := GetConfigState(stub, "owner")
 	return string(owner), err
 }
 
-/*TransferOwnership allows the current owner to transfer control of the contract to a new owner.
+/*GetPendingOwner returns the ID that has been offered the ownership but not accepted it yet, empty if there is none*/
+func (t *Token) GetPendingOwner(stub shim.ChaincodeStubInterface) (string, error) {
+	pendingOwner, err := GetConfigState(stub, "pendingOwner")
+	return string(pendingOwner), err
+}
+
+/*TransferOwnership allows the current owner to offer control of the contract to a new owner.
+The ownership only changes when the new owner calls AcceptOwnership, so a mistyped ID can not take over the token.
 
 * `args[0]` - the ID of the new owner.
 
-* `getOwner` - specifies the function of getting the current owner of token.*/
+* `getOwner` - specifies the function of getting the owner allowed to transfer.*/
 func (t *Token) TransferOwnership(stub shim.ChaincodeStubInterface,
 	args []string,
 	getOwner func(shim.ChaincodeStubInterface) (string, error),
 ) error {
-	callerID, err := GetCallerID(stub)
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+
+	tokenOwnerID, err := t.GetOwner(stub)
 	if err != nil {
 		return err
 	}
+	if tokenOwnerID == "" {
+		return fmt.Errorf("ownership has been renounced, it can not be transferred")
+	}
 
-	tokenOwnerID, err := getOwner(stub)
-	if err := CheckCallerIsOwner(callerID, tokenOwnerID); err != nil {
+	allowedOwnerID, err := getOwner(stub)
+	if err != nil {
+		return err
+	}
+	if err := CheckCallerIsOwner(callerID, allowedOwnerID); err != nil {
 		return err
 	}
 
 	newOwnerID := args[0]
+	if strings.TrimSpace(newOwnerID) == "" {
+		return fmt.Errorf("new owner ID should not be empty, use RenounceOwnership instead")
+	}
+
+	logger.Infof("TransferOwnership: offering ownership from %v to %v by %v...", tokenOwnerID, newOwnerID, callerID)
+
+	err = PutConfigState(stub, "pendingOwner", []byte(newOwnerID))
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.OwnershipPayload{PreviousOwner: tokenOwnerID, NewOwner: newOwnerID}})
+	return stub.SetEvent(erc20events.OWNERSHIP_TRANSFER_STARTED, json)
+}
+
+/*AcceptOwnership completes the ownership transfer, callable by the pending owner only.
+The roles of the previous owner are transferred to the new owner, who is at least granted ADMIN.
+
+* `transferRoles` - specifies the function of moving the roles of an identity to another one, or revoking them.*/
+func (t *Token) AcceptOwnership(stub shim.ChaincodeStubInterface,
+	transferRoles func(shim.ChaincodeStubInterface, []string) error,
+) error {
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+
+	pendingOwnerID, err := t.GetPendingOwner(stub)
+	if err != nil {
+		return err
+	}
+	if pendingOwnerID == "" {
+		return fmt.Errorf("there is no pending ownership transfer")
+	}
+	if pendingOwnerID != callerID {
+		return fmt.Errorf("Function only accessible to pending owner: %v", pendingOwnerID)
+	}
+
+	tokenOwnerID, err := t.GetOwner(stub)
+	if err != nil {
+		return err
+	}
+	if tokenOwnerID == "" {
+		return fmt.Errorf("ownership has been renounced, it can not be accepted")
+	}
+
+	logger.Infof("AcceptOwnership: ownership transferred from %v to %v", tokenOwnerID, callerID)
+
+	err = PutConfigState(stub, "owner", []byte(callerID))
+	if err != nil {
+		return err
+	}
+	err = DelConfigState(stub, "pendingOwner")
+	if err != nil {
+		return err
+	}
+	err = transferRoles(stub, []string{tokenOwnerID, callerID})
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.OwnershipPayload{PreviousOwner: tokenOwnerID, NewOwner: callerID}})
+	return stub.SetEvent(erc20events.OWNERSHIP_TRANSFERRED, json)
+}
+
+/*CancelOwnershipTransfer withdraws the pending ownership offer, callable by the current owner only.
+
+* `getOwner` - specifies the function of getting the owner allowed to cancel.*/
+func (t *Token) CancelOwnershipTransfer(stub shim.ChaincodeStubInterface,
+	getOwner func(shim.ChaincodeStubInterface) (string, error),
+) error {
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+
+	allowedOwnerID, err := getOwner(stub)
+	if err != nil {
+		return err
+	}
+	if err := CheckCallerIsOwner(callerID, allowedOwnerID); err != nil {
+		return err
+	}
+
+	pendingOwnerID, err := t.GetPendingOwner(stub)
+	if err != nil {
+		return err
+	}
+	if pendingOwnerID == "" {
+		return fmt.Errorf("there is no pending ownership transfer")
+	}
+
+	tokenOwnerID, err := t.GetOwner(stub)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("CancelOwnershipTransfer: offer to %v canceled by %v", pendingOwnerID, callerID)
+
+	err = DelConfigState(stub, "pendingOwner")
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.OwnershipPayload{PreviousOwner: tokenOwnerID, NewOwner: pendingOwnerID}})
+	return stub.SetEvent(erc20events.OWNERSHIP_TRANSFER_CANCELED, json)
+}
+
+/*RenounceOwnership leaves the contract without owner, callable by the current owner only.
+This is irreversible: the roles of the owner are revoked, the ownership can no longer be transferred,
+and without an owner the chaincode can no longer be upgraded.
+It fails while the owner is the only member of the ADMIN role, which would leave no one to grant the roles.
+
+* `getOwner` - specifies the function of getting the owner allowed to renounce.
+
+* `transferRoles` - specifies the function of moving the roles of an identity to another one, or revoking them.*/
+func (t *Token) RenounceOwnership(stub shim.ChaincodeStubInterface,
+	getOwner func(shim.ChaincodeStubInterface) (string, error),
+	transferRoles func(shim.ChaincodeStubInterface, []string) error,
+) error {
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+
+	allowedOwnerID, err := getOwner(stub)
+	if err != nil {
+		return err
+	}
+	if err := CheckCallerIsOwner(callerID, allowedOwnerID); err != nil {
+		return err
+	}
+
+	tokenOwnerID, err := t.GetOwner(stub)
+	if err != nil {
+		return err
+	}
+	if tokenOwnerID == "" {
+		return fmt.Errorf("ownership has already been renounced")
+	}
+
+	logger.Noticef("RenounceOwnership: %v renounces the ownership", tokenOwnerID)
+
+	err = transferRoles(stub, []string{tokenOwnerID, ""})
+	if err != nil {
+		return err
+	}
+	err = DelConfigState(stub, "owner")
+	if err != nil {
+		return err
+	}
+	err = DelConfigState(stub, "pendingOwner")
+	if err != nil {
+		return err
+	}
 
-	err = stub.PutState("owner", []byte(newOwnerID))
-	return err
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.OwnershipPayload{PreviousOwner: tokenOwnerID, NewOwner: ""}})
+	return stub.SetEvent(erc20events.OWNERSHIP_TRANSFERRED, json)
 }
@@ -1,13 +1,28 @@
-package erc20ownable
-
-import "github.com/hyperledger/fabric/core/chaincode/shim"
-
-/*OwnableTokenInterface consists of GetOwner & TransferOwnership*/
-type OwnableTokenInterface interface {
-	GetOwner(stub shim.ChaincodeStubInterface) (string, error)
-
-	TransferOwnership(stub shim.ChaincodeStubInterface,
-		args []string,
-		getOwner func(shim.ChaincodeStubInterface) (string, error),
-	) error
-}
+package erc20ownable
+
+import "github.com/hyperledger/fabric/core/chaincode/shim"
+
+/*OwnableTokenInterface consists of GetOwner and the two-step TransferOwnership & AcceptOwnership (with CancelOwnershipTransfer & RenounceOwnership)*/
+type OwnableTokenInterface interface {
+	GetOwner(stub shim.ChaincodeStubInterface) (string, error)
+
+	GetPendingOwner(stub shim.ChaincodeStubInterface) (string, error)
+
+	TransferOwnership(stub shim.ChaincodeStubInterface,
+		args []string,
+		getOwner func(shim.ChaincodeStubInterface) (string, error),
+	) error
+
+	AcceptOwnership(stub shim.ChaincodeStubInterface,
+		transferRoles func(shim.ChaincodeStubInterface, []string) error,
+	) error
+
+	CancelOwnershipTransfer(stub shim.ChaincodeStubInterface,
+		getOwner func(shim.ChaincodeStubInterface) (string, error),
+	) error
+
+	RenounceOwnership(stub shim.ChaincodeStubInterface,
+		getOwner func(shim.ChaincodeStubInterface) (string, error),
+		transferRoles func(shim.ChaincodeStubInterface, []string) error,
+	) error
+}
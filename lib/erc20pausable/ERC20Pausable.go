@@ -2,6 +2,7 @@ package erc20pausable
 
 import (
 	. "erc20/helpers"
+	"erc20/lib/erc20roles"
 	"strconv"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
@@ -12,7 +13,7 @@ type Token struct{}
 
 /*IsPaused get the "isPaused" state of token*/
 func (t *Token) IsPaused(stub shim.ChaincodeStubInterface) (bool, error) {
-	isPaused, err := stub.GetState("isPaused")
+	isPaused, err := GetConfigState(stub, "isPaused")
 	if err != nil {
 		return true, err
 	}
@@ -23,44 +24,24 @@ func (t *Token) IsPaused(stub shim.ChaincodeStubInterface) (bool, error) {
 	return strconv.ParseBool(string(isPaused))
 }
 
-/*Pause freezes the transfer/approve functions of the token, callable by token owner*/
+/*Pause freezes the transfer/approve functions of the token, callable by members of the PAUSER role*/
 func (t *Token) Pause(stub shim.ChaincodeStubInterface,
-	getOwner func(shim.ChaincodeStubInterface) (string, error),
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
 ) error {
-	callerID, err := GetCallerID(stub)
-	if err != nil {
-		return err
-	}
-
-	tokenOwnerID, err := getOwner(stub)
-	if err != nil {
-		return err
-	}
-
-	if err := CheckCallerIsOwner(callerID, tokenOwnerID); err != nil {
+	if _, err := CheckCallerIsMember(stub, erc20roles.PAUSER, hasRole); err != nil {
 		return err
 	}
 
-	return stub.PutState("isPaused", []byte("true"))
+	return PutConfigState(stub, "isPaused", []byte("true"))
 }
 
-/*Unpause un-freezes the transfer/approve functions of the token, callable by token owner*/
+/*Unpause un-freezes the transfer/approve functions of the token, callable by members of the PAUSER role*/
 func (t *Token) Unpause(stub shim.ChaincodeStubInterface,
-	getOwner func(shim.ChaincodeStubInterface) (string, error),
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
 ) error {
-	callerID, err := GetCallerID(stub)
-	if err != nil {
-		return err
-	}
-
-	tokenOwnerID, err := getOwner(stub)
-	if err != nil {
-		return err
-	}
-
-	if err := CheckCallerIsOwner(callerID, tokenOwnerID); err != nil {
+	if _, err := CheckCallerIsMember(stub, erc20roles.PAUSER, hasRole); err != nil {
 		return err
 	}
 
-	return stub.PutState("isPaused", []byte("false"))
+	return PutConfigState(stub, "isPaused", []byte("false"))
 }
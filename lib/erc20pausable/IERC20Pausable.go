@@ -7,10 +7,10 @@ type PausableTokenInterface interface {
 	IsPaused(stub shim.ChaincodeStubInterface) (bool, error)
 
 	Pause(stub shim.ChaincodeStubInterface,
-		getOwner func(shim.ChaincodeStubInterface) (string, error),
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
 	) error
 
 	Unpause(stub shim.ChaincodeStubInterface,
-		getOwner func(shim.ChaincodeStubInterface) (string, error),
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
 	) error
 }
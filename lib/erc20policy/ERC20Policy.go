@@ -0,0 +1,238 @@
+package erc20policy
+
+import (
+	"encoding/json"
+	. "erc20/helpers"
+	"erc20/lib/erc20roles"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
+)
+
+var logger = shim.NewLogger("policy-logger")
+
+//AnyValue matches an attribute whatever its value is, as long as the caller's certificate carries it
+const AnyValue = "*"
+
+//objectType of the composite key `MethodPolicy~[methodName]`
+const policyObjectType = "MethodPolicy"
+
+//exemptMethods can not be restricted, so the admins can always lift a policy locking them out
+var exemptMethods = []string{"SetMethodPolicy", "RemoveMethodPolicy"}
+
+/*MethodPolicy restricts an Invoke method to callers carrying certain Fabric CA attributes, NodeOUs and/or token roles*/
+type MethodPolicy struct {
+	Attributes map[string]string `json:"attributes,omitempty"` /*e.g. {"token.role": "minter"}, every attribute must match*/
+	NodeOUs    []string          `json:"nodeOUs,omitempty"`    /*e.g. ["client", "admin"], the caller must belong to one of them*/
+	Roles      []string          `json:"roles,omitempty"`      /*e.g. ["BURNER"], the caller must be a member of one of them*/
+}
+
+/*Token policy implements PolicyTokenInterface*/
+type Token struct{}
+
+/*GetMethodPolicy returns the policy of an Invoke method, nil if the method is not restricted.
+
+* `args[0]` - the method name.*/
+func (t *Token) GetMethodPolicy(stub shim.ChaincodeStubInterface, args []string) (*MethodPolicy, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+
+	policyKey, err := stub.CreateCompositeKey(policyObjectType, []string{args[0]})
+	if err != nil {
+		return nil, err
+	}
+	policyBytes, err := stub.GetState(policyKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(policyBytes) == 0 {
+		return nil, nil
+	}
+
+	policy := &MethodPolicy{}
+	if err := json.Unmarshal(policyBytes, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+/*CheckMethodPolicy returns an error if the chaincode caller does not satisfy the policy of an Invoke method,
+or of the method run by a multi-signature proposal.
+
+* `args[0]` - the method name.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) CheckMethodPolicy(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	if isExempt(args[0]) {
+		return nil
+	}
+	policy, err := t.GetMethodPolicy(stub, args)
+	if err != nil || policy == nil {
+		return err
+	}
+	methodName := args[0]
+
+	for attrName, expected := range policy.Attributes {
+		value, found, err := cid.GetAttributeValue(stub, attrName)
+		if err != nil {
+			return err
+		}
+		if !found || (expected != AnyValue && value != expected) {
+			logger.Noticef("CheckMethodPolicy: caller of %v does not match attribute %v=%v", methodName, attrName, expected)
+			return fmt.Errorf("Calling %v requires the certificate attribute %v=%v", methodName, attrName, expected)
+		}
+	}
+
+	if err := checkNodeOUs(stub, methodName, policy.NodeOUs); err != nil {
+		return err
+	}
+	return checkRoles(stub, methodName, policy.Roles, hasRole)
+}
+
+//checkNodeOUs returns an error if the chaincode caller belongs to none of `nodeOUs`, if any
+func checkNodeOUs(stub shim.ChaincodeStubInterface, methodName string, nodeOUs []string) error {
+	if len(nodeOUs) == 0 {
+		return nil
+	}
+	callerOUs, err := GetCallerNodeOUs(stub)
+	if err != nil {
+		return err
+	}
+	for _, nodeOU := range nodeOUs {
+		for _, callerOU := range callerOUs {
+			if strings.ToLower(nodeOU) == callerOU {
+				return nil
+			}
+		}
+	}
+	logger.Noticef("CheckMethodPolicy: caller of %v belongs to %v, not to %v", methodName, callerOUs, nodeOUs)
+	return fmt.Errorf("Calling %v requires one of the NodeOUs %v", methodName, nodeOUs)
+}
+
+//checkRoles returns an error if the chaincode caller is a member of none of `roles`, if any
+func checkRoles(stub shim.ChaincodeStubInterface,
+	methodName string,
+	roles []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		isMember, err := hasRole(stub, []string{role, callerID})
+		if err != nil || isMember {
+			return err
+		}
+	}
+	logger.Noticef("CheckMethodPolicy: caller of %v (%v) is not a member of %v", methodName, callerID, roles)
+	return fmt.Errorf("Calling %v requires a member of one of the roles %v, %v is not one of them", methodName, roles, callerID)
+}
+
+/*SetMethodPolicy restricts an Invoke method to callers matching a policy, callable by members of the ADMIN role.
+SetMethodPolicy & RemoveMethodPolicy themselves can not be restricted.
+
+* `args[0]` - the method name.
+
+* `args[1]` - JSON-formatted policy, e.g: `{"attributes": {"token.role": "minter"}, "nodeOUs": ["client", "admin"], "roles": ["MINTER"]}`
+
+* `methods` - the names of the methods dispatched by Invoke, a policy of any other name would restrict nothing.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) SetMethodPolicy(stub shim.ChaincodeStubInterface,
+	args []string,
+	methods []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	methodName := args[0]
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+	if isExempt(methodName) {
+		return fmt.Errorf("%v can not be restricted", methodName)
+	}
+	if !isMethod(methods, methodName) {
+		return fmt.Errorf("%v is not an Invoke method", methodName)
+	}
+
+	policy := &MethodPolicy{}
+	if err := json.Unmarshal([]byte(args[1]), policy); err != nil {
+		return fmt.Errorf("invalid method policy: %v", err)
+	}
+	if len(policy.Attributes) == 0 && len(policy.NodeOUs) == 0 && len(policy.Roles) == 0 {
+		return fmt.Errorf("method policy should restrict at least one attribute, NodeOU or role, use RemoveMethodPolicy instead")
+	}
+	for _, role := range policy.Roles {
+		if err := erc20roles.CheckRole(role); err != nil {
+			return fmt.Errorf("invalid method policy: %v", err)
+		}
+	}
+
+	logger.Infof("SetMethodPolicy: restricting %v to %v by %v", methodName, args[1], callerID)
+
+	policyKey, err := stub.CreateCompositeKey(policyObjectType, []string{methodName})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(policyKey, MalshalJSON(policy))
+}
+
+/*RemoveMethodPolicy lifts the restriction of an Invoke method, callable by members of the ADMIN role.
+
+* `args[0]` - the method name.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) RemoveMethodPolicy(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	methodName := args[0]
+
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("RemoveMethodPolicy: lifting restriction of %v by %v", methodName, callerID)
+
+	policyKey, err := stub.CreateCompositeKey(policyObjectType, []string{methodName})
+	if err != nil {
+		return err
+	}
+	return stub.DelState(policyKey)
+}
+
+//isExempt tells if `methodName` is one of the exemptMethods
+func isExempt(methodName string) bool {
+	return isMethod(exemptMethods, methodName)
+}
+
+//isMethod tells if `methodName` is one of `methods`
+func isMethod(methods []string, methodName string) bool {
+	for _, m := range methods {
+		if m == methodName {
+			return true
+		}
+	}
+	return false
+}
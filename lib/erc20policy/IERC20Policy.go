@@ -0,0 +1,24 @@
+package erc20policy
+
+import "github.com/hyperledger/fabric/core/chaincode/shim"
+
+/*PolicyTokenInterface consists of SetMethodPolicy & RemoveMethodPolicy (methods should be restricted), GetMethodPolicy & CheckMethodPolicy to check state*/
+type PolicyTokenInterface interface {
+	GetMethodPolicy(stub shim.ChaincodeStubInterface, args []string) (*MethodPolicy, error)
+
+	CheckMethodPolicy(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+
+	SetMethodPolicy(stub shim.ChaincodeStubInterface,
+		args []string,
+		methods []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+
+	RemoveMethodPolicy(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+}
@@ -0,0 +1,250 @@
+package erc20roles
+
+import (
+	. "erc20/helpers"
+	"erc20/lib/erc20events"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var logger = shim.NewLogger("roles-logger")
+
+/*enums for role names*/
+const (
+	ADMIN      = "ADMIN"
+	MINTER     = "MINTER"
+	PAUSER     = "PAUSER"
+	BURNER     = "BURNER"
+	COMPLIANCE = "COMPLIANCE"
+	REGISTRAR  = "REGISTRAR"
+)
+
+/*AllRoles lists every role known to the token, in the order they are granted to the initial owner*/
+var AllRoles = []string{ADMIN, MINTER, PAUSER, BURNER, COMPLIANCE, REGISTRAR}
+
+//objectType of the composite key `Role~[role]~[memberID]`
+const roleObjectType = "Role"
+
+/*Token roles implements RolesTokenInterface*/
+type Token struct{}
+
+/*HasRole checks if an identity is a member of a role.
+
+* `args[0]` - the role name.
+
+* `args[1]` - the ID of the identity.*/
+func (t *Token) HasRole(stub shim.ChaincodeStubInterface, args []string) (bool, error) {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return false, err
+	}
+	role, memberID := args[0], args[1]
+
+	roleKey, err := stub.CreateCompositeKey(roleObjectType, []string{role, memberID})
+	if err != nil {
+		return false, err
+	}
+	member, err := stub.GetState(roleKey)
+	if err != nil {
+		return false, err
+	}
+	return len(member) != 0, nil
+}
+
+/*GetRoleMembers returns the IDs of all members of a role.
+
+* `args[0]` - the role name.*/
+func (t *Token) GetRoleMembers(stub shim.ChaincodeStubInterface, args []string) ([]string, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return nil, err
+	}
+	role := args[0]
+	if err := CheckRole(role); err != nil {
+		return nil, err
+	}
+
+	iterator, err := stub.GetStateByPartialCompositeKey(roleObjectType, []string{role})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	members := []string{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := stub.SplitCompositeKey(queryResult.GetKey())
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, attributes[1])
+	}
+	return members, nil
+}
+
+/*GrantRole adds an identity to a role, callable by members of the ADMIN role.
+
+* `args[0]` - the role name.
+
+* `args[1]` - the ID of the new member.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) GrantRole(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	role, memberID := args[0], args[1]
+	if err := CheckRole(role); err != nil {
+		return err
+	}
+
+	callerID, err := CheckCallerIsMember(stub, ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("GrantRole: granting %v to %v by %v...", role, memberID, callerID)
+
+	err = putRoleState(stub, role, memberID, callerID)
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.RolePayload{Role: role, Account: memberID}})
+	return stub.SetEvent(erc20events.ROLE_GRANTED, json)
+}
+
+/*RevokeRole removes an identity from a role, callable by members of the ADMIN role.
+The last member of the ADMIN role can not be revoked, otherwise no one would be able to grant roles anymore.
+
+* `args[0]` - the role name.
+
+* `args[1]` - the ID of the member.
+
+* `hasRole` - specifies the function of checking whether the chaincode caller is a member of a role.*/
+func (t *Token) RevokeRole(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	role, memberID := args[0], args[1]
+	if err := CheckRole(role); err != nil {
+		return err
+	}
+
+	callerID, err := CheckCallerIsMember(stub, ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+
+	isMember, err := t.HasRole(stub, []string{role, memberID})
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return fmt.Errorf("%v is not a member of role %v", memberID, role)
+	}
+
+	if role == ADMIN {
+		admins, err := t.GetRoleMembers(stub, []string{ADMIN})
+		if err != nil {
+			return err
+		}
+		if len(admins) <= 1 {
+			return fmt.Errorf("can not revoke the last member of role %v", ADMIN)
+		}
+	}
+
+	logger.Infof("RevokeRole: revoking %v from %v by %v...", role, memberID, callerID)
+
+	err = delRoleState(stub, role, memberID)
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: callerID, Payload: erc20events.RolePayload{Role: role, Account: memberID}})
+	return stub.SetEvent(erc20events.ROLE_REVOKED, json)
+}
+
+/*TransferRoles moves every role of an identity to another one, which is at least granted ADMIN, or revokes them all,
+for the owner's roles to follow the ownership. It doesn't check the chaincode caller, the ownership functions do.
+Like RevokeRole, it fails to revoke the last member of the ADMIN role.
+
+* `args[0]` - the ID of the member.
+
+* `args[1]` - the ID of the identity receiving the roles, empty to revoke them.*/
+func (t *Token) TransferRoles(stub shim.ChaincodeStubInterface, args []string) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	fromID, toID := args[0], args[1]
+
+	if toID == "" {
+		isAdmin, err := t.HasRole(stub, []string{ADMIN, fromID})
+		if err != nil {
+			return err
+		}
+		admins, err := t.GetRoleMembers(stub, []string{ADMIN})
+		if err != nil {
+			return err
+		}
+		if isAdmin && len(admins) <= 1 {
+			return fmt.Errorf("can not revoke the last member of role %v, grant it to another identity first", ADMIN)
+		}
+	}
+
+	for _, role := range AllRoles {
+		isMember, err := t.HasRole(stub, []string{role, fromID})
+		if err != nil {
+			return err
+		}
+		if isMember {
+			logger.Infof("TransferRoles: revoking %v from %v", role, fromID)
+			if err := delRoleState(stub, role, fromID); err != nil {
+				return err
+			}
+		}
+		if toID != "" && (isMember || role == ADMIN) {
+			logger.Infof("TransferRoles: granting %v to %v", role, toID)
+			if err := putRoleState(stub, role, toID, fromID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//putRoleState adds `memberID` to a role, recording who granted it
+func putRoleState(stub shim.ChaincodeStubInterface, role string, memberID string, grantedBy string) error {
+	roleKey, err := stub.CreateCompositeKey(roleObjectType, []string{role, memberID})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(roleKey, []byte(grantedBy))
+}
+
+//delRoleState removes `memberID` from a role
+func delRoleState(stub shim.ChaincodeStubInterface, role string, memberID string) error {
+	roleKey, err := stub.CreateCompositeKey(roleObjectType, []string{role, memberID})
+	if err != nil {
+		return err
+	}
+	return stub.DelState(roleKey)
+}
+
+/*CheckRole returns an error if `role` is not one of the known roles*/
+func CheckRole(role string) error {
+	for _, r := range AllRoles {
+		if r == role {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown role %v, expected one of %v", role, AllRoles)
+}
@@ -0,0 +1,23 @@
+package erc20roles
+
+import "github.com/hyperledger/fabric/core/chaincode/shim"
+
+/*RolesTokenInterface consists of GrantRole & RevokeRole (methods should be restricted), HasRole & GetRoleMembers to check state,
+and TransferRoles for the roles following the ownership*/
+type RolesTokenInterface interface {
+	HasRole(stub shim.ChaincodeStubInterface, args []string) (bool, error)
+
+	GetRoleMembers(stub shim.ChaincodeStubInterface, args []string) ([]string, error)
+
+	GrantRole(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+
+	RevokeRole(stub shim.ChaincodeStubInterface,
+		args []string,
+		hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	) error
+
+	TransferRoles(stub shim.ChaincodeStubInterface, args []string) error
+}
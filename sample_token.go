@@ -1,18 +1,40 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	. "erc20/helpers"
+	"erc20/lib/erc20activation"
+	"erc20/lib/erc20alias"
 	"erc20/lib/erc20basic"
 	"erc20/lib/erc20burnable"
+	"erc20/lib/erc20classes"
+	"erc20/lib/erc20confidential"
+	"erc20/lib/erc20deltas"
 	"erc20/lib/erc20detailed"
+	"erc20/lib/erc20events"
+	"erc20/lib/erc20freezable"
+	"erc20/lib/erc20holders"
+	"erc20/lib/erc20migration"
 	"erc20/lib/erc20mintable"
+	"erc20/lib/erc20msplist"
+	"erc20/lib/erc20multisig"
+	"erc20/lib/erc20operator"
 	"erc20/lib/erc20ownable"
 	"erc20/lib/erc20pausable"
+	"erc20/lib/erc20policy"
+	"erc20/lib/erc20roles"
 	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
 	"github.com/hyperledger/fabric/protos/peer"
 )
 
@@ -29,22 +51,81 @@ type SampleToken struct {
 	erc20mintable.MintableTokenInterface
 	erc20burnable.BurnableTokenInterface
 	erc20pausable.PausableTokenInterface
+	erc20roles.RolesTokenInterface
+	erc20multisig.MultiSigTokenInterface
+	erc20policy.PolicyTokenInterface
+	erc20alias.AliasTokenInterface
+	erc20msplist.MSPListTokenInterface
+	erc20freezable.FreezableTokenInterface
+	erc20activation.ActivationTokenInterface
+	erc20migration.MigrationTokenInterface
+	erc20operator.OperatorTokenInterface
+	erc20classes.ClassesTokenInterface
+	erc20holders.HoldersTokenInterface
+	erc20deltas.DeltasTokenInterface
+	erc20confidential.ConfidentialTokenInterface
+}
+
+//multiSigMethods are the owner-only functions that must be proposed & approved once multi-signature is enabled
+var multiSigMethods = []string{"Mint", "Pause", "Unpause", "TransferOwnership", "CancelOwnershipTransfer", "RenounceOwnership", "GrantRole", "RevokeRole", "SetMultiSigPolicy", "SetMethodPolicy", "RemoveMethodPolicy", "SetMSPListMode", "AddMSP", "RemoveMSP", "CloseAccount", "SetActivationPolicy", "CreateClass", "SetHotAccount"}
+
+//invokeMethods are the methods dispatched by Invoke, any other name is rejected before reaching it,
+//and the only names a method policy can restrict
+var invokeMethods = []string{
+	"GetBalanceOf", "GetTotalSupply", "GetAllowance", "GetAllowancesOf", "GetApprovalsFor", "GetName", "GetSymbol", "GetDecimals",
+	"Mint", "Burn", "BurnFrom", "Transfer", "TransferFrom", "UpdateApproval", "Pause", "Unpause",
+	"GetMemo", "GetMemos", "GetMemosByReference", "RegisterMemoKey", "GetMemoKey",
+	"GetSchemaVersion", "GetStateMode", "MigrateSchema",
+	"GetOwner", "GetPendingOwner", "TransferOwnership", "AcceptOwnership", "CancelOwnershipTransfer", "RenounceOwnership",
+	"HasRole", "GetRoleMembers", "GrantRole", "RevokeRole",
+	"GetMultiSigPolicy", "SetMultiSigPolicy", "Propose", "Approve", "Revoke", "Execute", "GetProposal",
+	"GetMethodPolicy", "SetMethodPolicy", "RemoveMethodPolicy",
+	"RegisterAlias", "ReleaseAlias", "ResolveAlias", "GetAliases",
+	"GetMSPList", "SetMSPListMode", "AddMSP", "RemoveMSP",
+	"IsFrozen", "GetFreezeLog", "FreezeAccount", "UnfreezeAccount",
+	"Deactivate", "CloseAccount",
+	"Activate", "GetActivationPolicy", "GetActivationRecord", "GetActivationRequests", "SetActivationPolicy", "RequestActivation", "ApproveActivation", "RejectActivation",
+	"MigrateAccount", "GetMigration", "GetForwarding",
+	"IsOperatorFor", "AuthorizeOperator", "RevokeOperator", "OperatorSend", "OperatorBurn",
+	"CreateClass", "GetClass", "GetClasses", "GetBalanceOfBatch",
+	"GetHolders", "GetHolderCount", "CompactHolderCount",
+	"QueryAccounts",
+	"GetAccount", "SetAccountMetadata",
+	"IsHotAccount", "SetHotAccount", "CompactBalance",
+	"GetConfidentialBalance", "Shield", "Unshield", "ConfidentialTransfer",
+	"GetAccountHistory",
 }
 
 // main function starts up the chaincode in the container during instantiate
 func main() {
-	//new instance of token that mostly implements standard library
-	//erc20 basic type is extended with "memo" functionality
-	sampleToken := &SampleToken{
+	if err := shim.Start(NewSampleToken()); err != nil {
+		panic(err)
+	}
+}
+
+/*NewSampleToken returns a new instance of token that mostly implements standard library,
+its erc20 basic type is extended with "memo" functionality*/
+func NewSampleToken() *SampleToken {
+	return &SampleToken{
 		&CustomBasicToken{},
 		&erc20ownable.Token{},
 		&erc20detailed.Token{},
 		&erc20mintable.Token{},
 		&erc20burnable.Token{},
 		&erc20pausable.Token{},
-	}
-	if err := shim.Start(sampleToken); err != nil {
-		panic(err)
+		&erc20roles.Token{},
+		&erc20multisig.Token{},
+		&erc20policy.Token{},
+		&erc20alias.Token{},
+		&erc20msplist.Token{},
+		&erc20freezable.Token{},
+		&erc20activation.Token{},
+		&erc20migration.Token{},
+		&erc20operator.Token{},
+		&erc20classes.Token{},
+		&erc20holders.Token{},
+		&erc20deltas.Token{},
+		&erc20confidential.Token{},
 	}
 }
 
@@ -54,66 +135,126 @@ func main() {
 (https://hyperledger-fabric.readthedocs.io/en/release-1.4/chaincode4ade.html#initializing-the-chaincode).
 
 Init takes in one argument as a JSON-formatted string for token configurations, specifies the token attributes.
-Owner of the token is also initialized as the contract's invoker.
+Owner of the token is also initialized as the contract's invoker, and is granted every role in `erc20roles.AllRoles`.
+The optional `idScheme` picks how account IDs are derived (`legacy`, `escaped`, `pubkeyHash` or `address`, `legacy` by default),
+`idemixIDScheme` the disclosed attribute the IDs of Idemix callers are derived from (`enrollmentID` by default, `revocationHandle`,
+or `ou`, shared by every member of the OU),
+`initialSupply` the number of tokens minted to the owner (InitialMintAmount by default),
+`stateMode` how values are stored (`raw` strings or `json` documents CouchDB can query, `raw` by default),
+and `confidential` (`true` or `false` by default) whether accounts can hold confidential balances in private data collections.
+The ID schemes are fixed once the token is initialized, upgrades can change the state mode & the confidential mode.
+
+Examples: `{"name": "tokenName", "symbol": "tokenSymbol", "decimals": "18"}`,
+`{"name": "tokenName", "symbol": "tokenSymbol", "decimals": "18", "idScheme": "address", "idemixIDScheme": "revocationHandle"}`
 
-Examples: `{"name": "tokenName", "symbol": "tokenSymbol", "decimals": "18"}`*/
+Upgrades run the next pending step of `schemaMigrations` (MigrateSchema runs the others), the owner can pass them parameters by name in the optional `migrations` field,
+then apply the optional `stateMode` (converted by MigrateSchema once the pending steps are done) & `confidential` fields.
+
+Examples: `{"migrations": {"namespaceKeys": {"balances": ["[mspID],[IssuerCN],[SubjectCN]-suffix"]}}}`, `{"stateMode": "json"}`*/
 func (t *SampleToken) Init(stub shim.ChaincodeStubInterface) peer.Response {
-	callerID, err := GetCallerID(stub)
+	// ledgers written before the keys were namespaced keep the token attributes under bare keys until namespaceKeys converts them
+	isLegacy, err := isLegacyLedger(stub)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
 	// if this is not the first init call (chaincode upgrade)
 	// then owner validation is needed
-	if currentOwner, _ := t.GetOwner(stub); strings.TrimSpace(currentOwner) != "" {
+	// (the token attributes tell if the token is initialized, as the owner is empty once the ownership is renounced)
+	if decimals, _ := t.GetDecimals(stub); strings.TrimSpace(decimals) != "" || isLegacy {
+		callerID, err := ResolveCallerID(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
 		logger.Infof("Upgrading chaincode using %v...", callerID)
+		currentOwner, err := t.getUpgradeOwner(stub, isLegacy)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if strings.TrimSpace(currentOwner) == "" {
+			return shim.Error("Ownership has been renounced, the token can not be upgraded")
+		}
 		if err := CheckCallerIsOwner(callerID, currentOwner); err != nil {
 			return shim.Error(err.Error())
 		}
+
+		configs, params, err := parseUpgradeArgs(stub.GetStringArgs())
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if err := t.runSchemaMigrations(stub, currentOwner, params); err != nil {
+			return shim.Error(err.Error())
+		}
+		if err := t.changeConfigs(stub, configs); err != nil {
+			return shim.Error(err.Error())
+		}
 	} else {
-		logger.Infof("Init chaincode using %v...", callerID)
 		// if this is first call, then initialize states
 		args := stub.GetStringArgs()
 		if err := CheckArgsLength(args, 1); err != nil {
 			return shim.Error(err.Error())
 		}
 
-		coinConfig := JSONToMap(args[0])
-
-		// checks if "decimals" is a string of number format
-		n := StringToInt(coinConfig["decimals"].(string))
+		coinConfig := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(args[0]), &coinConfig); err != nil {
+			return shim.Error(fmt.Sprintf("invalid token configuration: %v", err))
+		}
+		if err := checkTokenConfig(coinConfig); err != nil {
+			return shim.Error(fmt.Sprintf("invalid token configuration: %v", err))
+		}
 
-		err = stub.PutState("owner", []byte(callerID))
-		if err != nil {
-			return shim.Error(err.Error())
+		//the ID scheme & the state mode are passed with the stub to every library called below,
+		//so the owner is resolved with the scheme and every value is written with the mode
+		configs := map[string]string{}
+		if scheme, ok := coinConfig["idScheme"].(string); ok {
+			if _, err := GetIdentityResolver(scheme); err != nil {
+				return shim.Error(err.Error())
+			}
+			configs[IDSchemeKey] = scheme
 		}
-		err = stub.PutState("name", []byte(coinConfig["name"].(string)))
-		if err != nil {
-			return shim.Error(err.Error())
+		if scheme, ok := coinConfig["idemixIDScheme"].(string); ok {
+			if err := CheckIdemixIDScheme(scheme); err != nil {
+				return shim.Error(err.Error())
+			}
+			configs[IdemixIDSchemeKey] = scheme
 		}
-		err = stub.PutState("symbol", []byte(coinConfig["symbol"].(string)))
-		if err != nil {
-			return shim.Error(err.Error())
+		if mode, ok := coinConfig["stateMode"].(string); ok {
+			if err := CheckStateMode(mode); err != nil {
+				return shim.Error(err.Error())
+			}
+			configs[StateModeKey] = mode
 		}
-		err = stub.PutState("decimals", []byte(coinConfig["decimals"].(string)))
+		stub = WithConfigs(stub, configs)
+		for _, key := range []string{IDSchemeKey, IdemixIDSchemeKey, StateModeKey} {
+			if value, ok := configs[key]; ok {
+				if err := PutConfigState(stub, key, []byte(value)); err != nil {
+					return shim.Error(err.Error())
+				}
+			}
+		}
+		callerID, err := ResolveCallerID(stub)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
+		logger.Infof("Init chaincode using %v...", callerID)
 
-		//mint the initial total supply
-		//https://github.com/OpenZeppelin/openzeppelin-contracts/blob/master/contracts/examples/SimpleToken.sol
-		//activate the owner account first
-		err = t.Activate(stub, []string{callerID}, t.GetBalanceOf)
+		err = t.initToken(stub, callerID, coinConfig)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
+		if confidential, ok := coinConfig["confidential"].(string); ok {
+			enabled, err := parseConfidentialMode(confidential)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+			err = PutConfigState(stub, ConfidentialModeKey, []byte(enabled))
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
 
-		err = t.Mint(stub,
-			[]string{callerID, Mul(big.NewInt(InitialMintAmount), Pow(10, n)).String()},
-			withOwnerIs(callerID),
-			withInitialBalanceOf(0),
-			t.GetTotalSupply,
-		)
+		//new ledgers are written with the latest data layout
+		err = PutConfigState(stub, schemaVersionKey, []byte(strconv.Itoa(latestSchemaVersion())))
 		if err != nil {
 			return shim.Error(err.Error())
 		}
@@ -122,6 +263,116 @@ func (t *SampleToken) Init(stub shim.ChaincodeStubInterface) peer.Response {
 	return shim.Success(nil)
 }
 
+//isLegacyLedger tells if the token attributes are stored under bare keys, as written before the keys were namespaced
+func isLegacyLedger(stub shim.ChaincodeStubInterface) (bool, error) {
+	legacyDecimals, err := stub.GetState("decimals")
+	return len(legacyDecimals) != 0, err
+}
+
+//getUpgradeOwner returns the owner of the token, under its bare key in a legacy ledger
+func (t *SampleToken) getUpgradeOwner(stub shim.ChaincodeStubInterface, isLegacy bool) (string, error) {
+	if !isLegacy {
+		return t.GetOwner(stub)
+	}
+	legacyOwner, err := stub.GetState("owner")
+	return string(legacyOwner), err
+}
+
+//parseConfidentialMode returns the confidential mode as IsConfidentialMode reads it, whatever spelling ParseBool accepted
+func parseConfidentialMode(confidential string) (string, error) {
+	enabled, err := strconv.ParseBool(confidential)
+	if err != nil {
+		return "", fmt.Errorf("invalid confidential mode %v, expected true or false", confidential)
+	}
+	return strconv.FormatBool(enabled), nil
+}
+
+//initToken writes the token attributes, makes `ownerID` (the chaincode caller) the owner with every role
+//and mints the initial supply (`initialSupply` tokens if set, InitialMintAmount otherwise) to it
+func (t *SampleToken) initToken(stub shim.ChaincodeStubInterface, ownerID string, coinConfig map[string]interface{}) error {
+	// checks if "decimals" is a string of number format
+	n := StringToInt(coinConfig["decimals"].(string))
+
+	err := PutConfigState(stub, "owner", []byte(ownerID))
+	if err != nil {
+		return err
+	}
+	err = PutConfigState(stub, "name", []byte(coinConfig["name"].(string)))
+	if err != nil {
+		return err
+	}
+	err = PutConfigState(stub, "symbol", []byte(coinConfig["symbol"].(string)))
+	if err != nil {
+		return err
+	}
+	err = PutConfigState(stub, "decimals", []byte(coinConfig["decimals"].(string)))
+	if err != nil {
+		return err
+	}
+
+	err = t.grantAllRoles(stub, ownerID)
+	if err != nil {
+		return err
+	}
+
+	//mint the initial total supply
+	//https://github.com/OpenZeppelin/openzeppelin-contracts/blob/master/contracts/examples/SimpleToken.sol
+	//activate the owner account first
+	err = t.Activate(stub, []string{ownerID}, t.GetBalanceOf)
+	if err != nil {
+		return err
+	}
+
+	initialSupply := big.NewInt(InitialMintAmount)
+	if s, ok := coinConfig["initialSupply"].(string); ok {
+		initialSupply = StringToBigInt(s)
+	}
+	if initialSupply.Sign() == 0 {
+		return nil
+	}
+	return t.Mint(stub,
+		[]string{ownerID, Mul(initialSupply, Pow(10, n)).String()},
+		withRolesOf(ownerID),
+		withInitialBalanceOf(0),
+		t.GetTotalSupply,
+	)
+}
+
+//initClass initializes a token class created by CreateClass, the chaincode caller becomes its owner
+func (t *SampleToken) initClass(stub shim.ChaincodeStubInterface, config string) error {
+	classConfig := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(config), &classConfig); err != nil {
+		return fmt.Errorf("invalid class configuration: %v", err)
+	}
+	if err := checkTokenConfig(classConfig); err != nil {
+		return fmt.Errorf("invalid class configuration: %v", err)
+	}
+
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	return t.initToken(stub, callerID, classConfig)
+}
+
+//checkTokenConfig checks the token attributes passed to Init or CreateClass before initToken writes them
+func checkTokenConfig(config map[string]interface{}) error {
+	for _, field := range []string{"name", "symbol", "decimals"} {
+		if _, ok := config[field].(string); !ok {
+			return fmt.Errorf("%v is missing", field)
+		}
+	}
+	if _, err := strconv.ParseUint(config["decimals"].(string), 10, 8); err != nil {
+		return err
+	}
+	if s, ok := config["initialSupply"].(string); ok {
+		if _, err := strconv.ParseUint(s, 10, 64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //bypass the instance's own GetBalanceOf method to avoid "user is not registered" error as the caller is not
 //activated during first initialization phase (uncommitted transaction)
 func withInitialBalanceOf(initialBalance int64) func(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error) {
@@ -130,15 +381,168 @@ func withInitialBalanceOf(initialBalance int64) func(stub shim.ChaincodeStubInte
 	}
 }
 
-func withOwnerIs(owner string) func(shim.ChaincodeStubInterface) (string, error) {
-	return func(shim.ChaincodeStubInterface) (string, error) {
-		return owner, nil
+//bypass the instance's own HasRole method, roles granted during first initialization phase are not committed yet
+func withRolesOf(memberID string) func(shim.ChaincodeStubInterface, []string) (bool, error) {
+	return func(stub shim.ChaincodeStubInterface, args []string) (bool, error) {
+		return args[1] == memberID, nil
+	}
+}
+
+//grantAllRoles grants every known role to `memberID` (who must be the chaincode caller),
+//bypassing the admin check as there is no admin yet
+func (t *SampleToken) grantAllRoles(stub shim.ChaincodeStubInterface, memberID string) error {
+	for _, role := range erc20roles.AllRoles {
+		if err := t.GrantRole(stub, []string{role, memberID}, withRolesOf(memberID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//resolveAliases returns a copy of `params` where the aliases at `indexes` are replaced by their account IDs
+func (t *SampleToken) resolveAliases(stub shim.ChaincodeStubInterface, params []string, indexes ...int) ([]string, error) {
+	resolved := append([]string{}, params...)
+	for _, i := range indexes {
+		if i >= len(resolved) {
+			continue
+		}
+		accountID, err := t.ResolveAlias(stub, []string{resolved[i]})
+		if err != nil {
+			return nil, err
+		}
+		resolved[i] = accountID
+	}
+	return resolved, nil
+}
+
+//resolveForwarding returns a copy of `params` where the migrated accounts at `indexes` are replaced by their new IDs
+func (t *SampleToken) resolveForwarding(stub shim.ChaincodeStubInterface, params []string, indexes ...int) ([]string, error) {
+	resolved := append([]string{}, params...)
+	for _, i := range indexes {
+		if i >= len(resolved) {
+			continue
+		}
+		accountID, err := t.ResolveForwarding(stub, []string{resolved[i]})
+		if err != nil {
+			return nil, err
+		}
+		resolved[i] = accountID
+	}
+	return resolved, nil
+}
+
+//checkAccounts resolves the aliases & forwardings of the accounts in the params of a method,
+//and fails if any of the accounts involved is not allowed to transact.
+//Invoke & executed proposals both run it, so a proposal can't reach accounts Invoke would refuse
+func (t *SampleToken) checkAccounts(stub shim.ChaincodeStubInterface, methodName string, params []string) ([]string, error) {
+	var err error
+	//accounts can be given as registered aliases instead of raw IDs
+	switch methodName {
+	case "Transfer", "UpdateApproval", "BurnFrom", "Activate", "Deactivate", "ApproveActivation", "RejectActivation", "AuthorizeOperator", "RevokeOperator", "OperatorBurn":
+		params, err = t.resolveAliases(stub, params, 0)
+	case "TransferFrom", "CloseAccount", "MigrateAccount", "OperatorSend":
+		params, err = t.resolveAliases(stub, params, 0, 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	//tokens sent to a migrated account go to its new account, or are rejected
+	switch methodName {
+	case "Transfer", "Mint":
+		params, err = t.resolveForwarding(stub, params, 0)
+	case "TransferFrom", "OperatorSend":
+		params, err = t.resolveForwarding(stub, params, 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	//accounts of MSPs that are not allowed to hold tokens can't be activated, send, receive, burn or approve
+	accounts, err := t.involvedAccounts(stub, methodName, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.CheckAccountMSPs(stub, accounts); err != nil {
+		return nil, err
+	}
+	//frozen accounts are blocked the same way, whatever their MSP
+	if err := t.CheckNotFrozen(stub, accounts); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+//involvedAccounts returns the accounts a method activates, sends from, sends to, burns from or approves
+func (t *SampleToken) involvedAccounts(stub shim.ChaincodeStubInterface, methodName string, params []string) ([]string, error) {
+	//whether the caller is involved & the indexes of the involved accounts in `params`
+	var withCaller bool
+	var indexes []int
+	switch methodName {
+	case "Activate", "Mint", "ApproveActivation":
+		indexes = []int{0}
+	case "Transfer", "UpdateApproval", "BurnFrom", "Deactivate", "AuthorizeOperator", "OperatorBurn":
+		withCaller, indexes = true, []int{0}
+	case "TransferFrom", "OperatorSend":
+		withCaller, indexes = true, []int{0, 1}
+	case "CloseAccount", "MigrateAccount":
+		indexes = []int{0, 1}
+	case "Burn", "RequestActivation", "Shield", "Unshield":
+		withCaller = true
+	case "ConfidentialTransfer":
+		//the receiver is passed in the transient map
+		input, err := erc20confidential.GetInput(stub)
+		if err != nil {
+			return nil, err
+		}
+		withCaller, params, indexes = true, []string{input.Receiver}, []int{0}
+	}
+
+	accounts := []string{}
+	if withCaller {
+		callerID, err := ResolveCallerID(stub)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, callerID)
+	}
+	for _, i := range indexes {
+		if i < len(params) {
+			accounts = append(accounts, params[i])
+		}
 	}
+	return accounts, nil
 }
 
 /*Invoke is called per transaction on the chaincode*/
 func (t *SampleToken) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 	methodName, params := stub.GetFunctionAndParameters()
+	if !isInvokeMethod(methodName) {
+		return shim.Error("Input function is not defined in chaincode")
+	}
+
+	//a ledger is only used once the migrations of the last upgrade are done
+	if methodName != "MigrateSchema" && methodName != "GetSchemaVersion" && methodName != "GetStateMode" {
+		if err := t.checkSchemaVersion(stub); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	//methods run on a token class when the first param is `class:[classID]`, on the default token otherwise
+	if len(params) > 0 && strings.HasPrefix(params[0], erc20classes.ClassPrefix) {
+		switch methodName {
+		//the freezes, MSP list, migrations, method & multi-signature policies are shared by every class, set on the default token
+		case "CreateClass", "GetClass", "GetClasses", "GetBalanceOfBatch",
+			"FreezeAccount", "UnfreezeAccount", "SetMSPListMode", "AddMSP", "RemoveMSP", "MigrateAccount",
+			"SetMethodPolicy", "RemoveMethodPolicy", "SetMultiSigPolicy":
+			return shim.Error("Calling " + methodName + " is not allowed on a token class")
+		}
+		classStub, err := t.ClassStub(stub, []string{strings.TrimPrefix(params[0], erc20classes.ClassPrefix)})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		stub, params = classStub, params[1:]
+	}
 
 	//some functions are locked when the token state is "paused"
 	isPaused, err := t.IsPaused(stub)
@@ -147,11 +551,44 @@ func (t *SampleToken) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 	}
 	if isPaused {
 		switch methodName {
-		case "Transfer", "TransferFrom", "UpdateApproval":
+		case "Transfer", "TransferFrom", "UpdateApproval", "Deactivate", "CloseAccount", "MigrateAccount", "AuthorizeOperator", "OperatorSend", "OperatorBurn", "Shield", "Unshield", "ConfidentialTransfer":
 			return shim.Error("Calling " + methodName + " is not allowed when token is paused")
 		}
 	}
 
+	//owner-only functions can only be called through an approved proposal when multi-signature is enabled
+	isMultiSig, err := t.IsMultiSigEnabled(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if isMultiSig {
+		for _, m := range multiSigMethods {
+			if m == methodName {
+				return shim.Error("Calling " + methodName + " requires an approved multi-signature proposal")
+			}
+		}
+	}
+
+	//methods can be restricted to callers with certain CA attributes, NodeOUs & roles,
+	//the executor of a proposal must satisfy the policy of the proposed method as well
+	if err := t.CheckMethodPolicy(stub, []string{methodName}, t.HasRole); err != nil {
+		return shim.Error(err.Error())
+	}
+	if methodName == "Execute" && len(params) != 0 {
+		proposal, err := t.GetProposal(stub, params[:1])
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if err := t.CheckMethodPolicy(stub, []string{proposal.Method}, t.HasRole); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+
+	params, err = t.checkAccounts(stub, methodName, params)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
 	switch methodName {
 	case "GetBalanceOf":
 		f, err := t.GetBalanceOf(stub, params)
@@ -171,18 +608,75 @@ func (t *SampleToken) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 			return shim.Error(err.Error())
 		}
 		return shim.Success([]byte(f.String()))
+	case "GetAllowancesOf":
+		s, err := t.GetAllowancesOf(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "GetApprovalsFor":
+		s, err := t.GetApprovalsFor(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
 	case "GetOwner":
 		s, err := t.GetOwner(stub)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
 		return shim.Success([]byte(s))
+	case "GetSchemaVersion":
+		v, err := t.GetSchemaVersion(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(strconv.Itoa(v)))
+	case "GetStateMode":
+		mode, err := GetConfig(stub, StateModeKey)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if mode == "" {
+			mode = RawStateMode
+		}
+		return shim.Success([]byte(mode))
+	case "MigrateSchema":
+		err := t.MigrateSchema(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "GetPendingOwner":
+		s, err := t.GetPendingOwner(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(s))
 	case "TransferOwnership":
 		err := t.TransferOwnership(stub, params, t.GetOwner)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
 		return shim.Success(nil)
+	case "AcceptOwnership":
+		err := t.AcceptOwnership(stub, t.TransferRoles)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "CancelOwnershipTransfer":
+		err := t.CancelOwnershipTransfer(stub, t.GetOwner)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "RenounceOwnership":
+		err := t.RenounceOwnership(stub, t.GetOwner, t.TransferRoles)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
 	case "GetName":
 		s, err := t.GetName(stub)
 		if err != nil {
@@ -202,7 +696,7 @@ func (t *SampleToken) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 		}
 		return shim.Success([]byte(s))
 	case "Mint":
-		err := t.Mint(stub, params, t.GetOwner, t.GetBalanceOf, t.GetTotalSupply)
+		err := t.Mint(stub, params, t.HasRole, t.GetBalanceOf, t.GetTotalSupply)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
@@ -238,13 +732,13 @@ func (t *SampleToken) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 		}
 		return shim.Success(nil)
 	case "Pause":
-		err := t.Pause(stub, t.GetOwner)
+		err := t.Pause(stub, t.HasRole)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
 		return shim.Success(nil)
 	case "Unpause":
-		err := t.Unpause(stub, t.GetOwner)
+		err := t.Unpause(stub, t.HasRole)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
@@ -255,137 +749,1720 @@ func (t *SampleToken) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
 			return shim.Error(err.Error())
 		}
 		return shim.Success([]byte(s))
-	case "Activate":
-		err := t.Activate(stub, params, t.GetBalanceOf)
+	case "GetMemos":
+		s, err := t.GetMemos(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "GetMemosByReference":
+		s, err := t.GetMemosByReference(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "RegisterMemoKey":
+		err := t.RegisterMemoKey(stub)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
 		return shim.Success(nil)
-	}
-
-	return shim.Error("Input function is not defined in chaincode")
-}
-
-//#endregion chain code implementation
-
-//#region custom non-standard ERC20 implementation (transaction memo)
-var customLogger = shim.NewLogger("memo-logger")
-
-/*CustomBasicToken adds "memo" feature on top of basic ERC20 implementation*/
-type CustomBasicToken struct {
-	parentToken *erc20basic.Token
-}
-
-/*GetTotalSupply reimplement erc20basic's GetTotalSupply method*/
-func (t *CustomBasicToken) GetTotalSupply(stub shim.ChaincodeStubInterface) (*big.Int, error) {
-	return t.parentToken.GetTotalSupply(stub)
-}
-
-/*GetAllowance reimplement erc20basic's GetAllowance method*/
-func (t *CustomBasicToken) GetAllowance(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error) {
-	return t.parentToken.GetAllowance(stub, args)
-}
-
-/*UpdateApproval reimplement erc20basic's UpdateApproval method*/
-func (t *CustomBasicToken) UpdateApproval(stub shim.ChaincodeStubInterface, args []string) error {
-	return t.parentToken.UpdateApproval(stub, args)
-}
-
-/*GetMemo is a customed non standard erc20 that return the last memo string attached with transaction.
-
-* `args[0]` - the key ID of target client.*/
-func (t *SampleToken) GetMemo(stub shim.ChaincodeStubInterface, args []string) (string, error) {
-	if err := CheckArgsLength(args, 1); err != nil {
-		return "", err
-	}
-
-	//expect only one element in iterator
-	iterator, err := stub.GetStateByPartialCompositeKey("Memo", args)
-	defer iterator.Close()
-
-	if err != nil {
-		customLogger.Errorf("[sample-token.GetMemo] error after GetStateByPartialCompositeKey: %v", err)
-		return "", err
-	}
-	if iterator.HasNext() {
-		customLogger.Infof("Getting last memo from composite key %v", args[0])
-		queryResult, err := iterator.Next()
-		return string(queryResult.GetValue()), err
-	}
-	customLogger.Warningf("Memo not found for ID %v", args[0])
+	case "GetMemoKey":
+		s, err := t.GetMemoKey(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(s))
+	case "HasRole":
+		b, err := t.HasRole(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(strconv.FormatBool(b)))
+	case "GetRoleMembers":
+		members, err := t.GetRoleMembers(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(members))
+	case "GrantRole":
+		err := t.GrantRole(stub, params, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "RevokeRole":
+		err := t.RevokeRole(stub, params, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "GetMultiSigPolicy":
+		policy, err := t.GetMultiSigPolicy(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(policy))
+	case "SetMultiSigPolicy":
+		err := t.SetMultiSigPolicy(stub, params, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "Propose":
+		s, err := t.Propose(stub, params, multiSigMethods)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(s))
+	case "Approve":
+		err := t.Approve(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "Revoke":
+		err := t.Revoke(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "Execute":
+		err := t.Execute(stub, params, t.executeProposal)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "GetProposal":
+		proposal, err := t.GetProposal(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(proposal))
+	case "GetMethodPolicy":
+		policy, err := t.GetMethodPolicy(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(policy))
+	case "SetMethodPolicy":
+		err := t.SetMethodPolicy(stub, params, invokeMethods, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "RemoveMethodPolicy":
+		err := t.RemoveMethodPolicy(stub, params, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "Activate":
+		err := t.CheckActivation(stub, params, t.HasRole, t.GetOwner)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = t.Activate(stub, params, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "GetActivationPolicy":
+		s, err := t.GetActivationPolicy(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(s))
+	case "GetActivationRecord":
+		record, err := t.GetActivationRecord(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(record))
+	case "GetActivationRequests":
+		requests, err := t.GetActivationRequests(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(requests))
+	case "SetActivationPolicy":
+		err := t.SetActivationPolicy(stub, params, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "RequestActivation":
+		err := t.RequestActivation(stub, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "ApproveActivation":
+		err := t.ApproveActivation(stub, params, t.HasRole, t.GetOwner, t.activate)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "RejectActivation":
+		err := t.RejectActivation(stub, params, t.HasRole, t.GetOwner)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "Deactivate":
+		err := t.Deactivate(stub, params, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "CloseAccount":
+		err := t.CloseAccount(stub, params, t.HasRole, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "MigrateAccount":
+		err := t.MigrateAccount(stub, params, t.GetOwner, t.GetBalanceOf, t.migrateAccount)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "GetMigration":
+		s, err := t.GetMigration(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "GetForwarding":
+		s, err := t.GetForwarding(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "IsOperatorFor":
+		b, err := t.IsOperatorFor(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(strconv.FormatBool(b)))
+	case "AuthorizeOperator":
+		err := t.AuthorizeOperator(stub, params, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "RevokeOperator":
+		err := t.RevokeOperator(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "OperatorSend":
+		err := t.OperatorSend(stub, params, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "OperatorBurn":
+		err := t.OperatorBurn(stub, params, t.HasRole, t.GetBalanceOf, t.GetTotalSupply)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "CreateClass":
+		err := t.CreateClass(stub, params, t.HasRole, t.initClass)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "GetClass":
+		s, err := t.GetClass(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "GetClasses":
+		s, err := t.GetClasses(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "GetBalanceOfBatch":
+		s, err := t.GetBalanceOfBatch(stub, params, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "GetHolders":
+		s, err := t.GetHolders(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "GetHolderCount":
+		n, err := t.GetHolderCount(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(strconv.FormatInt(n, 10)))
+	case "CompactHolderCount":
+		n, err := t.CompactHolderCount(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(strconv.FormatInt(n, 10)))
+	case "GetAccount":
+		s, err := t.GetAccount(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "GetAccountHistory":
+		s, err := t.GetAccountHistory(stub, params, t.ResolveMigratedID)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "SetAccountMetadata":
+		err := t.SetAccountMetadata(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "QueryAccounts":
+		s, err := t.QueryAccounts(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(s))
+	case "IsHotAccount":
+		b, err := t.IsHotAccount(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(strconv.FormatBool(b)))
+	case "SetHotAccount":
+		err := t.SetHotAccount(stub, params, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "CompactBalance":
+		b, err := t.CompactBalance(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(b.String()))
+	case "GetConfidentialBalance":
+		b, err := t.GetConfidentialBalance(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(b.String()))
+	case "Shield":
+		err := t.Shield(stub, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "Unshield":
+		err := t.Unshield(stub, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "ConfidentialTransfer":
+		err := t.ConfidentialTransfer(stub, t.GetBalanceOf)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "RegisterAlias":
+		err := t.RegisterAlias(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "ReleaseAlias":
+		err := t.ReleaseAlias(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "ResolveAlias":
+		s, err := t.ResolveAlias(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(s))
+	case "GetAliases":
+		aliases, err := t.GetAliases(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(aliases))
+	case "IsFrozen":
+		b, err := t.IsFrozen(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(strconv.FormatBool(b)))
+	case "GetFreezeLog":
+		actions, err := t.GetFreezeLog(stub, params)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(actions))
+	case "FreezeAccount":
+		err := t.FreezeAccount(stub, params, t.HasRole, t.putAccountFrozen)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "UnfreezeAccount":
+		err := t.UnfreezeAccount(stub, params, t.HasRole, t.putAccountFrozen)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "GetMSPList":
+		list, err := t.GetMSPList(stub)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(MalshalJSON(list))
+	case "SetMSPListMode":
+		err := t.SetMSPListMode(stub, params, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "AddMSP":
+		err := t.AddMSP(stub, params, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	case "RemoveMSP":
+		err := t.RemoveMSP(stub, params, t.HasRole)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	}
+
+	return shim.Error("Input function is not defined in chaincode")
+}
+
+//executeProposal calls an owner-only function of an approved multi-signature proposal,
+//the role checks are already satisfied by the signers' approvals, the accounts are checked as in Invoke
+func (t *SampleToken) executeProposal(stub shim.ChaincodeStubInterface, methodName string, params []string) error {
+	params, err := t.checkAccounts(stub, methodName, params)
+	if err != nil {
+		return err
+	}
+
+	switch methodName {
+	case "Mint":
+		return t.Mint(stub, params, withMultiSigApproval, t.GetBalanceOf, t.GetTotalSupply)
+	case "Pause":
+		return t.Pause(stub, withMultiSigApproval)
+	case "Unpause":
+		return t.Unpause(stub, withMultiSigApproval)
+	case "TransferOwnership":
+		return t.TransferOwnership(stub, params, withMultiSigOwner)
+	case "CancelOwnershipTransfer":
+		return t.CancelOwnershipTransfer(stub, withMultiSigOwner)
+	case "RenounceOwnership":
+		return t.RenounceOwnership(stub, withMultiSigOwner, t.TransferRoles)
+	case "GrantRole":
+		return t.GrantRole(stub, params, withMultiSigApproval)
+	case "RevokeRole":
+		return t.RevokeRole(stub, params, withMultiSigApproval)
+	case "SetMultiSigPolicy":
+		return t.SetMultiSigPolicy(stub, params, withMultiSigApproval)
+	case "SetMethodPolicy":
+		return t.SetMethodPolicy(stub, params, invokeMethods, withMultiSigApproval)
+	case "RemoveMethodPolicy":
+		return t.RemoveMethodPolicy(stub, params, withMultiSigApproval)
+	case "SetMSPListMode":
+		return t.SetMSPListMode(stub, params, withMultiSigApproval)
+	case "AddMSP":
+		return t.AddMSP(stub, params, withMultiSigApproval)
+	case "RemoveMSP":
+		return t.RemoveMSP(stub, params, withMultiSigApproval)
+	case "CloseAccount":
+		return t.CloseAccount(stub, params, withMultiSigApproval, t.GetBalanceOf)
+	case "SetActivationPolicy":
+		return t.SetActivationPolicy(stub, params, withMultiSigApproval)
+	case "CreateClass":
+		return t.CreateClass(stub, params, withMultiSigApproval, t.initClass)
+	case "SetHotAccount":
+		return t.SetHotAccount(stub, params, withMultiSigApproval)
+	}
+	return fmt.Errorf("%v can not be executed by a proposal", methodName)
+}
+
+func withMultiSigApproval(shim.ChaincodeStubInterface, []string) (bool, error) {
+	return true, nil
+}
+
+//the signers of an approved proposal act on behalf of the owner
+func withMultiSigOwner(stub shim.ChaincodeStubInterface) (string, error) {
+	return ResolveCallerID(stub)
+}
+
+//isInvokeMethod tells if `methodName` is one of the invokeMethods
+func isInvokeMethod(methodName string) bool {
+	for _, m := range invokeMethods {
+		if m == methodName {
+			return true
+		}
+	}
+	return false
+}
+
+//#endregion chain code implementation
+
+//#region custom non-standard ERC20 implementation (transaction memo)
+var customLogger = shim.NewLogger("memo-logger")
+
+/*CustomBasicToken adds "memo" feature on top of basic ERC20 implementation*/
+type CustomBasicToken struct {
+	parentToken *erc20basic.Token
+}
+
+/*GetTotalSupply reimplement erc20basic's GetTotalSupply method*/
+func (t *CustomBasicToken) GetTotalSupply(stub shim.ChaincodeStubInterface) (*big.Int, error) {
+	return t.parentToken.GetTotalSupply(stub)
+}
+
+/*GetAllowance reimplement erc20basic's GetAllowance method*/
+func (t *CustomBasicToken) GetAllowance(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error) {
+	return t.parentToken.GetAllowance(stub, args)
+}
+
+/*GetAllowancesOf reimplement erc20basic's GetAllowancesOf method*/
+func (t *CustomBasicToken) GetAllowancesOf(stub shim.ChaincodeStubInterface, args []string) (*erc20basic.AllowancesPage, error) {
+	return t.parentToken.GetAllowancesOf(stub, args)
+}
+
+/*GetApprovalsFor reimplement erc20basic's GetApprovalsFor method*/
+func (t *CustomBasicToken) GetApprovalsFor(stub shim.ChaincodeStubInterface, args []string) (*erc20basic.AllowancesPage, error) {
+	return t.parentToken.GetApprovalsFor(stub, args)
+}
+
+/*UpdateApproval reimplement erc20basic's UpdateApproval method*/
+func (t *CustomBasicToken) UpdateApproval(stub shim.ChaincodeStubInterface, args []string) error {
+	return t.parentToken.UpdateApproval(stub, args)
+}
+
+/*ClearAllowances reimplement erc20basic's ClearAllowances method*/
+func (t *CustomBasicToken) ClearAllowances(stub shim.ChaincodeStubInterface, args []string) error {
+	return t.parentToken.ClearAllowances(stub, args)
+}
+
+/*MoveAllowances reimplement erc20basic's MoveAllowances method*/
+func (t *CustomBasicToken) MoveAllowances(stub shim.ChaincodeStubInterface, args []string) error {
+	return t.parentToken.MoveAllowances(stub, args)
+}
+
+/*GetMemo is a customed non standard erc20 that return the last memo string attached with transaction,
+memos encrypted for the client start with EncryptedMemoPrefix and are decrypted with DecryptMemo.
+
+* `args[0]` - the key ID of target client.*/
+func (t *SampleToken) GetMemo(stub shim.ChaincodeStubInterface, args []string) (string, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return "", err
+	}
+
+	//expect only one element in iterator
+	iterator, err := stub.GetStateByPartialCompositeKey(MemoObjectType, args)
+	defer iterator.Close()
+
+	if err != nil {
+		customLogger.Errorf("[sample-token.GetMemo] error after GetStateByPartialCompositeKey: %v", err)
+		return "", err
+	}
+	if iterator.HasNext() {
+		customLogger.Infof("Getting last memo from composite key %v", args[0])
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return "", err
+		}
+		isJSON, err := IsJSONStateMode(stub)
+		return string(DecodeDocument(MemoObjectType, queryResult.GetValue(), isJSON)), err
+	}
+	customLogger.Warningf("Memo not found for ID %v", args[0])
 	return "", fmt.Errorf("Memo not found for ID %v", args[0])
 }
 
-/*Transfer adds "memo" feature after erc20basic's Transfer method*/
-func (t *CustomBasicToken) Transfer(stub shim.ChaincodeStubInterface, args []string, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error {
-	err := t.parentToken.Transfer(stub, args, getBalanceOf)
+/*MemosPage is a page of the memo history, `Bookmark` is passed to get the next page and is empty on the last one*/
+type MemosPage struct {
+	Memos    []MemoRecord `json:"memos"`
+	Bookmark string       `json:"bookmark"`
+}
+
+/*GetMemos is a customed non standard erc20 that returns the memos sent & received by a client, oldest first.
+
+* `args[0]` - the key ID of target client.
+
+* `args[1]` - the page size, DefaultPageSize if empty or missing.
+
+* `args[2]` - the bookmark returned with the previous page, empty or missing for the first page.*/
+func (t *SampleToken) GetMemos(stub shim.ChaincodeStubInterface, args []string) (*MemosPage, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("the ID of client is required")
+	}
+	args = append(args, make([]string, 3)...)[:3]
+	pageSize, err := ParsePageSize(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	customLogger.Infof("[sample-token.GetMemos] getting %v memos of %v after %q", pageSize, args[0], args[2])
+
+	page := &MemosPage{Memos: []MemoRecord{}}
+	page.Bookmark, err = QueryPage(stub, MemoLogObjectType, []string{args[0]}, pageSize, args[2], func(keyAttributes []string, value []byte) error {
+		record, err := UnmarshalMemoRecord(value)
+		if err != nil {
+			return err
+		}
+		page.Memos = append(page.Memos, *record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+/*GetMemosByReference is a customed non standard erc20 that returns the memos of the transfers with a reference, oldest first for each receiver,
+to reconcile e.g. the payments of an invoice.
+
+* `args[0]` - the reference.
+
+* `args[1]` - the page size, DefaultPageSize if empty or missing.
+
+* `args[2]` - the bookmark returned with the previous page, empty or missing for the first page.*/
+func (t *SampleToken) GetMemosByReference(stub shim.ChaincodeStubInterface, args []string) (*MemosPage, error) {
+	if len(args) < 1 || args[0] == "" {
+		return nil, fmt.Errorf("the reference is required")
+	}
+	args = append(args, make([]string, 3)...)[:3]
+	pageSize, err := ParsePageSize(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	customLogger.Infof("[sample-token.GetMemosByReference] getting %v memos of reference %v after %q", pageSize, args[0], args[2])
+
+	page := &MemosPage{Memos: []MemoRecord{}}
+	page.Bookmark, err = QueryPage(stub, MemoReferenceObjectType, []string{args[0]}, pageSize, args[2], func(keyAttributes []string, value []byte) error {
+		record, err := GetMemoRecordState(stub, keyAttributes[1], keyAttributes[2], keyAttributes[3])
+		if err != nil || record == nil {
+			return err
+		}
+		page.Memos = append(page.Memos, *record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+/*RegisterMemoKey is a customed non standard erc20 that registers the public key of the chaincode caller's enrollment certificate,
+so the memos sent to the caller through the transient map are encrypted for it*/
+func (t *SampleToken) RegisterMemoKey(stub shim.ChaincodeStubInterface) error {
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	callerCert, err := cid.GetX509Certificate(stub)
+	if err != nil {
+		return err
+	}
+	if callerCert == nil {
+		return fmt.Errorf("memo keys are taken from x509 certificates")
+	}
+	if _, ok := callerCert.PublicKey.(*ecdsa.PublicKey); !ok {
+		return fmt.Errorf("memo keys must be ECDSA keys")
+	}
+	der, err := x509.MarshalPKIXPublicKey(callerCert.PublicKey)
+	if err != nil {
+		return err
+	}
+	customLogger.Infof("[sample-token.RegisterMemoKey] registering the memo key of %v", callerID)
+	return PutMemoKeyState(stub, callerID, der)
+}
+
+/*GetMemoKey is a customed non standard erc20 that returns the PEM public key registered by a client with RegisterMemoKey,
+to encrypt memos client-side with EncryptMemo.
+
+* `args[0]` - the key ID of target client.*/
+func (t *SampleToken) GetMemoKey(stub shim.ChaincodeStubInterface, args []string) (string, error) {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return "", err
+	}
+	der, err := GetMemoKeyState(stub, args[0])
+	if err != nil {
+		return "", err
+	}
+	if len(der) == 0 {
+		return "", fmt.Errorf("%v has not registered a memo key", args[0])
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+/*Transfer adds "memo" feature after erc20basic's Transfer method,
+the 3rd element in `args` is the memo and the 4th its reference (e.g. an invoice number), both optional*/
+func (t *CustomBasicToken) Transfer(stub shim.ChaincodeStubInterface, args []string, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error {
+	err := t.parentToken.Transfer(stub, args, getBalanceOf)
+	if err != nil {
+		return err
+	}
+	senderID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	args = append(args, make([]string, 4)...)[:4]
+	receiverID, amount, comment, reference := args[0], args[1], args[2], args[3]
+	return recordMemo(stub, senderID, receiverID, amount, comment, reference)
+}
+
+/*TransferFrom adds "memo" feature after erc20basic's TransferFrom method,
+the 4th element in `args` is the memo and the 5th its reference (e.g. an invoice number), both optional*/
+func (t *CustomBasicToken) TransferFrom(stub shim.ChaincodeStubInterface,
+	args []string,
+	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+	getAllowance func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+) error {
+	err := t.parentToken.TransferFrom(stub, args, getBalanceOf, getAllowance)
+	if err != nil {
+		return err
+	}
+	args = append(args, make([]string, 5)...)[:5]
+	senderID, receiverID, amount, comment, reference := args[0], args[1], args[2], args[3], args[4]
+	return recordMemo(stub, senderID, receiverID, amount, comment, reference)
+}
+
+//recordMemo records the memo of a transfer: the memo in the args, or else the memo of the transient map encrypted for the receiver.
+//It becomes the last memo of the receiver, and a MemoRecord of the memo history with its reference
+func recordMemo(stub shim.ChaincodeStubInterface, senderID string, receiverID string, amount string, comment string, reference string) error {
+	if comment == "" {
+		memo, err := encryptedMemo(stub, receiverID)
+		if err != nil {
+			return err
+		}
+		comment = memo
+	}
+	if comment == "" && reference == "" {
+		return nil
+	}
+	if comment != "" {
+		if err := setMemo(stub, receiverID, comment); err != nil {
+			return err
+		}
+	}
+	record, err := NewMemoRecord(stub, senderID, receiverID, StringToBigInt(amount), comment, reference)
+	if err != nil {
+		return err
+	}
+	customLogger.Infof("recording memo of %v from %v with reference %q", receiverID, senderID, reference)
+	return PutMemoRecord(stub, record)
+}
+
+//setMemo updates world-state with a composite key of objectType "Memo", attribute of `key` and value of `memo`
+func setMemo(stub shim.ChaincodeStubInterface, key string, memo string) error {
+	customLogger.Infof("setting memo of %v to %v", key, memo)
+	return PutMemoState(stub, key, memo)
+}
+
+//encryptedMemo encrypts the memo passed in the transient map (if any) for the key registered by `receiverID`,
+//the plaintext never appears in the proposal args nor the world-state
+func encryptedMemo(stub shim.ChaincodeStubInterface, receiverID string) (string, error) {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return "", err
+	}
+	plaintext, ok := transient[MemoTransientKey]
+	if !ok {
+		return "", nil
+	}
+	return EncryptMemoFor(stub, receiverID, plaintext, transient[MemoEntropyTransientKey])
+}
+
+/*GetBalanceOf is customed version of ERC20's standard, it rejects unregistered clients*/
+func (t *CustomBasicToken) GetBalanceOf(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error) {
+	tokenBalance, err := GetBalanceState(stub, args[0])
+	logger.Infof("[sample-token.GetBalanceOf] balance of %v: %v", args[0], string(tokenBalance))
+	// if the returned buffer is empty, this account is not registered
+	if len(tokenBalance) == 0 {
+		logger.Noticef("[sample-token.GetBalanceOf] %v is not registered", args[0])
+		return nil, fmt.Errorf("%v is not registered", args[0])
+	}
+	return BufferToBigInt(DefaultToZeroIfEmpty(tokenBalance)), err
+}
+
+/*Activate is a customed non standard erc20 that writes the account record of client with a balance of "0".
+This marks the active state of target so that subsequent Transfer operations will be successful,
+the chaincode caller is recorded as the approver of the activation. A migrated account can not be activated again.
+
+* `args[0]` - the key ID of target client.*/
+func (t *SampleToken) Activate(stub shim.ChaincodeStubInterface, args []string, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error {
+	clientID := args[0]
+	forwarding, err := t.GetForwarding(stub, []string{clientID})
+	if err != nil {
+		return err
+	}
+	if forwarding != nil {
+		return fmt.Errorf("%v has migrated to %v, it can not be activated again", clientID, forwarding.NewID)
+	}
+	balanceOfReceiver, err := getBalanceOf(stub, []string{clientID})
+	//if the client is never activated before
+	if err != nil && balanceOfReceiver == nil {
+		logger.Noticef("[sample-token.Activate] registering %v...", clientID)
+		// write the account record with a zero balance
+		// so the next time (customed) `GetBalanceOf` is called it won't show error
+		callerID, err := ResolveCallerID(stub)
+		if err != nil {
+			return err
+		}
+		account, err := NewAccount(stub, clientID, callerID)
+		if err != nil {
+			return err
+		}
+		if err := PutAccountRecord(stub, account); err != nil {
+			return err
+		}
+		return t.RecordActivation(stub, []string{clientID})
+	}
+	logger.Errorf("[sample-token.Activate] %v is already registered", clientID)
+	return fmt.Errorf("%v is already registered", clientID)
+}
+
+//activate is Activate with the instance's own GetBalanceOf, for approved activation requests
+func (t *SampleToken) activate(stub shim.ChaincodeStubInterface, args []string) error {
+	return t.Activate(stub, args, t.GetBalanceOf)
+}
+
+/*Deactivate is a customed non standard erc20 that retires the caller's own account, see CloseAccount.
+
+* `args[0]` - the key ID of the client receiving the remaining balance.*/
+func (t *SampleToken) Deactivate(stub shim.ChaincodeStubInterface, args []string, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error {
+	if err := CheckArgsLength(args, 1); err != nil {
+		return err
+	}
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	return t.closeAccount(stub, callerID, args[0], callerID, getBalanceOf)
+}
+
+/*CloseAccount is a customed non standard erc20 that retires an account, callable by members of the ADMIN role.
+The remaining balance is swept to another account, every allowance given by or to the account is cleared,
+and the account is unregistered again (until it is activated anew).
+
+* `args[0]` - the key ID of the client to close.
+
+* `args[1]` - the key ID of the client receiving the remaining balance.*/
+func (t *SampleToken) CloseAccount(stub shim.ChaincodeStubInterface,
+	args []string,
+	hasRole func(shim.ChaincodeStubInterface, []string) (bool, error),
+	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+) error {
+	if err := CheckArgsLength(args, 2); err != nil {
+		return err
+	}
+	callerID, err := CheckCallerIsMember(stub, erc20roles.ADMIN, hasRole)
+	if err != nil {
+		return err
+	}
+	return t.closeAccount(stub, args[0], args[1], callerID, getBalanceOf)
+}
+
+func (t *SampleToken) closeAccount(stub shim.ChaincodeStubInterface,
+	clientID string,
+	sweepToID string,
+	closerID string,
+	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
+) error {
+	if clientID == sweepToID {
+		return fmt.Errorf("the remaining balance of %v can not be swept to itself", clientID)
+	}
+	balance, err := getBalanceOf(stub, []string{clientID})
+	if err != nil {
+		return err
+	}
+	//confidential balances are only moved by their owner, they would be left behind
+	if err := CheckNoConfidentialBalance(stub, clientID); err != nil {
+		return err
+	}
+	balanceOfSweepTo, err := GetCreditedBalance(stub, sweepToID, getBalanceOf)
+	if err != nil {
+		return err
+	}
+
+	logger.Noticef("[sample-token.closeAccount] closing %v by %v, sweeping %v to %v...", clientID, closerID, balance, sweepToID)
+
+	sweepToChange, err := CreditBalance(stub, sweepToID, balanceOfSweepTo, balance)
+	if err != nil {
+		return err
+	}
+	// deleting the balance makes (customed) `GetBalanceOf` treat the client as unregistered again
+	err = DelBalanceState(stub, clientID)
+	if err != nil {
+		return err
+	}
+	err = UpdateHolderCount(stub, sweepToChange, BalanceChange{Before: balance})
+	if err != nil {
+		return err
+	}
+	err = t.ClearAllowances(stub, []string{clientID})
+	if err != nil {
+		return err
+	}
+	err = t.ClearOperators(stub, []string{clientID})
+	if err != nil {
+		return err
+	}
+
+	json := MalshalJSON(erc20events.Event{Origin: closerID, Payload: erc20events.ClosurePayload{Account: clientID, SweptTo: sweepToID, Amount: balance}})
+	return stub.SetEvent(erc20events.ACCOUNT_CLOSED, json)
+}
+
+//putAccountFrozen flags the record of `accountID` as frozen or not for FreezeAccount & UnfreezeAccount,
+//in the default token and in every class it's registered in, since the freeze applies to all of them
+func (t *SampleToken) putAccountFrozen(stub shim.ChaincodeStubInterface, accountID string, frozen bool) error {
+	if err := PutAccountFrozen(stub, accountID, frozen); err != nil {
+		return err
+	}
+	classes, err := t.GetClasses(stub)
+	if err != nil {
+		return err
+	}
+	for _, class := range classes {
+		classStub, err := t.ClassStub(stub, []string{class.ID})
+		if err != nil {
+			return err
+		}
+		if err := PutAccountFrozen(classStub, accountID, frozen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//migrateAccount moves the account of `oldID` to `newID` for MigrateAccount,
+//in the default token and in every class it's registered in, since the forwarding applies to all of them
+func (t *SampleToken) migrateAccount(stub shim.ChaincodeStubInterface, oldID string, newID string) error {
+	if err := t.moveAccount(stub, oldID, newID); err != nil {
+		return err
+	}
+	classes, err := t.GetClasses(stub)
+	if err != nil {
+		return err
+	}
+	for _, class := range classes {
+		classStub, err := t.ClassStub(stub, []string{class.ID})
+		if err != nil {
+			return err
+		}
+		if _, err := t.GetBalanceOf(classStub, []string{oldID}); err != nil {
+			continue
+		}
+		if err := t.moveAccount(classStub, oldID, newID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//moveAccount moves the balance, allowances, memos & activation record of `oldID` to `newID`, revokes its operators and clears its memo key
+func (t *SampleToken) moveAccount(stub shim.ChaincodeStubInterface, oldID string, newID string) error {
+	balance, err := t.GetBalanceOf(stub, []string{oldID})
+	if err != nil {
+		return err
+	}
+	if err := CheckNoConfidentialBalance(stub, oldID); err != nil {
+		return err
+	}
+	//the new account may already be activated, e.g. by an earlier Activate with the reissued certificate
+	balanceOfNew, err := GetCreditedBalance(stub, newID, t.GetBalanceOf)
+	isNewActivated := err == nil
+
+	logger.Noticef("[sample-token.moveAccount] moving %v and the records of %v to %v...", balance, oldID, newID)
+
+	newChange := BalanceChange{After: balance}
+	if isNewActivated {
+		newChange, err = CreditBalance(stub, newID, balanceOfNew, balance)
+	} else {
+		//the new account takes over the record (activation, nonce & metadata) of the old one
+		var account *Account
+		if account, err = GetAccountRecord(stub, oldID); err == nil {
+			account.ID = newID
+			err = PutAccountRecord(stub, account)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	err = DelBalanceState(stub, oldID)
+	if err != nil {
+		return err
+	}
+	err = UpdateHolderCount(stub, newChange, BalanceChange{Before: balance})
+	if err != nil {
+		return err
+	}
+	err = t.MoveAllowances(stub, []string{oldID, newID})
+	if err != nil {
+		return err
+	}
+	//the operators were authorized for the old identity, the new one authorizes its own
+	err = t.ClearOperators(stub, []string{oldID})
+	if err != nil {
+		return err
+	}
+
+	memo, err := t.GetMemo(stub, []string{oldID})
+	if err == nil {
+		if err := setMemo(stub, newID, memo); err != nil {
+			return err
+		}
+		if err := DelMemoState(stub, oldID); err != nil {
+			return err
+		}
+	}
+	err = MoveMemoHistory(stub, oldID, newID)
+	if err != nil {
+		return err
+	}
+	//the memo key is the public key of the old certificate, the new ID registers its own with RegisterMemoKey,
+	//the memos encrypted before the migration stay readable with the old private key only
+	err = DelMemoKeyState(stub, oldID)
+	if err != nil {
+		return err
+	}
+
+	if isNewActivated {
+		return nil
+	}
+	return t.MoveActivationRecord(stub, []string{oldID, newID})
+}
+
+//#endregion custom non-standard ERC20 implementation (transaction memo)
+
+//#region state schema migrations
+
+//schemaVersionKey is the config entry storing the version of the data layout of the ledger
+const schemaVersionKey = "schemaVersion"
+
+/*schemaMigration converts the ledger to the data layout `Version`, from the one of the previous step.
+Steps must be idempotent: the version is only stored by the transaction running them,
+and ledgers written before versions were stored run every step.
+A transaction runs a single step, so every step reads the state written by the previous ones,
+and `Run` returns false when it converted a batch of the ledger only, the next batch is converted by the next MigrateSchema transaction.
+Shipped steps are frozen: they write the data layout of their version, later layouts are written by new steps.*/
+type schemaMigration struct {
+	Version int
+	Name    string
+	Run     func(t *SampleToken, stub shim.ChaincodeStubInterface, ownerID string, params map[string]interface{}) (bool, error)
+}
+
+//defaultMigrationBatchSize is the number of keys a batched migration step converts per transaction, unless its `batchSize` parameter is set
+const defaultMigrationBatchSize = 500
+
+//migrationBookmarkKey is the config entry holding the last key converted by the running step, the next batch starts after it
+const migrationBookmarkKey = "migrationBookmark"
+
+//schemaMigrations are the steps bringing a ledger to the latest data layout, in order
+var schemaMigrations = []schemaMigration{
+	{Version: 1, Name: "grantOwnerRoles", Run: grantOwnerRoles},
+	{Version: 2, Name: "namespaceKeys", Run: namespaceKeys},
+	{Version: 3, Name: "countHolders", Run: countHolders},
+	{Version: 4, Name: "pruneZeroAllowances", Run: pruneZeroAllowances},
+	{Version: 5, Name: "accountRecords", Run: accountRecords},
+	{Version: 6, Name: "accountClass", Run: accountClass},
+	{Version: 7, Name: "indexActivationRequests", Run: indexActivationRequests},
+}
+
+//latestSchemaVersion is the data layout written by this chaincode
+func latestSchemaVersion() int {
+	return schemaMigrations[len(schemaMigrations)-1].Version
+}
+
+/*GetSchemaVersion returns the version of the data layout of the ledger, 0 for ledgers written before versions were stored*/
+func (t *SampleToken) GetSchemaVersion(stub shim.ChaincodeStubInterface) (int, error) {
+	version, err := GetConfigState(stub, schemaVersionKey)
+	if err != nil || len(version) == 0 {
+		return 0, err
+	}
+	return strconv.Atoi(string(version))
+}
+
+//parseUpgradeArgs returns the state mode & confidential mode set by the upgrade args, keyed by config entry,
+//and their `migrations` field, keyed by migration name
+func parseUpgradeArgs(args []string) (map[string]string, map[string]interface{}, error) {
+	configs, params := map[string]string{}, map[string]interface{}{}
+	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
+		return configs, params, nil
+	}
+	upgradeConfig := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(args[0]), &upgradeConfig); err != nil {
+		return nil, nil, fmt.Errorf("invalid upgrade args: %v", err)
+	}
+	if migrations, ok := upgradeConfig["migrations"]; ok {
+		if params, ok = migrations.(map[string]interface{}); !ok {
+			return nil, nil, fmt.Errorf("invalid upgrade args: `migrations` should be an object keyed by migration name")
+		}
+	}
+	if value, ok := upgradeConfig["stateMode"]; ok {
+		mode, _ := value.(string)
+		if err := CheckStateMode(mode); err != nil {
+			return nil, nil, fmt.Errorf("invalid upgrade args: %v", err)
+		}
+		configs[StateModeKey] = mode
+	}
+	if value, ok := upgradeConfig["confidential"]; ok {
+		confidential, _ := value.(string)
+		enabled, err := parseConfidentialMode(confidential)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid upgrade args: %v", err)
+		}
+		configs[ConfidentialModeKey] = enabled
+	}
+	return configs, params, nil
+}
+
+/*MigrateSchema runs the next pending migration step (or batch of a step) after an upgrade, then the next batch of the state mode conversion
+started by the upgrade, callable by the owner.
+The owner calls it until GetSchemaVersion returns the latest version and GetStateMode the new state mode, the token can't be used until then.
+
+* `args[0]` - optional, the upgrade args passing parameters to the migrations, e.g. `{"migrations": {"namespaceKeys": {"batchSize": 100}}}`,
+or to the state mode conversion, e.g. `{"migrations": {"convertStateMode": {"batchSize": 100}}}`.*/
+func (t *SampleToken) MigrateSchema(stub shim.ChaincodeStubInterface, args []string) error {
+	callerID, err := ResolveCallerID(stub)
+	if err != nil {
+		return err
+	}
+	isLegacy, err := isLegacyLedger(stub)
+	if err != nil {
+		return err
+	}
+	ownerID, err := t.getUpgradeOwner(stub, isLegacy)
+	if err != nil {
+		return err
+	}
+	if err := CheckCallerIsOwner(callerID, ownerID); err != nil {
+		return err
+	}
+	_, params, err := parseUpgradeArgs(args)
+	if err != nil {
+		return err
+	}
+	version, err := t.GetSchemaVersion(stub)
+	if err != nil {
+		return err
+	}
+	if version < latestSchemaVersion() {
+		return t.runSchemaMigrations(stub, ownerID, params)
+	}
+	return t.convertStateMode(stub, params)
+}
+
+//checkSchemaVersion returns an error while the migrations or the state mode conversion started by an upgrade are not done
+func (t *SampleToken) checkSchemaVersion(stub shim.ChaincodeStubInterface) error {
+	version, err := t.GetSchemaVersion(stub)
+	if err != nil {
+		return err
+	}
+	//ledgers that are not initialized yet have no version
+	if version != 0 && version < latestSchemaVersion() {
+		return fmt.Errorf("the ledger is being migrated to version %v (now %v), the owner continues the migration with MigrateSchema", latestSchemaVersion(), version)
+	}
+	conversion, err := getStateModeConversion(stub)
 	if err != nil {
 		return err
 	}
-	//assumes the 3rd element in `args` is the comment
-	if len(args) == 3 {
-		receiverID, _, comment := args[0], args[1], args[2]
-		return setMemo(stub, receiverID, comment)
+	if conversion != nil {
+		return fmt.Errorf("the ledger is being converted to the %v state mode, the owner continues the conversion with MigrateSchema", conversion.Mode)
 	}
 	return nil
 }
 
-/*TransferFrom adds "memo" feature after erc20basic's TransferFrom method*/
-func (t *CustomBasicToken) TransferFrom(stub shim.ChaincodeStubInterface,
-	args []string,
-	getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
-	getAllowance func(shim.ChaincodeStubInterface, []string) (*big.Int, error),
-) error {
-	err := t.parentToken.TransferFrom(stub, args, getBalanceOf, getAllowance)
+//parseBatchSize returns the `batchSize` parameter of a migration, defaultMigrationBatchSize if unset
+func parseBatchSize(params map[string]interface{}) (int, error) {
+	value, ok := params["batchSize"]
+	if !ok {
+		return defaultMigrationBatchSize, nil
+	}
+	batchSize, ok := value.(float64)
+	if !ok || batchSize < 1 || batchSize != float64(int(batchSize)) {
+		return 0, fmt.Errorf("invalid batch size %v, expected an integer > 0", value)
+	}
+	return int(batchSize), nil
+}
+
+//migrateBatch passes `convert` the next `batchSize` composite keys `objectType~...` after the bookmark of the running step,
+//and stores the last one as the bookmark the next MigrateSchema transaction resumes from.
+//It returns true and drops the bookmark once the last key is converted.
+func migrateBatch(stub shim.ChaincodeStubInterface,
+	objectType string,
+	batchSize int,
+	convert func(key string, value []byte) error,
+) (bool, error) {
+	bookmark, err := GetConfigState(stub, migrationBookmarkKey)
+	if err != nil {
+		return false, err
+	}
+	batch, hasMore, err := readMigrationBatch(stub, objectType, string(bookmark), batchSize)
+	if err != nil {
+		return false, err
+	}
+	for _, queryResult := range batch {
+		if err := convert(queryResult.Key, queryResult.Value); err != nil {
+			return false, err
+		}
+	}
+	if !hasMore {
+		return true, DelConfigState(stub, migrationBookmarkKey)
+	}
+	logger.Infof("[sample-token.migrateBatch] converted %v %v keys, the next ones are converted by the next transaction", len(batch), objectType)
+	return false, PutConfigState(stub, migrationBookmarkKey, []byte(batch[len(batch)-1].Key))
+}
+
+//readMigrationBatch returns the `batchSize` composite keys `objectType~...` after `bookmark`, and whether more keys follow.
+//The batch is read before it's converted, so the iterator never walks the keys written by the conversion
+func readMigrationBatch(stub shim.ChaincodeStubInterface, objectType string, bookmark string, batchSize int) ([]*queryresult.KV, bool, error) {
+	iterator, err := stub.GetStateByPartialCompositeKey(objectType, []string{})
+	if err != nil {
+		return nil, false, err
+	}
+	defer iterator.Close()
+
+	batch := []*queryresult.KV{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, false, err
+		}
+		if queryResult.Key <= bookmark {
+			continue
+		}
+		if len(batch) == batchSize {
+			return batch, true, nil
+		}
+		batch = append(batch, queryResult)
+	}
+	return batch, false, nil
+}
+
+//runSchemaMigrations runs the next step the ledger has not run yet and stores its version once it's done,
+//the following steps are run by the next MigrateSchema transactions
+func (t *SampleToken) runSchemaMigrations(stub shim.ChaincodeStubInterface, ownerID string, params map[string]interface{}) error {
+	version, err := t.GetSchemaVersion(stub)
 	if err != nil {
 		return err
 	}
-	//assumes the 4th element in `args` is the comment
-	if len(args) == 4 {
-		_, receiverID, _, comment := args[0], args[1], args[2], args[3]
-		return setMemo(stub, receiverID, comment)
+	if version > latestSchemaVersion() {
+		return fmt.Errorf("the ledger schema version %v is newer than the chaincode's (%v)", version, latestSchemaVersion())
+	}
+	for _, migration := range schemaMigrations {
+		if migration.Version <= version {
+			continue
+		}
+		stepParams, ok := params[migration.Name].(map[string]interface{})
+		if !ok {
+			stepParams = map[string]interface{}{}
+		}
+		logger.Infof("[sample-token.runSchemaMigrations] migrating the ledger to version %v (%v)...", migration.Version, migration.Name)
+		done, err := migration.Run(t, stub, ownerID, stepParams)
+		if err != nil {
+			return fmt.Errorf("migration %v (%v) failed: %v", migration.Version, migration.Name, err)
+		}
+		if !done {
+			return nil
+		}
+		return PutConfigState(stub, schemaVersionKey, []byte(strconv.Itoa(migration.Version)))
 	}
 	return nil
 }
 
-//setMemo updates world-state with a composite key of objectType "Memo", attribute of `key` and value of `memo`
-func setMemo(stub shim.ChaincodeStubInterface, key string, memo string) error {
-	memoKey, err := stub.CreateCompositeKey("Memo", []string{key})
+/*changeConfigs applies the state mode & confidential mode `configs` of an upgrade.
+A new state mode starts a stateModeConversion, run by MigrateSchema, the state mode is switched once it's done.
+Disabling the confidential mode keeps the confidential balances, they can't move until it's enabled again.*/
+func (t *SampleToken) changeConfigs(stub shim.ChaincodeStubInterface, configs map[string]string) error {
+	if mode, ok := configs[StateModeKey]; ok {
+		conversion, err := getStateModeConversion(stub)
+		if err != nil {
+			return err
+		}
+		if conversion != nil {
+			return fmt.Errorf("the ledger is being converted to the %v state mode, the owner finishes the conversion with MigrateSchema first", conversion.Mode)
+		}
+		current, err := GetConfig(stub, StateModeKey)
+		if err != nil {
+			return err
+		}
+		if current == "" {
+			current = RawStateMode
+		}
+		if mode != current {
+			logger.Infof("[sample-token.changeConfigs] converting the ledger to the %v state mode", mode)
+			err := PutConfigState(stub, stateModeConversionKey, MalshalJSON(stateModeConversion{Mode: mode}))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if value, ok := configs[ConfidentialModeKey]; ok {
+		logger.Infof("[sample-token.changeConfigs] setting %v to %v", ConfidentialModeKey, value)
+		return PutConfigState(stub, ConfidentialModeKey, []byte(value))
+	}
+	return nil
+}
+
+//stateModeConversionKey is the config entry holding the stateModeConversion being run
+const stateModeConversionKey = "stateModeConversion"
+
+/*stateModeConversion converts the ledger to the state `Mode` in phases, one objectType of the default token or of a class each:
+the allowances & memos are converted `batchSize` keys per MigrateSchema transaction (defaultMigrationBatchSize by default),
+then the config entries, a few per class, are converted with the state mode itself by the last one*/
+type stateModeConversion struct {
+	Mode  string `json:"mode"`
+	Phase int    `json:"phase"`
+}
+
+//stateModeConversionObjectTypes are the objectTypes converted in batches by a stateModeConversion, in order
+var stateModeConversionObjectTypes = []string{AllowanceObjectType, MemoObjectType}
+
+//getStateModeConversion returns the stateModeConversion being run, nil if there is none
+func getStateModeConversion(stub shim.ChaincodeStubInterface) (*stateModeConversion, error) {
+	value, err := GetConfigState(stub, stateModeConversionKey)
+	if err != nil || len(value) == 0 {
+		return nil, err
+	}
+	conversion := &stateModeConversion{}
+	if err := json.Unmarshal(value, conversion); err != nil {
+		return nil, err
+	}
+	return conversion, nil
+}
+
+//convertStateMode runs the next batch of the stateModeConversion, if any, the state is read in the mode being left until the last one
+func (t *SampleToken) convertStateMode(stub shim.ChaincodeStubInterface, params map[string]interface{}) error {
+	conversion, err := getStateModeConversion(stub)
+	if err != nil || conversion == nil {
+		return err
+	}
+	stepParams, ok := params["convertStateMode"].(map[string]interface{})
+	if !ok {
+		stepParams = map[string]interface{}{}
+	}
+	batchSize, err := parseBatchSize(stepParams)
+	if err != nil {
+		return err
+	}
+	classes, err := t.GetClasses(stub)
+	if err != nil {
+		return err
+	}
+	stubs := []shim.ChaincodeStubInterface{stub}
+	for _, class := range classes {
+		classStub, err := t.ClassStub(stub, []string{class.ID})
+		if err != nil {
+			return err
+		}
+		stubs = append(stubs, classStub)
+	}
+
+	if conversion.Phase < len(stubs)*len(stateModeConversionObjectTypes) {
+		phaseStub := stubs[conversion.Phase/len(stateModeConversionObjectTypes)]
+		objectType := stateModeConversionObjectTypes[conversion.Phase%len(stateModeConversionObjectTypes)]
+		done, err := migrateBatch(phaseStub, objectType, batchSize, func(key string, value []byte) error {
+			return ConvertDocument(phaseStub, key, value, conversion.Mode)
+		})
+		if err != nil || !done {
+			return err
+		}
+		conversion.Phase++
+		return PutConfigState(stub, stateModeConversionKey, MalshalJSON(conversion))
+	}
+
+	logger.Infof("[sample-token.convertStateMode] converting the config of the token & its %v classes to the %v state mode", len(classes), conversion.Mode)
+	for _, s := range stubs {
+		if err := ConvertDocuments(s, ConfigObjectType, conversion.Mode); err != nil {
+			return err
+		}
+	}
+	err = DelConfigState(stub, stateModeConversionKey)
 	if err != nil {
 		return err
 	}
-	customLogger.Infof("setting %v to memo %v", memoKey, memo)
-	return stub.PutState(memoKey, []byte(memo))
+	//the state mode is written in the new mode, which can't be read back in the same transaction
+	return PutConfigState(WithConfigs(stub, map[string]string{StateModeKey: conversion.Mode}), StateModeKey, []byte(conversion.Mode))
 }
 
-/*GetBalanceOf is customed version of ERC20's standard, it rejects unregistered clients*/
-func (t *CustomBasicToken) GetBalanceOf(stub shim.ChaincodeStubInterface, args []string) (*big.Int, error) {
-	tokenBalance, err := stub.GetState(args[0])
-	logger.Infof("[sample-token.GetBalanceOf] balance of %v: %v", args[0], string(tokenBalance))
-	// if the returned buffer is empty, this account is not registered
-	if len(tokenBalance) == 0 {
-		logger.Noticef("[sample-token.GetBalanceOf] %v is not registered", args[0])
-		return nil, fmt.Errorf("%v is not registered", args[0])
+//grantOwnerRoles hands every role to the owner of ledgers created before roles existed, which have no admin yet,
+//so privileged functions stay reachable
+func grantOwnerRoles(t *SampleToken, stub shim.ChaincodeStubInterface, ownerID string, params map[string]interface{}) (bool, error) {
+	admins, err := t.GetRoleMembers(stub, []string{erc20roles.ADMIN})
+	if err != nil || len(admins) != 0 {
+		return true, err
 	}
-	return BufferToBigInt(DefaultToZeroIfEmpty(tokenBalance)), err
+	return true, t.grantAllRoles(stub, ownerID)
 }
 
-/*Activate is a customed non standard erc20 that set the balance of client to "0".
-This marks the active state of target so that subsequent Transfer operations will be successful
+//legacyConfigKeys are the token attributes & configurations stored under bare keys before they were namespaced
+var legacyConfigKeys = []string{"owner", "pendingOwner", "name", "symbol", "decimals", "totalSupply", "isPaused", "multiSigPolicy", "mspListMode", "activationPolicy", IDSchemeKey, IdemixIDSchemeKey}
 
-* `args[0]` - the key ID of target client.*/
-func (t *SampleToken) Activate(stub shim.ChaincodeStubInterface, args []string, getBalanceOf func(shim.ChaincodeStubInterface, []string) (*big.Int, error)) error {
-	clientID := args[0]
-	balanceOfReceiver, err := getBalanceOf(stub, []string{clientID})
-	//if the client is never activated before
-	if err != nil && balanceOfReceiver == nil {
-		logger.Noticef("[sample-token.Activate] registering %v...", clientID)
-		// set the buffer to "0"
-		// so the next time (customed) `GetBalanceOf` is called it won't show error
-		return stub.PutState(clientID, []byte{48})
+//objectType of the former `Allowance~[ownerID]~[spenderID]` index of the bare allowance keys
+const legacyAllowanceObjectType = "Allowance"
+
+//namespaceKeys moves the bare keys of a ledger written before the keys were namespaced:
+//the token attributes & configurations to `config~[name]`, the `[ownerID]-[spenderID]` allowances to `allowance~[ownerID]~[spenderID]`
+//and the balances, stored under the raw account IDs, to `balance~[accountID]`, `batchSize` bare keys per transaction (defaultMigrationBatchSize by default).
+//Allowances are told apart from balances by the former `Allowance~[ownerID]~[spenderID]` index, keys which may be either fail the migration
+//unless they are listed in the optional `balances` parameter (bare keys) or `allowances` parameter (`[ownerID, spenderID]` pairs).
+func namespaceKeys(t *SampleToken, stub shim.ChaincodeStubInterface, ownerID string, params map[string]interface{}) (bool, error) {
+	batchSize, err := parseBatchSize(params)
+	if err != nil {
+		return false, err
 	}
-	logger.Errorf("[sample-token.Activate] %v is already registered", clientID)
-	return fmt.Errorf("%v is already registered", clientID)
+	balances := map[string]bool{}
+	if keys, ok := params["balances"].([]interface{}); ok {
+		for _, key := range keys {
+			balances[fmt.Sprint(key)] = true
+		}
+	}
+	allowances := map[string][2]string{}
+	if pairs, ok := params["allowances"].([]interface{}); ok {
+		for _, pair := range pairs {
+			ids, ok := pair.([]interface{})
+			if !ok || len(ids) != 2 {
+				return false, fmt.Errorf("`allowances` should list [ownerID, spenderID] pairs, got %v", pair)
+			}
+			owner, spender := fmt.Sprint(ids[0]), fmt.Sprint(ids[1])
+			allowances[owner+"-"+spender] = [2]string{owner, spender}
+		}
+	}
+
+	//the token attributes are moved by the first batch, the others find them namespaced
+	isConfig := map[string]bool{}
+	for _, name := range legacyConfigKeys {
+		isConfig[name] = true
+		value, err := stub.GetState(name)
+		if err != nil {
+			return false, err
+		}
+		if len(value) == 0 {
+			continue
+		}
+		logger.Infof("[sample-token.namespaceKeys] moving %v to the config namespace", name)
+		if err := PutConfigState(stub, name, value); err != nil {
+			return false, err
+		}
+		if err := stub.DelState(name); err != nil {
+			return false, err
+		}
+	}
+
+	iterator, err := stub.GetStateByRange("", "")
+	if err != nil {
+		return false, err
+	}
+	defer iterator.Close()
+
+	moved := 0
+	for iterator.HasNext() {
+		if moved == batchSize {
+			logger.Infof("[sample-token.namespaceKeys] moved %v keys, the next ones are moved by the next transaction", moved)
+			return false, nil
+		}
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return false, err
+		}
+		key, value := queryResult.GetKey(), queryResult.GetValue()
+		//composite keys are already namespaced (peers leave them out of the range)
+		if isConfig[key] || strings.HasPrefix(key, "\x00") {
+			continue
+		}
+		moved++
+
+		pair, isAllowance := allowances[key]
+		if !isAllowance && !balances[key] {
+			if pair, isAllowance, err = classifyLegacyKey(stub, key, isConfig); err != nil {
+				return false, err
+			}
+		}
+		if isAllowance {
+			logger.Infof("[sample-token.namespaceKeys] moving allowance of %v from %v to the allowance namespace", pair[1], pair[0])
+			err = moveLegacyAllowance(stub, pair[0], pair[1], value)
+		} else {
+			logger.Infof("[sample-token.namespaceKeys] moving balance of %v to the balance namespace", key)
+			err = moveLegacyBalance(stub, key, value)
+		}
+		if err != nil {
+			return false, err
+		}
+		if err := stub.DelState(key); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
 }
 
-//#endregion custom non-standard ERC20 implementation (transaction memo)
+//moveLegacyAllowance writes a bare allowance to `allowance~[ownerID]~[spenderID]`, indexed by spender,
+//and removes it from the former index
+func moveLegacyAllowance(stub shim.ChaincodeStubInterface, ownerID string, spenderID string, value []byte) error {
+	if err := PutAllowanceState(stub, ownerID, spenderID, value); err != nil {
+		return err
+	}
+	//ledgers written before the index existed need it to clear & move allowances
+	spenderKey, err := stub.CreateCompositeKey(AllowanceSpenderObjectType, []string{spenderID, ownerID})
+	if err != nil {
+		return err
+	}
+	if err := stub.PutState(spenderKey, []byte{0x00}); err != nil {
+		return err
+	}
+	indexKey, err := stub.CreateCompositeKey(legacyAllowanceObjectType, []string{ownerID, spenderID})
+	if err != nil {
+		return err
+	}
+	return stub.DelState(indexKey)
+}
+
+//moveLegacyBalance writes a bare balance to `balance~[accountID]`, as the bare string accountRecords converts to an Account record
+func moveLegacyBalance(stub shim.ChaincodeStubInterface, accountID string, value []byte) error {
+	balanceKey, err := stub.CreateCompositeKey(BalanceObjectType, []string{accountID})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(balanceKey, value)
+}
+
+//classifyLegacyKey tells if the bare `key` is an allowance (and of which owner & spender) or a balance:
+//`[ownerID]-[spenderID]` keys of an indexed allowance are allowances, keys which don't start with an account ID & a dash are balances,
+//the other keys are either, and fail the migration
+func classifyLegacyKey(stub shim.ChaincodeStubInterface, key string, isConfig map[string]bool) ([2]string, bool, error) {
+	candidates := [][2]string{}
+	for i := strings.Index(key, "-"); i >= 0; i = nextIndex(key, "-", i) {
+		ownerID, spenderID := key[:i], key[i+1:]
+		if spenderID == "" {
+			continue
+		}
+		indexKey, err := stub.CreateCompositeKey(legacyAllowanceObjectType, []string{ownerID, spenderID})
+		if err != nil {
+			return [2]string{}, false, err
+		}
+		index, err := stub.GetState(indexKey)
+		if err != nil {
+			return [2]string{}, false, err
+		}
+		if len(index) != 0 {
+			return [2]string{ownerID, spenderID}, true, nil
+		}
+		isAccount, err := isLegacyAccount(stub, ownerID, isConfig)
+		if err != nil {
+			return [2]string{}, false, err
+		}
+		if isAccount {
+			candidates = append(candidates, [2]string{ownerID, spenderID})
+		}
+	}
+	if len(candidates) != 0 {
+		return [2]string{}, false, fmt.Errorf("bare key %v may be a balance or an allowance of %v from %v, "+
+			"list it in the `balances` or `allowances` parameter of namespaceKeys", key, candidates[0][1], candidates[0][0])
+	}
+	return [2]string{}, false, nil
+}
+
+//isLegacyAccount tells if `accountID` has a balance, under its bare key or already moved to the balance namespace
+func isLegacyAccount(stub shim.ChaincodeStubInterface, accountID string, isConfig map[string]bool) (bool, error) {
+	if isConfig[accountID] {
+		return false, nil
+	}
+	value, err := stub.GetState(accountID)
+	if err != nil || len(value) != 0 {
+		return len(value) != 0, err
+	}
+	account, err := GetAccountRecord(stub, accountID)
+	return account != nil, err
+}
+
+//pruneZeroAllowances removes the zero allowances & their spender index, which are no longer kept,
+//so the allowance lists of `GetAllowancesOf` & `GetApprovalsFor` only hold actual approvals, `batchSize` allowances per transaction
+func pruneZeroAllowances(t *SampleToken, stub shim.ChaincodeStubInterface, ownerID string, params map[string]interface{}) (bool, error) {
+	batchSize, err := parseBatchSize(params)
+	if err != nil {
+		return false, err
+	}
+	isJSON, err := IsJSONStateMode(stub)
+	if err != nil {
+		return false, err
+	}
+	return migrateBatch(stub, AllowanceObjectType, batchSize, func(key string, value []byte) error {
+		if BufferToBigInt(DecodeDocument(AllowanceObjectType, value, isJSON)).Sign() != 0 {
+			return nil
+		}
+		_, attributes, err := stub.SplitCompositeKey(key)
+		if err != nil {
+			return err
+		}
+		logger.Infof("[sample-token.pruneZeroAllowances] removing zero allowance of %v from %v", attributes[1], attributes[0])
+		if err := stub.DelState(key); err != nil {
+			return err
+		}
+		spenderKey, err := stub.CreateCompositeKey(AllowanceSpenderObjectType, []string{attributes[1], attributes[0]})
+		if err != nil {
+			return err
+		}
+		return stub.DelState(spenderKey)
+	})
+}
+
+//accountRecordV5 is the layout of the account records written by accountRecords,
+//kept apart from Account so later changes of the record don't change what the shipped step writes
+type accountRecordV5 struct {
+	DocType      string   `json:"docType"`
+	ID           string   `json:"account"`
+	MSP          string   `json:"msp"`
+	Balance      *big.Int `json:"balance"`
+	ActivatedAt  int64    `json:"activatedAt"`
+	ActivatedBy  string   `json:"activatedBy"`
+	Frozen       bool     `json:"frozen"`
+	Nonce        uint64   `json:"nonce"`
+	LastActivity int64    `json:"lastActivity"`
+}
+
+//accountRecords converts the balances moved by namespaceKeys to Account records,
+//filled with the activation records & freezes of the accounts, `batchSize` balances per transaction.
+//It runs in its own transactions, after the last batch of namespaceKeys is committed,
+//so it reads the moved balances rather than the bare keys of the same transaction
+func accountRecords(t *SampleToken, stub shim.ChaincodeStubInterface, ownerID string, params map[string]interface{}) (bool, error) {
+	batchSize, err := parseBatchSize(params)
+	if err != nil {
+		return false, err
+	}
+	return migrateBatch(stub, BalanceObjectType, batchSize, func(key string, value []byte) error {
+		if bytes.HasPrefix(value, []byte("{")) {
+			return nil
+		}
+		_, attributes, err := stub.SplitCompositeKey(key)
+		if err != nil {
+			return err
+		}
+		account := &accountRecordV5{
+			DocType: AccountDocType,
+			ID:      attributes[0],
+			MSP:     strings.SplitN(attributes[0], ",", 2)[0],
+			Balance: BufferToBigInt(value),
+		}
+
+		logger.Infof("[sample-token.accountRecords] converting the balance of %v to an account record", account.ID)
+		record, err := t.GetActivationRecord(stub, []string{account.ID})
+		if err != nil {
+			return err
+		}
+		if record != nil {
+			account.ActivatedAt, account.ActivatedBy = record.ApprovedAt, record.ApprovedBy
+		}
+		if account.Frozen, err = t.IsFrozen(stub, []string{account.ID}); err != nil {
+			return err
+		}
+		return stub.PutState(key, MalshalJSON(account))
+	})
+}
+
+//accountClass rewrites the account records of the default token written before their empty class was stored,
+//so QueryAccounts finds them with the indexes on the class, `batchSize` records per transaction
+func accountClass(t *SampleToken, stub shim.ChaincodeStubInterface, ownerID string, params map[string]interface{}) (bool, error) {
+	batchSize, err := parseBatchSize(params)
+	if err != nil {
+		return false, err
+	}
+	return migrateBatch(stub, BalanceObjectType, batchSize, func(key string, value []byte) error {
+		record := struct {
+			Class *string `json:"class"`
+		}{}
+		if bytes.HasPrefix(value, []byte("{")) {
+			if err := json.Unmarshal(value, &record); err != nil {
+				return err
+			}
+		}
+		if record.Class != nil {
+			return nil
+		}
+		_, attributes, err := stub.SplitCompositeKey(key)
+		if err != nil {
+			return err
+		}
+		account, err := DecodeAccountRecord(attributes[0], value)
+		if err != nil {
+			return err
+		}
+		return PutAccountRecord(stub, account)
+	})
+}
+
+//indexActivationRequests adds the pending activation requests written before they were indexed to the index GetActivationRequests pages through,
+//`batchSize` requests per transaction
+func indexActivationRequests(t *SampleToken, stub shim.ChaincodeStubInterface, ownerID string, params map[string]interface{}) (bool, error) {
+	batchSize, err := parseBatchSize(params)
+	if err != nil {
+		return false, err
+	}
+	return migrateBatch(stub, erc20activation.ActivationRequestObjectType, batchSize, func(key string, value []byte) error {
+		_, attributes, err := stub.SplitCompositeKey(key)
+		if err != nil {
+			return err
+		}
+		return t.IndexActivationRequest(stub, attributes)
+	})
+}
+
+//countHolders seeds the holder count of ledgers written before it was maintained by transfers, mints & burns,
+//`batchSize` balances per transaction: each batch adds its holders to the count of the previous ones
+func countHolders(t *SampleToken, stub shim.ChaincodeStubInterface, ownerID string, params map[string]interface{}) (bool, error) {
+	batchSize, err := parseBatchSize(params)
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	//the first batch starts from zero, the token is locked until the migration is done so no transfer changes the count meanwhile
+	bookmark, err := GetConfigState(stub, migrationBookmarkKey)
+	if err != nil {
+		return false, err
+	}
+	if len(bookmark) != 0 {
+		if count, err = GetHolderCount(stub); err != nil {
+			return false, err
+		}
+	}
+	done, err := migrateBatch(stub, BalanceObjectType, batchSize, func(key string, value []byte) error {
+		if BufferToBigInt(DecodeDocument(BalanceObjectType, value, false)).Sign() > 0 {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return done, ResetHolderCount(stub, count)
+}
+
+//nextIndex returns the index of the next `sep` in `s` after index `i`, -1 if there is none
+func nextIndex(s string, sep string, i int) int {
+	next := strings.Index(s[i+1:], sep)
+	if next < 0 {
+		return -1
+	}
+	return i + 1 + next
+}
+
+//#endregion state schema migrations
@@ -2,11 +2,9 @@ package main_test
 
 import (
 	. "erc20"
-	"erc20/lib/erc20burnable"
-	"erc20/lib/erc20detailed"
-	"erc20/lib/erc20mintable"
-	"erc20/lib/erc20ownable"
-	"erc20/lib/erc20pausable"
+	. "erc20/helpers"
+	"erc20/lib/erc20activation"
+	. "erc20/testutils"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	. "github.com/onsi/ginkgo"
@@ -33,17 +31,10 @@ var _ = Describe("Account activation", func() {
 		ownerSubject = `Org1-child1`
 	)
 
-	sampleToken := SampleToken{
-		&CustomBasicToken{},
-		&erc20ownable.Token{},
-		&erc20detailed.Token{},
-		&erc20mintable.Token{},
-		&erc20burnable.Token{},
-		&erc20pausable.Token{},
-	}
+	sampleToken := NewSampleToken()
 
 	// var err error
-	var mockStub *shim.MockStub = shim.NewMockStub("mockStubNormal", &sampleToken)
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubNormal", sampleToken)
 	// var initialTotalSupply *big.Int = Mul(big.NewInt(InitialMintAmount), Pow(10, StringToInt(tokenDecimals)))
 
 	// ownerID := ownerOrg + "," + ownerIssuer + "," + ownerSubject
@@ -65,7 +56,11 @@ var _ = Describe("Account activation", func() {
 	})
 
 	It("Should activate the fake account", func() {
-		err := sampleToken.Activate(mockStub, []string{"fake-account"}, sampleToken.GetBalanceOf)
+		//the invoker is recorded as the approver of the activation
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+
+		err = sampleToken.Activate(mockStub, []string{"fake-account"}, sampleToken.GetBalanceOf)
 		Expect(err).To(BeNil())
 	})
 
@@ -74,4 +69,100 @@ var _ = Describe("Account activation", func() {
 		Expect(err).To(BeNil())
 		Expect(balance.String()).To(Equal("0"))
 	})
+
+	When("An activation policy is set", func() {
+		var policyStub *shim.MockStub = shim.NewMockStub("mockStubActivationPolicy", sampleToken)
+
+		ownerID := ownerOrg + "," + ownerIssuer + "," + ownerSubject
+		fromID := fromOrg + "," + issuer + "," + fromSubject
+		toID := toOrg + "," + issuer + "," + toSubject
+
+		It("Initializes the token with the `self` policy", func() {
+			_, err := SetCurrentCaller(policyStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(policyStub.MockInit(txID, [][]byte{[]byte(`{"name": "activation", "symbol": "AC", "decimals": "0"}`)}).Message).To(BeEmpty())
+			Expect(policyStub.MockInvoke(txID, [][]byte{[]byte("SetActivationPolicy"), []byte(erc20activation.SELF)}).Message).To(BeEmpty())
+		})
+
+		It("Should only activate the caller's own account", func() {
+			res := policyStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(toID)})
+			Expect(res.Message).To(ContainSubstring("only lets"))
+
+			_, err := SetCurrentCaller(policyStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+			Expect(policyStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+
+			record, err := sampleToken.GetActivationRecord(policyStub, []string{fromID})
+			Expect(err).To(BeNil())
+			Expect(record.ApprovedBy).To(Equal(fromID))
+		})
+
+		It("Should queue activations with the `approval` policy", func() {
+			_, err := SetCurrentCaller(policyStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(policyStub.MockInvoke(txID, [][]byte{[]byte("SetActivationPolicy"), []byte(erc20activation.APPROVAL)}).Message).To(BeEmpty())
+
+			_, err = SetCurrentCaller(policyStub, toOrg, Client2Cert)
+			Expect(err).To(BeNil())
+			res := policyStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(toID)})
+			Expect(res.Message).To(ContainSubstring("requires an approved RequestActivation"))
+			Expect(policyStub.MockInvoke(txID, [][]byte{[]byte("RequestActivation")}).Message).To(BeEmpty())
+
+			page, err := sampleToken.GetActivationRequests(&PaginatedStub{MockStub: policyStub}, []string{})
+			Expect(err).To(BeNil())
+			Expect(page.Requests).To(HaveLen(1))
+			Expect(page.Requests[0].Account).To(Equal(toID))
+			Expect(page.Bookmark).To(BeEmpty())
+		})
+
+		It("Should not queue the activation of an activated account", func() {
+			_, err := SetCurrentCaller(policyStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+			res := policyStub.MockInvoke(txID, [][]byte{[]byte("RequestActivation")})
+			Expect(res.Message).To(ContainSubstring("is already activated"))
+		})
+
+		It("Should not be approved by anyone else than the owner or a registrar", func() {
+			_, err := SetCurrentCaller(policyStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+			res := policyStub.MockInvoke(txID, [][]byte{[]byte("ApproveActivation"), []byte(toID)})
+			Expect(res.Message).To(ContainSubstring("role REGISTRAR"))
+		})
+
+		It("Should activate once approved by the owner", func() {
+			_, err := SetCurrentCaller(policyStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(policyStub.MockInvoke(txID, [][]byte{[]byte("ApproveActivation"), []byte(toID)}).Message).To(BeEmpty())
+
+			balance, err := sampleToken.GetBalanceOf(policyStub, []string{toID})
+			Expect(err).To(BeNil())
+			Expect(balance.String()).To(Equal("0"))
+
+			record, err := sampleToken.GetActivationRecord(policyStub, []string{toID})
+			Expect(err).To(BeNil())
+			Expect(record.ApprovedBy).To(Equal(ownerID))
+			Expect(record.RequestedAt).NotTo(BeZero())
+
+			page, err := sampleToken.GetActivationRequests(&PaginatedStub{MockStub: policyStub}, []string{})
+			Expect(err).To(BeNil())
+			Expect(page.Requests).To(BeEmpty())
+		})
+
+		It("Should index the pending requests written before the index", func() {
+			legacyID := toOrg + ",Org1-child2,Org1-child1-client2"
+			requestKey, err := policyStub.CreateCompositeKey(erc20activation.ActivationRequestObjectType, []string{legacyID})
+			Expect(err).To(BeNil())
+			policyStub.MockTransactionStart(txID)
+			Expect(policyStub.PutState(requestKey, []byte(`{"account":"`+legacyID+`","requestedAt":1,"status":"pending"}`))).To(BeNil())
+			Expect(PutConfigState(policyStub, "schemaVersion", []byte("6"))).To(BeNil())
+			policyStub.MockTransactionEnd(txID)
+
+			_, err = MigrateSchema(policyStub, txID, `{}`, "7")
+			Expect(err).To(BeNil())
+			page, err := sampleToken.GetActivationRequests(&PaginatedStub{MockStub: policyStub}, []string{})
+			Expect(err).To(BeNil())
+			Expect(page.Requests).To(HaveLen(1))
+			Expect(page.Requests[0].Account).To(Equal(legacyID))
+		})
+	})
 })
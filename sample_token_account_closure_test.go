@@ -0,0 +1,98 @@
+package main_test
+
+import (
+	. "erc20"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Account closure", func() {
+	const (
+		txID = `test-closure-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerIssuer  = `Org1`
+		ownerOrg     = `sampleOrgMSP`
+		ownerSubject = `Org1-child1`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubClosure", sampleToken)
+
+	ownerID := ownerOrg + "," + ownerIssuer + "," + ownerSubject
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+
+	It("Initializes the token as owner, funds `fromID` & sets up allowances", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "closure", "symbol": "CL", "decimals": "0"}`)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(toID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("10")}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(fromID), []byte("5")}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(toID), []byte("3")}).Message).To(BeEmpty())
+	})
+
+	It("Should not sweep to an unregistered account", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("Deactivate"), []byte("fake-account")})
+		Expect(res.Message).To(ContainSubstring("is not registered"))
+	})
+
+	It("Should not be closed by anyone else than an admin", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("CloseAccount"), []byte(toID), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("role ADMIN"))
+	})
+
+	It("Deactivates `fromID`, sweeping its balance to `toID`", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Deactivate"), []byte(toID)}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{toID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("10"))
+
+		_, err = sampleToken.GetBalanceOf(mockStub, []string{fromID})
+		Expect(err.Error()).To(ContainSubstring("is not registered"))
+	})
+
+	It("Should clear the allowances given by & to `fromID`", func() {
+		allowance, err := sampleToken.GetAllowance(mockStub, []string{fromID, toID})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("0"))
+
+		allowance, err = sampleToken.GetAllowance(mockStub, []string{ownerID, fromID})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("0"))
+	})
+
+	It("Should let an admin close `toID`", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("CloseAccount"), []byte(toID), []byte(ownerID)}).Message).To(BeEmpty())
+
+		_, err = sampleToken.GetBalanceOf(mockStub, []string{toID})
+		Expect(err.Error()).To(ContainSubstring("is not registered"))
+	})
+
+	It("Should activate a closed account anew", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(toID)}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{toID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("0"))
+	})
+})
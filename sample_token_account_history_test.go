@@ -0,0 +1,206 @@
+package main_test
+
+import (
+	"bytes"
+	. "erc20"
+	. "erc20/helpers"
+	"erc20/lib/erc20holders"
+	. "erc20/testutils"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+//historyStub serves the history of the keys recorded after each transaction, which MockStub doesn't implement
+type historyStub struct {
+	*shim.MockStub
+	history map[string][]*queryresult.KeyModification
+}
+
+//record appends the changes of `keys` made by the last transaction to their history
+func (s *historyStub) record(keys ...string) {
+	for _, key := range keys {
+		modifications := s.history[key]
+		value, ok := s.State[key]
+		if len(modifications) == 0 && !ok {
+			continue
+		}
+		if len(modifications) != 0 && bytes.Equal(modifications[len(modifications)-1].Value, value) {
+			continue
+		}
+		s.history[key] = append(modifications, &queryresult.KeyModification{TxId: s.TxID, Value: value, Timestamp: s.TxTimestamp, IsDelete: !ok})
+	}
+}
+
+func (s *historyStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{modifications: s.history[key]}, nil
+}
+
+type historyIterator struct {
+	modifications []*queryresult.KeyModification
+}
+
+func (i *historyIterator) HasNext() bool {
+	return len(i.modifications) != 0
+}
+
+func (i *historyIterator) Next() (*queryresult.KeyModification, error) {
+	modification := i.modifications[0]
+	i.modifications = i.modifications[1:]
+	return modification, nil
+}
+
+func (i *historyIterator) Close() error {
+	return nil
+}
+
+var _ = Describe("Account history", func() {
+	const (
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubAccountHistory", sampleToken)
+	stub := &historyStub{MockStub: mockStub, history: map[string][]*queryresult.KeyModification{}}
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	newFromID := fromOrg + ",Org1-child2,Org1-child1-client1"
+	closedID := fromOrg + ",Org1-child2,Org1-child1-client2"
+
+	balanceKey := func(accountID string) string {
+		key, err := mockStub.CreateCompositeKey(BalanceObjectType, []string{accountID})
+		Expect(err).To(BeNil())
+		return key
+	}
+
+	invoker := &TxInvoker{Prefix: "test-account-history"}
+	invoke := func(args ...string) {
+		_, message := invoker.Invoke(mockStub, nil, args...)
+		Expect(message).To(BeEmpty())
+		stub.TxID = invoker.TxID()
+		stub.record(balanceKey(ownerID), balanceKey(fromID), balanceKey(newFromID), balanceKey(closedID))
+	}
+	getHistory := func(args ...string) *erc20holders.HistoryPage {
+		page, err := sampleToken.GetAccountHistory(stub, args, sampleToken.ResolveMigratedID)
+		Expect(err).To(BeNil())
+		return page
+	}
+
+	It("Initializes the token & moves tokens with & without memos", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit("test-account-history-00", [][]byte{[]byte(`{"name": "history", "symbol": "AH", "decimals": "0", "initialSupply": "1000"}`)}).Message).To(BeEmpty())
+		stub.TxID = "test-account-history-00"
+		stub.record(balanceKey(ownerID))
+
+		invoke("Activate", fromID)
+		invoke("Transfer", fromID, "10", "rent", "INV-7")
+		invoke("Transfer", fromID, "5")
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		invoke("Transfer", ownerID, "3", "refund")
+		invoke("SetAccountMetadata", "label", "payroll")
+	})
+
+	It("Should return the balance changes with their memos", func() {
+		page := getHistory(fromID)
+		Expect(page.Bookmark).To(BeEmpty())
+		Expect(page.History).To(HaveLen(3))
+
+		Expect(page.History[0].TxID).To(Equal("test-account-history-02"))
+		Expect(page.History[0].Timestamp).NotTo(BeZero())
+		Expect(page.History[0].Balance.String()).To(Equal("10"))
+		Expect(page.History[0].Delta.String()).To(Equal("10"))
+		Expect(page.History[0].Memo.Memo).To(Equal("rent"))
+		Expect(page.History[0].Memo.Reference).To(Equal("INV-7"))
+		Expect(page.History[0].Memo.From).To(Equal(ownerID))
+
+		Expect(page.History[1].Balance.String()).To(Equal("15"))
+		Expect(page.History[1].Delta.String()).To(Equal("5"))
+		Expect(page.History[1].Memo).To(BeNil())
+
+		Expect(page.History[2].Balance.String()).To(Equal("12"))
+		Expect(page.History[2].Delta.String()).To(Equal("-3"))
+		Expect(page.History[2].Memo.Memo).To(Equal("refund"))
+		Expect(page.History[2].Memo.To).To(Equal(ownerID))
+
+		owner := getHistory(ownerID)
+		Expect(owner.History).To(HaveLen(4))
+		Expect(owner.History[0].Delta.String()).To(Equal("1000"))
+		Expect(owner.History[3].Balance.String()).To(Equal("988"))
+		Expect(owner.History[3].Memo.Memo).To(Equal("refund"))
+	})
+
+	It("Should page through the history", func() {
+		page := getHistory(fromID, "2")
+		Expect(page.History).To(HaveLen(2))
+		Expect(page.Bookmark).NotTo(BeEmpty())
+
+		page = getHistory(fromID, "2", page.Bookmark)
+		Expect(page.History).To(HaveLen(1))
+		Expect(page.History[0].Balance.String()).To(Equal("12"))
+		Expect(page.Bookmark).To(BeEmpty())
+	})
+
+	It("Should filter the history by time range", func() {
+		first := getHistory(fromID).History[0].Timestamp
+		Expect(getHistory(fromID, "", "", fmt.Sprint(first), fmt.Sprint(first+3600)).History).To(HaveLen(3))
+		Expect(getHistory(fromID, "", "", fmt.Sprint(first+3600)).History).To(BeEmpty())
+		Expect(getHistory(fromID, "", "", "", fmt.Sprint(first-1)).History).To(BeEmpty())
+
+		_, err := sampleToken.GetAccountHistory(stub, []string{fromID, "", "", fmt.Sprint(first), fmt.Sprint(first - 1)}, sampleToken.ResolveMigratedID)
+		Expect(err).NotTo(BeNil())
+		_, err = sampleToken.GetAccountHistory(stub, []string{fromID, "", "", "yesterday"}, sampleToken.ResolveMigratedID)
+		Expect(err.Error()).To(ContainSubstring("invalid start time"))
+	})
+
+	It("Should keep the memos of a migrated account in its history", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		invoke("Activate", newFromID)
+		_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		invoke("MigrateAccount", fromID, newFromID)
+
+		page := getHistory(fromID)
+		Expect(page.History).To(HaveLen(4))
+		Expect(page.History[0].Memo.Memo).To(Equal("rent"))
+		Expect(page.History[2].Memo.Memo).To(Equal("refund"))
+		Expect(page.History[3].Delta.String()).To(Equal("-12"))
+		Expect(page.History[3].Deleted).To(BeTrue())
+
+		//the history of the new account starts with the migration
+		page = getHistory(newFromID)
+		Expect(page.History).To(HaveLen(1))
+		Expect(page.History[0].Delta.String()).To(Equal("12"))
+		Expect(page.History[0].TxID).To(Equal(getHistory(fromID).History[3].TxID))
+	})
+
+	It("Should keep the closing of an account with no balance", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		invoke("Activate", closedID)
+		invoke("CloseAccount", closedID, ownerID)
+
+		page := getHistory(closedID)
+		Expect(page.History).To(HaveLen(1))
+		Expect(page.History[0].Delta.String()).To(Equal("0"))
+		Expect(page.History[0].Deleted).To(BeTrue())
+	})
+
+	It("Should fail without a history database", func() {
+		res := mockStub.MockInvoke("test-account-history-id", [][]byte{[]byte("GetAccountHistory"), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("not implemented"))
+	})
+})
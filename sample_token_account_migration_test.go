@@ -0,0 +1,311 @@
+package main_test
+
+import (
+	"encoding/json"
+	. "erc20"
+	. "erc20/helpers"
+	"erc20/lib/erc20migration"
+	. "erc20/testutils"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Account migration", func() {
+	const (
+		txID = `test-migration-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+
+		//the certificates reissued by another CA
+		newIssuer = `Org1-child2`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubMigration", sampleToken)
+
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+	newFromID := fromOrg + "," + newIssuer + "," + fromSubject
+	newToID := toOrg + "," + newIssuer + "," + toSubject
+
+	It("Initializes the token as owner & funds `fromID`", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "migration", "symbol": "MG", "decimals": "0"}`)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(toID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("100"), []byte("salary")}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(toID), []byte("10")}).Message).To(BeEmpty())
+	})
+
+	It("Should not be migrated by anyone else", func() {
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(fromID), []byte(newFromID)})
+		Expect(res.Message).To(ContainSubstring("nor the token owner"))
+	})
+
+	It("Should wait for the MSP admin once approved by the owner", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(fromID), []byte(newFromID)}).Message).To(BeEmpty())
+
+		migration, err := sampleToken.GetMigration(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(migration.OwnerID).To(Equal(ownerOrg + ",Org1,Org1-child1"))
+		Expect(migration.MSPAdminID).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("100"))
+	})
+
+	It("Should not be migrated by the old identity to an account that is not activated", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(fromID), []byte(newFromID)})
+		Expect(res.Message).To(ContainSubstring("must be activated before"))
+	})
+
+	It("Should migrate when called by the old identity", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(newFromID)}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(fromID), []byte(newFromID)}).Message).To(BeEmpty())
+
+		_, err = sampleToken.GetBalanceOf(mockStub, []string{fromID})
+		Expect(err.Error()).To(ContainSubstring("is not registered"))
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{newFromID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("100"))
+
+		allowance, err := sampleToken.GetAllowance(mockStub, []string{newFromID, toID})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("10"))
+		allowance, err = sampleToken.GetAllowance(mockStub, []string{fromID, toID})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("0"))
+
+		memo, err := sampleToken.GetMemo(mockStub, []string{newFromID})
+		Expect(err).To(BeNil())
+		Expect(memo).To(Equal("salary"))
+		//the memo history moves to the new ID, its records keep the account IDs of the time
+		page, err := sampleToken.GetMemos(&PaginatedStub{MockStub: mockStub}, []string{newFromID, "10", ""})
+		Expect(err).To(BeNil())
+		Expect(page.Memos).To(HaveLen(1))
+		Expect(page.Memos[0].To).To(Equal(fromID))
+		page, err = sampleToken.GetMemos(&PaginatedStub{MockStub: mockStub}, []string{fromID, "10", ""})
+		Expect(err).To(BeNil())
+		Expect(page.Memos).To(BeEmpty())
+
+		record, err := sampleToken.GetActivationRecord(mockStub, []string{newFromID})
+		Expect(err).To(BeNil())
+		Expect(record.Account).To(Equal(newFromID))
+
+		migration, err := sampleToken.GetMigration(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(migration).To(BeNil())
+	})
+
+	It("Should redirect transfers sent to the old account", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("5")}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{newFromID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("105"))
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetForwarding"), []byte(fromID)})
+		Expect(res.Message).To(BeEmpty())
+		forwarding := erc20migration.Forwarding{}
+		Expect(json.Unmarshal(res.Payload, &forwarding)).To(BeNil())
+		Expect(forwarding.NewID).To(Equal(newFromID))
+		Expect(forwarding.Mode).To(Equal(erc20migration.REDIRECT))
+	})
+
+	It("Should not migrate an account twice", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(fromID), []byte(toID)})
+		Expect(res.Message).To(ContainSubstring("has already migrated"))
+	})
+
+	It("Should not activate a migrated account again, nor migrate an account to it", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("can not be activated again"))
+
+		//migrating the new account back would chain both forwardings into a loop
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(newFromID), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("can not be migrated to"))
+	})
+
+	It("Should add up the allowances landing on the same pair once migrated", func() {
+		oldCert, err := NewCert("Org1-child3", "Org1-child3-client", nil, nil)
+		Expect(err).To(BeNil())
+		newCert, err := NewCert("Org1-child4", "Org1-child3-client", nil, nil)
+		Expect(err).To(BeNil())
+		oldID, newID := fromOrg+",Org1-child3,Org1-child3-client", fromOrg+",Org1-child4,Org1-child3-client"
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(oldID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(newID)}).Message).To(BeEmpty())
+
+		//an allowance to itself, one to the new account & one from it all become the new account's allowance to itself
+		_, err = SetCurrentCaller(mockStub, fromOrg, oldCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(oldID), []byte("1")}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(newID), []byte("4")}).Message).To(BeEmpty())
+		_, err = SetCurrentCaller(mockStub, fromOrg, newCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(oldID), []byte("2")}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, oldCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(oldID), []byte(newID)}).Message).To(BeEmpty())
+
+		allowance, err := sampleToken.GetAllowance(mockStub, []string{newID, newID})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("7"))
+		for _, pair := range [][]string{{oldID, oldID}, {oldID, newID}, {newID, oldID}} {
+			allowance, err = sampleToken.GetAllowance(mockStub, pair)
+			Expect(err).To(BeNil())
+			Expect(allowance.String()).To(Equal("0"))
+		}
+	})
+
+	It("Should move the encrypted memos & clear the memo key", func() {
+		oldCert, err := NewCert("Org1-child5", "Org1-child5-client", nil, nil)
+		Expect(err).To(BeNil())
+		newCert, err := NewCert("Org1-child6", "Org1-child5-client", nil, nil)
+		Expect(err).To(BeNil())
+		oldID, newID := fromOrg+",Org1-child5,Org1-child5-client", fromOrg+",Org1-child6,Org1-child5-client"
+		encrypted := map[string][]byte{
+			MemoTransientKey:        []byte("invoice #7"),
+			MemoEntropyTransientKey: []byte(strings.Repeat("e", MinMemoEntropy)),
+		}
+		defer func() { mockStub.TransientMap = nil }()
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(oldID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(newID)}).Message).To(BeEmpty())
+		_, err = SetCurrentCaller(mockStub, fromOrg, oldCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RegisterMemoKey")}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		mockStub.TransientMap = encrypted
+		Expect(mockStub.MockInvoke("test-migration-memo-id", [][]byte{[]byte("Transfer"), []byte(oldID), []byte("10"), []byte(""), []byte("INV-7")}).Message).To(BeEmpty())
+		mockStub.TransientMap = nil
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, oldCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(oldID), []byte(newID)}).Message).To(BeEmpty())
+
+		memo, err := sampleToken.GetMemo(mockStub, []string{newID})
+		Expect(err).To(BeNil())
+		Expect(memo).To(HavePrefix(EncryptedMemoPrefix))
+		page, err := sampleToken.GetMemos(&PaginatedStub{MockStub: mockStub}, []string{newID, "10", ""})
+		Expect(err).To(BeNil())
+		Expect(page.Memos).To(HaveLen(1))
+		Expect(page.Memos[0].Memo).To(Equal(memo))
+		page, err = sampleToken.GetMemosByReference(&PaginatedStub{MockStub: mockStub}, []string{"INV-7", "10", ""})
+		Expect(err).To(BeNil())
+		Expect(page.Memos).To(HaveLen(1))
+		Expect(page.Memos[0].To).To(Equal(oldID))
+
+		//the memo key is the one of the old certificate, the new ID registers its own
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetMemoKey"), []byte(oldID)})
+		Expect(res.Message).To(ContainSubstring("has not registered a memo key"))
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		mockStub.TransientMap = encrypted
+		res = mockStub.MockInvoke("test-migration-memo-id-2", [][]byte{[]byte("Transfer"), []byte(newID), []byte("10")})
+		Expect(res.Message).To(ContainSubstring(newID + " has not registered a memo key"))
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, newCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RegisterMemoKey")}).Message).To(BeEmpty())
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke("test-migration-memo-id-3", [][]byte{[]byte("Transfer"), []byte(newID), []byte("10")}).Message).To(BeEmpty())
+	})
+
+	It("Should fail on forwardings looping back instead of following them forever", func() {
+		mockStub.MockTransactionStart(txID)
+		for oldID, newID := range map[string]string{"loop-a": "loop-b", "loop-b": "loop-a"} {
+			key, err := mockStub.CreateCompositeKey("Forwarding", []string{oldID})
+			Expect(err).To(BeNil())
+			forwarding := erc20migration.Forwarding{OldID: oldID, NewID: newID, Mode: erc20migration.REDIRECT}
+			forwardingBytes, err := json.Marshal(forwarding)
+			Expect(err).To(BeNil())
+			Expect(mockStub.PutState(key, forwardingBytes)).To(BeNil())
+		}
+		mockStub.MockTransactionEnd(txID)
+
+		_, err := sampleToken.ResolveForwarding(mockStub, []string{"loop-a"})
+		Expect(err.Error()).To(ContainSubstring("loops back to loop-a"))
+	})
+
+	It("Should reject transfers sent to an account migrated in `reject` mode", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(newToID)}).Message).To(BeEmpty())
+
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(toID), []byte(newToID), []byte(erc20migration.REJECT)}).Message).To(BeEmpty())
+
+		//the allowance given to the old account follows it
+		allowance, err := sampleToken.GetAllowance(mockStub, []string{newFromID, newToID})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("10"))
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(toID), []byte("5")})
+		Expect(res.Message).To(ContainSubstring("has migrated to " + newToID))
+	})
+
+	It("Should not let the owner approve as the MSP admin of its own MSP as well", func() {
+		//an account of the owner's MSP, the owner's certificate carrying the admin NodeOU
+		oldID, newID := ownerOrg+",Org1,Org1-client", ownerOrg+",Org1,Org1-client-reissued"
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(oldID)}).Message).To(BeEmpty())
+		ownerAdminCert, err := NewCert("Org1", "Org1-child1", []string{"admin"}, nil)
+		Expect(err).To(BeNil())
+		_, err = SetCurrentCaller(mockStub, ownerOrg, ownerAdminCert)
+		Expect(err).To(BeNil())
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(oldID), []byte(newID)})
+		Expect(res.Message).To(BeEmpty())
+		migration, err := sampleToken.GetMigration(mockStub, []string{oldID})
+		Expect(err).To(BeNil())
+		Expect(migration.OwnerID).To(Equal(ownerOrg + ",Org1,Org1-child1"))
+		Expect(migration.MSPAdminID).To(BeEmpty())
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(oldID), []byte(newID)})
+		Expect(res.Message).To(ContainSubstring("has already approved"))
+		forwarding, err := sampleToken.GetForwarding(mockStub, []string{oldID})
+		Expect(err).To(BeNil())
+		Expect(forwarding).To(BeNil())
+	})
+})
@@ -0,0 +1,135 @@
+package main_test
+
+import (
+	"encoding/json"
+	. "erc20"
+	. "erc20/helpers"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Account records", func() {
+	const (
+		txID = `test-account-record-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg = `clientOrg2MSP`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubAccountRecord", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+
+	getAccount := func(accountID string) Account {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetAccount"), []byte(accountID)})
+		Expect(res.Message).To(BeEmpty())
+		account := Account{}
+		Expect(json.Unmarshal(res.Payload, &account)).To(BeNil())
+		return account
+	}
+
+	It("Should reject an invalid token configuration", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "records", "symbol": "RC", "decimals": "0", "initialSupply": "abc"}`)})
+		Expect(res.Message).To(ContainSubstring("invalid token configuration"))
+		res = mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "records", "decimals": "0"}`)})
+		Expect(res.Message).To(ContainSubstring("symbol is missing"))
+	})
+
+	It("Initializes the token, recording the owner's activation & mint", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "records", "symbol": "RC", "decimals": "0", "initialSupply": "1000"}`)}).Message).To(BeEmpty())
+
+		owner := getAccount(ownerID)
+		Expect(owner.DocType).To(Equal(AccountDocType))
+		Expect(owner.MSP).To(Equal(ownerOrg))
+		Expect(owner.Balance.String()).To(Equal("1000"))
+		Expect(owner.ActivatedBy).To(Equal(ownerID))
+		Expect(owner.ActivatedAt).NotTo(BeZero())
+		Expect(owner.Nonce).To(Equal(uint64(1)))
+		Expect(owner.LastActivity).NotTo(BeZero())
+	})
+
+	It("Should record who activated an account", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+
+		from := getAccount(fromID)
+		Expect(from.Balance.String()).To(Equal("0"))
+		Expect(from.ActivatedBy).To(Equal(ownerID))
+		Expect(from.Nonce).To(BeZero())
+		Expect(from.LastActivity).To(BeZero())
+	})
+
+	It("Should count the balance changes", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("10")}).Message).To(BeEmpty())
+
+		from := getAccount(fromID)
+		Expect(from.Balance.String()).To(Equal("10"))
+		Expect(from.Nonce).To(Equal(uint64(1)))
+		Expect(from.LastActivity).NotTo(BeZero())
+		Expect(getAccount(ownerID).Nonce).To(Equal(uint64(2)))
+	})
+
+	It("Should let account holders set their own metadata", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("SetAccountMetadata"), []byte("label"), []byte("payroll")}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("SetAccountMetadata"), []byte("team"), []byte("finance")}).Message).To(BeEmpty())
+		Expect(getAccount(fromID).Metadata).To(Equal(map[string]string{"label": "payroll", "team": "finance"}))
+
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("SetAccountMetadata"), []byte("team"), []byte("")}).Message).To(BeEmpty())
+		Expect(getAccount(fromID).Metadata).To(Equal(map[string]string{"label": "payroll"}))
+		Expect(getAccount(fromID).Balance.String()).To(Equal("10"))
+	})
+
+	It("Should not set the metadata of unregistered accounts", func() {
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("SetAccountMetadata"), []byte("label"), []byte("savings")})
+		Expect(res.Message).To(ContainSubstring("is not registered"))
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetAccount"), []byte("fake-account")})
+		Expect(res.Message).To(ContainSubstring("is not registered"))
+	})
+
+	It("Should convert the balances stored as bare strings on upgrade", func() {
+		mockStub.MockTransactionStart(txID)
+		balanceKey, err := mockStub.CreateCompositeKey(BalanceObjectType, []string{"legacy-account"})
+		Expect(err).To(BeNil())
+		Expect(mockStub.PutState(balanceKey, []byte("25"))).To(BeNil())
+		//a ledger written before the account records
+		Expect(PutConfigState(mockStub, "schemaVersion", []byte("4"))).To(BeNil())
+		mockStub.MockTransactionEnd(txID)
+
+		//bare balances read as records until then, the token waits for the upgrade
+		account, err := sampleToken.GetAccount(mockStub, []string{"legacy-account"})
+		Expect(err).To(BeNil())
+		Expect(account.Balance.String()).To(Equal("25"))
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetAccount"), []byte("legacy-account")})
+		Expect(res.Message).To(ContainSubstring("the ledger is being migrated"))
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{}`)}).Message).To(BeEmpty())
+		_, err = MigrateSchema(mockStub, txID, `{}`, "7")
+		Expect(err).To(BeNil())
+
+		Expect(string(mockStub.State[balanceKey])).To(HavePrefix(`{"docType":"account"`))
+		Expect(getAccount("legacy-account").Balance.String()).To(Equal("25"))
+		Expect(getAccount(fromID).Metadata).To(HaveKey("label"))
+	})
+})
@@ -0,0 +1,110 @@
+package main_test
+
+import (
+	"encoding/json"
+	. "erc20"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alias registry", func() {
+	const (
+		txID = `test-alias-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg = `clientOrg2MSP`
+
+		ownerOrg = `sampleOrgMSP`
+
+		fromAlias = `alice@org1`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubAlias", sampleToken)
+
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+
+	It("Initializes the token as owner", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "alias", "symbol": "AL", "decimals": "0"}`)}).Message).To(BeEmpty())
+	})
+
+	It("Should not resolve an unregistered alias", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("ResolveAlias"), []byte(fromAlias)})
+		Expect(res.Message).To(ContainSubstring("alias " + fromAlias + " is not registered"))
+
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromAlias)}).Message).To(ContainSubstring("is not registered"))
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromAlias), []byte("10")}).Message).To(ContainSubstring("is not registered"))
+	})
+
+	It("Should pass the values that can not be aliases as they are", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte("fake-account")}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte("fake-account"), []byte("10")}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{"fake-account"})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("10"))
+	})
+
+	It("Should reject malformed aliases", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("RegisterAlias"), []byte("Alice")})
+		Expect(res.Message).To(ContainSubstring("invalid alias"))
+	})
+
+	It("Registers an alias for `fromID`", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RegisterAlias"), []byte(fromAlias)}).Message).To(BeEmpty())
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("ResolveAlias"), []byte(fromAlias)})
+		Expect(res.Message).To(BeEmpty())
+		Expect(string(res.Payload)).To(Equal(fromID))
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetAliases"), []byte(fromID)})
+		Expect(res.Message).To(BeEmpty())
+		aliases := []string{}
+		Expect(json.Unmarshal(res.Payload, &aliases)).To(BeNil())
+		Expect(aliases).To(ConsistOf(fromAlias))
+	})
+
+	It("Should not register an alias twice", func() {
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("RegisterAlias"), []byte(fromAlias)})
+		Expect(res.Message).To(ContainSubstring("already registered"))
+	})
+
+	It("Should not be released by another account", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("ReleaseAlias"), []byte(fromAlias)})
+		Expect(res.Message).To(ContainSubstring("is not registered by"))
+	})
+
+	It("Should activate & transfer to the alias", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromAlias)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromAlias), []byte("10")}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("10"))
+	})
+
+	It("Releases the alias", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("ReleaseAlias"), []byte(fromAlias)}).Message).To(BeEmpty())
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("ResolveAlias"), []byte(fromAlias)})
+		Expect(res.Message).To(ContainSubstring("is not registered"))
+	})
+})
@@ -0,0 +1,124 @@
+package main_test
+
+import (
+	. "erc20"
+	"erc20/lib/erc20basic"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Allowance enumeration", func() {
+	const (
+		txID = `test-allowances-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubAllowances", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+
+	//the paged queries are called with a stub implementing the pagination of the peer
+	queries := map[string]func(shim.ChaincodeStubInterface, []string) (*erc20basic.AllowancesPage, error){
+		"GetAllowancesOf": sampleToken.GetAllowancesOf,
+		"GetApprovalsFor": sampleToken.GetApprovalsFor,
+	}
+	getPage := func(args ...string) erc20basic.AllowancesPage {
+		page, err := queries[args[0]](&PaginatedStub{MockStub: mockStub}, args[1:])
+		Expect(err).To(BeNil())
+		return *page
+	}
+
+	It("Initializes the token as owner & approves two spenders", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "allowances", "symbol": "AL", "decimals": "0"}`)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(toID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(fromID), []byte("10")}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(toID), []byte("20")}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(toID), []byte("5")}).Message).To(BeEmpty())
+	})
+
+	It("Should page through the allowances of an owner by spender ID", func() {
+		page := getPage("GetAllowancesOf", ownerID, "1")
+		Expect(page.Allowances).To(HaveLen(1))
+		Expect(page.Allowances[0].Spender).To(Equal(fromID))
+		Expect(page.Allowances[0].Amount.String()).To(Equal("10"))
+		Expect(page.Bookmark).NotTo(BeEmpty())
+
+		page = getPage("GetAllowancesOf", ownerID, "1", page.Bookmark)
+		Expect(page.Allowances).To(HaveLen(1))
+		Expect(page.Allowances[0].Spender).To(Equal(toID))
+		Expect(page.Allowances[0].Amount.String()).To(Equal("20"))
+		Expect(page.Bookmark).To(BeEmpty())
+	})
+
+	It("Should list the approvals of a spender by owner ID", func() {
+		page := getPage("GetApprovalsFor", toID)
+		Expect(page.Allowances).To(HaveLen(2))
+		Expect(page.Allowances[0].Owner).To(Equal(fromID))
+		Expect(page.Allowances[0].Amount.String()).To(Equal("5"))
+		Expect(page.Allowances[1].Owner).To(Equal(ownerID))
+		Expect(page.Bookmark).To(BeEmpty())
+	})
+
+	It("Should remove the allowances spent entirely", func() {
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("TransferFrom"), []byte(ownerID), []byte(fromID), []byte("20")}).Message).To(BeEmpty())
+
+		page := getPage("GetApprovalsFor", toID)
+		Expect(page.Allowances).To(HaveLen(1))
+		Expect(page.Allowances[0].Owner).To(Equal(fromID))
+
+		page = getPage("GetAllowancesOf", ownerID)
+		Expect(page.Allowances).To(HaveLen(1))
+		Expect(page.Allowances[0].Spender).To(Equal(fromID))
+	})
+
+	It("Should remove the allowances approved to zero", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(toID), []byte("0")}).Message).To(BeEmpty())
+
+		Expect(getPage("GetApprovalsFor", toID).Allowances).To(BeEmpty())
+		Expect(getPage("GetAllowancesOf", fromID).Allowances).To(BeEmpty())
+		allowanceKey, err := mockStub.CreateCompositeKey("allowance", []string{fromID, toID})
+		Expect(err).To(BeNil())
+		spenderKey, err := mockStub.CreateCompositeKey("AllowanceSpender", []string{toID, fromID})
+		Expect(err).To(BeNil())
+		Expect(mockStub.State).NotTo(HaveKey(allowanceKey))
+		Expect(mockStub.State).NotTo(HaveKey(spenderKey))
+	})
+
+	It("Should reject the allowances that are not integers >= 0", func() {
+		for _, amount := range []string{"-1", "1.5", "1e3", "abc"} {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(toID), []byte(amount)})
+			Expect(res.Message).To(ContainSubstring("invalid allowance"))
+		}
+	})
+
+	It("Should require the account ID", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetAllowancesOf")})
+		Expect(res.Message).To(ContainSubstring("ID of owner is required"))
+	})
+})
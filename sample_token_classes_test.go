@@ -0,0 +1,202 @@
+package main_test
+
+import (
+	"encoding/json"
+	. "erc20"
+	. "erc20/helpers"
+	"erc20/lib/erc20classes"
+	"erc20/lib/erc20events"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token classes", func() {
+	const (
+		txID         = `test-classes-id`
+		proposalTxID = `test-classes-proposal-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		ownerOrg = `sampleOrgMSP`
+
+		pointsClass = `class:points`
+		fromAlias   = `alice@org1`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubClasses", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	//`fromID` reissued by another CA
+	newFromID := fromOrg + ",Org1-child2," + fromSubject
+
+	It("Initializes the default token as owner", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "default", "symbol": "DF", "decimals": "0"}`)}).Message).To(BeEmpty())
+	})
+
+	It("Should not be created by anyone else than an admin", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("CreateClass"), []byte("points"), []byte(`{"name": "Points", "symbol": "PT", "decimals": "0"}`)})
+		Expect(res.Message).To(ContainSubstring("role ADMIN"))
+	})
+
+	It("Should reject invalid classes", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("CreateClass"), []byte("Points"), []byte(`{"name": "Points", "symbol": "PT", "decimals": "0"}`)})
+		Expect(res.Message).To(ContainSubstring("invalid class ID"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("CreateClass"), []byte("points"), []byte(`{"name": "Points"}`)})
+		Expect(res.Message).To(ContainSubstring("symbol is missing"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetName"), []byte(pointsClass)})
+		Expect(res.Message).To(ContainSubstring("does not exist"))
+	})
+
+	It("Creates a class with its own metadata & supply", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("CreateClass"), []byte("points"), []byte(`{"name": "Points", "symbol": "PT", "decimals": "0", "initialSupply": "500"}`)})
+		Expect(res.Message).To(BeEmpty())
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetClasses")})
+		classes := []erc20classes.TokenClass{}
+		Expect(json.Unmarshal(res.Payload, &classes)).To(BeNil())
+		Expect(classes).To(HaveLen(1))
+		Expect(classes[0].ID).To(Equal("points"))
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetName"), []byte(pointsClass)})
+		Expect(string(res.Payload)).To(Equal("Points"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetName")})
+		Expect(string(res.Payload)).To(Equal("default"))
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetTotalSupply"), []byte(pointsClass)})
+		Expect(string(res.Payload)).To(Equal("500"))
+	})
+
+	It("Should keep the balances of each class apart", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		//aliases are shared by every class
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RegisterAlias"), []byte(fromAlias)}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(pointsClass), []byte(fromAlias)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(pointsClass), []byte(fromAlias), []byte("50")}).Message).To(BeEmpty())
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOf"), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("is not registered"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOf"), []byte(pointsClass), []byte(fromID)})
+		Expect(string(res.Payload)).To(Equal("50"))
+	})
+
+	It("Should pause a class on its own", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Pause"), []byte(pointsClass)}).Message).To(BeEmpty())
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(pointsClass), []byte(fromID), []byte("1")})
+		Expect(res.Message).To(ContainSubstring("not allowed when token is paused"))
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("1")}).Message).To(BeEmpty())
+
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Unpause"), []byte(pointsClass)}).Message).To(BeEmpty())
+	})
+
+	It("Should record the class in the events", func() {
+		lastEvent := func() erc20events.Event {
+			event := erc20events.Event{}
+			for len(mockStub.ChaincodeEventsChannel) > 1 {
+				<-mockStub.ChaincodeEventsChannel
+			}
+			Expect(json.Unmarshal((<-mockStub.ChaincodeEventsChannel).GetPayload(), &event)).To(BeNil())
+			return event
+		}
+
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(pointsClass), []byte(fromID), []byte("1")}).Message).To(BeEmpty())
+		event := lastEvent()
+		Expect(event.Origin).To(Equal(ownerID))
+		Expect(event.Class).To(Equal("points"))
+
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("1")}).Message).To(BeEmpty())
+		Expect(lastEvent().Class).To(BeEmpty())
+	})
+
+	It("Should query balances across classes", func() {
+		accounts := `["` + ownerID + `", "` + fromID + `", "` + ownerID + `"]`
+		classes := `["", "points", "points"]`
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOfBatch"), []byte(accounts), []byte(classes)})
+		Expect(res.Message).To(BeEmpty())
+		Expect(string(res.Payload)).To(Equal("[999999998,51,449]"))
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOfBatch"), []byte(pointsClass), []byte(accounts), []byte(classes)})
+		Expect(res.Message).To(ContainSubstring("not allowed on a token class"))
+	})
+
+	It("Should apply the freezes of the default token to every class", func() {
+		getAccount := func(args ...string) Account {
+			input := [][]byte{[]byte("GetAccount")}
+			for _, arg := range args {
+				input = append(input, []byte(arg))
+			}
+			res := mockStub.MockInvoke(txID, input)
+			Expect(res.Message).To(BeEmpty())
+			account := Account{}
+			Expect(json.Unmarshal(res.Payload, &account)).To(BeNil())
+			return account
+		}
+
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("FreezeAccount"), []byte(fromID), []byte("SANCTIONS")}).Message).To(BeEmpty())
+		Expect(getAccount(fromID).Frozen).To(BeTrue())
+		Expect(getAccount(pointsClass, fromID).Frozen).To(BeTrue())
+		//the class owner can't lift it on its own
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("UnfreezeAccount"), []byte(pointsClass), []byte(fromID), []byte("CLEARED")})
+		Expect(res.Message).To(ContainSubstring("not allowed on a token class"))
+
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(pointsClass), []byte(ownerID), []byte("1")})
+		Expect(res.Message).To(ContainSubstring("is frozen"))
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UnfreezeAccount"), []byte(fromID), []byte("CLEARED")}).Message).To(BeEmpty())
+		Expect(getAccount(pointsClass, fromID).Frozen).To(BeFalse())
+	})
+
+	It("Should migrate the accounts of every class & redirect their transfers", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(newFromID)}).Message).To(BeEmpty())
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateAccount"), []byte(fromID), []byte(newFromID)}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(pointsClass), []byte(fromID), []byte("1")}).Message).To(BeEmpty())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOf"), []byte(pointsClass), []byte(newFromID)})
+		Expect(string(res.Payload)).To(Equal("52"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOf"), []byte(pointsClass), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("is not registered"))
+	})
+
+	It("Should create classes through multi-signature proposals once enabled", func() {
+		policy := `{"signers": ["` + ownerID + `"], "threshold": 1}`
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("SetMultiSigPolicy"), []byte(policy)}).Message).To(BeEmpty())
+
+		badges := `{"name": "Badges", "symbol": "BG", "decimals": "0"}`
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("CreateClass"), []byte("badges"), []byte(badges)})
+		Expect(res.Message).To(ContainSubstring("requires an approved multi-signature proposal"))
+		Expect(mockStub.MockInvoke(proposalTxID, [][]byte{[]byte("Propose"), []byte("CreateClass"), []byte("badges"), []byte(badges)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Execute"), []byte(proposalTxID)}).Message).To(BeEmpty())
+
+		//the class follows the multi-signature policy of the default token
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("Mint"), []byte("class:badges"), []byte(ownerID), []byte("10")})
+		Expect(res.Message).To(ContainSubstring("requires an approved multi-signature proposal"))
+	})
+})
@@ -0,0 +1,155 @@
+package main_test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	. "erc20"
+	. "erc20/helpers"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Confidential balances", func() {
+	const (
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubConfidential", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+	bilateral := "confidential-" + fromOrg + "-" + toOrg
+
+	invoker := &TxInvoker{Prefix: "test-confidential"}
+	invoke := func(stub *shim.MockStub, transient string, args ...string) (string, string) {
+		if transient == "" {
+			return invoker.Invoke(stub, nil, args...)
+		}
+		salt := make([]byte, MinSaltEntropy)
+		_, err := rand.Read(salt)
+		Expect(err).To(BeNil())
+		return invoker.Invoke(stub, map[string][]byte{"confidential": []byte(transient), "confidentialSalt": salt}, args...)
+	}
+
+	It("Initializes a token with confidential balances", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit("test-confidential-init", [][]byte{[]byte(`{"name": "confidential", "symbol": "CF", "decimals": "0", "initialSupply": "1000", "confidential": "TRUE"}`)}).Message).To(BeEmpty())
+		//any spelling accepted by strconv.ParseBool enables the mode
+		res := mockStub.MockInvoke("test-confidential-init", [][]byte{[]byte("GetConfidentialBalance"), []byte(ownerID)})
+		Expect(res.Message).To(BeEmpty())
+
+		for _, accountID := range []string{fromID, toID} {
+			_, message := invoke(mockStub, "", "Activate", accountID)
+			Expect(message).To(BeEmpty())
+		}
+		_, message := invoke(mockStub, "", "Transfer", fromID, "100")
+		Expect(message).To(BeEmpty())
+	})
+
+	It("Should shield public tokens to a bilateral collection", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+
+		_, message := invoke(mockStub, "", "Shield")
+		Expect(message).To(ContainSubstring("transient input is missing"))
+		mockStub.TransientMap = map[string][]byte{"confidential": []byte(`{"amount": "60"}`)}
+		res := mockStub.MockInvoke("test-confidential-unsalted", [][]byte{[]byte("Shield")})
+		Expect(res.Message).To(ContainSubstring("random bytes in the confidentialSalt transient entry"))
+		for _, amount := range []string{"1.5", "1e3", "NaN", "0", "-1"} {
+			_, message = invoke(mockStub, `{"amount": "`+amount+`", "counterparty": "`+toOrg+`"}`, "Shield")
+			Expect(message).To(ContainSubstring("expected an integer > 0"))
+		}
+		_, message = invoke(mockStub, `{"amount": "101", "counterparty": "`+toOrg+`"}`, "Shield")
+		Expect(message).To(ContainSubstring("shield amount should be less than balance"))
+
+		_, message = invoke(mockStub, `{"amount": "60", "counterparty": "`+toOrg+`"}`, "Shield")
+		Expect(message).To(BeEmpty())
+		Expect(invoke(mockStub, "", "GetBalanceOf", fromID)).To(Equal("40"))
+		Expect(invoke(mockStub, "", "GetConfidentialBalance", fromID, toOrg)).To(Equal("60"))
+		Expect(invoke(mockStub, "", "GetConfidentialBalance", fromID)).To(Equal("0"))
+		Expect(mockStub.PvtState).To(HaveKey(bilateral))
+
+		//the value hashed on the ledger is salted
+		key, err := mockStub.CreateCompositeKey(BalanceObjectType, []string{fromID})
+		Expect(err).To(BeNil())
+		privateBalance := PrivateBalance{}
+		Expect(json.Unmarshal(mockStub.PvtState[bilateral][key], &privateBalance)).To(BeNil())
+		Expect(privateBalance.Balance.String()).To(Equal("60"))
+		Expect(privateBalance.Salt).To(HaveLen(64))
+	})
+
+	It("Should transfer confidential balances with the transient inputs only", func() {
+		_, message := invoke(mockStub, `{"receiver": "fake-account", "amount": "25"}`, "ConfidentialTransfer")
+		Expect(message).To(ContainSubstring("is not registered"))
+		_, message = invoke(mockStub, `{"receiver": "`+toID+`", "amount": "61"}`, "ConfidentialTransfer")
+		Expect(message).To(ContainSubstring("transfer amount should be less than confidential balance"))
+
+		_, message = invoke(mockStub, `{"receiver": "`+toID+`", "amount": "25"}`, "ConfidentialTransfer")
+		Expect(message).To(BeEmpty())
+		Expect(invoke(mockStub, "", "GetConfidentialBalance", fromID, toOrg)).To(Equal("35"))
+		Expect(invoke(mockStub, "", "GetConfidentialBalance", toID, fromOrg)).To(Equal("25"))
+		//the public balances don't change
+		Expect(invoke(mockStub, "", "GetBalanceOf", fromID)).To(Equal("40"))
+		Expect(invoke(mockStub, "", "GetBalanceOf", toID)).To(Equal("0"))
+	})
+
+	It("Should unshield confidential tokens", func() {
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+
+		_, message := invoke(mockStub, `{"amount": "26", "counterparty": "`+fromOrg+`"}`, "Unshield")
+		Expect(message).To(ContainSubstring("unshield amount should be less than confidential balance"))
+		_, message = invoke(mockStub, `{"amount": "25", "counterparty": "`+fromOrg+`"}`, "Unshield")
+		Expect(message).To(BeEmpty())
+		Expect(invoke(mockStub, "", "GetBalanceOf", toID)).To(Equal("25"))
+		Expect(invoke(mockStub, "", "GetConfidentialBalance", toID, fromOrg)).To(Equal("0"))
+		Expect(invoke(mockStub, "", "GetTotalSupply")).To(Equal("1000"))
+	})
+
+	It("Should not close or migrate an account holding a confidential balance", func() {
+		newFromID := fromOrg + ",Org1-child2," + fromSubject
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		_, message := invoke(mockStub, "", "Activate", newFromID)
+		Expect(message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		_, message = invoke(mockStub, "", "Deactivate", ownerID)
+		Expect(message).To(ContainSubstring("holds a confidential balance in " + bilateral))
+		_, message = invoke(mockStub, "", "MigrateAccount", fromID, newFromID)
+		Expect(message).To(ContainSubstring("holds a confidential balance in " + bilateral))
+
+		//the balance left by the unshield is 0
+		_, err = SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		_, message = invoke(mockStub, "", "Deactivate", ownerID)
+		Expect(message).To(BeEmpty())
+	})
+
+	It("Should reject confidential transactions when the mode is not enabled", func() {
+		publicStub := shim.NewMockStub("mockStubPublic", sampleToken)
+		_, err := SetCurrentCaller(publicStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(publicStub.MockInit("test-confidential-init", [][]byte{[]byte(`{"name": "public", "symbol": "PB", "decimals": "0", "initialSupply": "1000"}`)}).Message).To(BeEmpty())
+
+		_, message := invoke(publicStub, `{"amount": "10"}`, "Shield")
+		Expect(message).To(ContainSubstring("confidential balances are not enabled"))
+		Expect(invoke(publicStub, "", "GetBalanceOf", ownerID)).To(Equal("1000"))
+	})
+})
@@ -0,0 +1,150 @@
+package main_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	. "erc20"
+	. "erc20/helpers"
+	. "erc20/testutils"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encrypted memos", func() {
+	const (
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg = `clientOrg2MSP`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubEncryptedMemo", sampleToken)
+
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+
+	//the certs in /testutils come without their private keys, the recipient gets a self-signed one
+	recipientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "memo-recipient"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &recipientKey.PublicKey, recipientKey)
+	if err != nil {
+		panic(err)
+	}
+	recipientCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	recipientID := toOrg + ",memo-recipient,memo-recipient"
+
+	entropy := strings.Repeat("e", MinMemoEntropy)
+
+	invoker := &TxInvoker{Prefix: "test-encrypted-memo"}
+	invoke := func(transient map[string]string, args ...string) (string, string) {
+		entries := map[string][]byte{}
+		for key, value := range transient {
+			entries[key] = []byte(value)
+		}
+		return invoker.Invoke(mockStub, entries, args...)
+	}
+
+	It("Initializes the token & registers the recipient's memo key", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit("test-encrypted-memo-init", [][]byte{[]byte(`{"name": "memo", "symbol": "MM", "decimals": "0", "initialSupply": "1000"}`)}).Message).To(BeEmpty())
+		for _, accountID := range []string{fromID, recipientID} {
+			_, message := invoke(nil, "Activate", accountID)
+			Expect(message).To(BeEmpty())
+		}
+
+		_, message := invoke(nil, "GetMemoKey", recipientID)
+		Expect(message).To(ContainSubstring("has not registered a memo key"))
+
+		_, err = SetCurrentCaller(mockStub, toOrg, recipientCert)
+		Expect(err).To(BeNil())
+		_, message = invoke(nil, "RegisterMemoKey")
+		Expect(message).To(BeEmpty())
+
+		publicKey, message := invoke(nil, "GetMemoKey", recipientID)
+		Expect(message).To(BeEmpty())
+		block, _ := pem.Decode([]byte(publicKey))
+		Expect(block).NotTo(BeNil())
+		expected, err := x509.MarshalPKIXPublicKey(&recipientKey.PublicKey)
+		Expect(err).To(BeNil())
+		Expect(block.Bytes).To(Equal(expected))
+	})
+
+	It("Should encrypt the transient memos for the recipient", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+
+		_, message := invoke(map[string]string{MemoTransientKey: "invoice #42", MemoEntropyTransientKey: entropy}, "Transfer", recipientID, "10")
+		Expect(message).To(BeEmpty())
+
+		memo, message := invoke(nil, "GetMemo", recipientID)
+		Expect(message).To(BeEmpty())
+		Expect(memo).To(HavePrefix(EncryptedMemoPrefix))
+		for _, value := range mockStub.State {
+			Expect(string(value)).NotTo(ContainSubstring("invoice #42"))
+		}
+
+		plaintext, err := DecryptMemo(recipientKey, memo)
+		Expect(err).To(BeNil())
+		Expect(string(plaintext)).To(Equal("invoice #42"))
+
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).To(BeNil())
+		_, err = DecryptMemo(otherKey, memo)
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("Should derive a new key for every transaction", func() {
+		first, _ := invoke(nil, "GetMemo", recipientID)
+		_, message := invoke(map[string]string{MemoTransientKey: "invoice #42", MemoEntropyTransientKey: entropy}, "Transfer", recipientID, "10")
+		Expect(message).To(BeEmpty())
+
+		second, _ := invoke(nil, "GetMemo", recipientID)
+		Expect(second).NotTo(Equal(first))
+		plaintext, err := DecryptMemo(recipientKey, second)
+		Expect(err).To(BeNil())
+		Expect(string(plaintext)).To(Equal("invoice #42"))
+	})
+
+	It("Should reject transient memos without entropy or memo key", func() {
+		_, message := invoke(map[string]string{MemoTransientKey: "invoice #43"}, "Transfer", recipientID, "10")
+		Expect(message).To(ContainSubstring("random bytes"))
+		_, message = invoke(map[string]string{MemoTransientKey: "invoice #43", MemoEntropyTransientKey: entropy}, "Transfer", fromID, "10")
+		Expect(message).To(ContainSubstring("has not registered a memo key"))
+	})
+
+	It("Should store the memos encrypted client-side as they are", func() {
+		memo, err := EncryptMemo(&recipientKey.PublicKey, []byte("invoice #44"), []byte(strings.Repeat("c", MinMemoEntropy)))
+		Expect(err).To(BeNil())
+		_, message := invoke(nil, "Transfer", recipientID, "10", memo)
+		Expect(message).To(BeEmpty())
+
+		stored, _ := invoke(nil, "GetMemo", recipientID)
+		Expect(stored).To(Equal(memo))
+		plaintext, err := DecryptMemo(recipientKey, stored)
+		Expect(err).To(BeNil())
+		Expect(string(plaintext)).To(Equal("invoice #44"))
+	})
+})
@@ -0,0 +1,117 @@
+package main_test
+
+import (
+	. "erc20"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Per-account freeze", func() {
+	const (
+		txID         = `test-freezable-id`
+		unfreezeTxID = `test-unfreeze-id`
+		proposalTxID = `test-freezable-proposal-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubFreezable", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+
+	It("Initializes the token as owner & funds `fromID`", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "freezable", "symbol": "FZ", "decimals": "0"}`)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(toID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("10")}).Message).To(BeEmpty())
+	})
+
+	It("Should require a reason code", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("FreezeAccount"), []byte(fromID), []byte(" ")})
+		Expect(res.Message).To(ContainSubstring("reason code"))
+	})
+
+	It("Should only be frozen by a compliance officer", func() {
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("FreezeAccount"), []byte(fromID), []byte("SANCTIONS")})
+		Expect(res.Message).To(ContainSubstring("role COMPLIANCE"))
+	})
+
+	When("`fromID` is frozen", func() {
+		It("Freezes `fromID`", func() {
+			_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("FreezeAccount"), []byte(fromID), []byte("SANCTIONS")}).Message).To(BeEmpty())
+
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("IsFrozen"), []byte(fromID)})
+			Expect(string(res.Payload)).To(Equal("true"))
+		})
+
+		It("Should not receive tokens", func() {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("1")})
+			Expect(res.Message).To(ContainSubstring("is frozen"))
+		})
+
+		It("Should not send, burn or approve", func() {
+			_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+			for _, args := range [][][]byte{
+				{[]byte("Transfer"), []byte(toID), []byte("1")},
+				{[]byte("Burn"), []byte("1")},
+				{[]byte("UpdateApproval"), []byte(toID), []byte("1")},
+			} {
+				Expect(mockStub.MockInvoke(txID, args).Message).To(ContainSubstring("is frozen"))
+			}
+		})
+
+		It("Should let everyone else transact", func() {
+			_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(toID), []byte("1")}).Message).To(BeEmpty())
+		})
+
+		It("Should not receive tokens minted by a multi-signature proposal", func() {
+			policy := `{"signers": ["` + ownerID + `"], "threshold": 1}`
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("SetMultiSigPolicy"), []byte(policy)}).Message).To(BeEmpty())
+			Expect(mockStub.MockInvoke(proposalTxID, [][]byte{[]byte("Propose"), []byte("Mint"), []byte(fromID), []byte("10")}).Message).To(BeEmpty())
+
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("Execute"), []byte(proposalTxID)})
+			Expect(res.Message).To(ContainSubstring("is frozen"))
+		})
+	})
+
+	It("Should transact again once unfrozen", func() {
+		Expect(mockStub.MockInvoke(unfreezeTxID, [][]byte{[]byte("UnfreezeAccount"), []byte(fromID), []byte("CLEARED")}).Message).To(BeEmpty())
+
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(toID), []byte("1")}).Message).To(BeEmpty())
+	})
+
+	It("Should log every freeze action, oldest first", func() {
+		actions, err := sampleToken.GetFreezeLog(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(actions).To(HaveLen(2))
+		Expect(actions[0].Action).To(Equal("FREEZE"))
+		Expect(actions[1].Action).To(Equal("UNFREEZE"))
+		Expect(actions[0].Timestamp).To(BeNumerically("<=", actions[1].Timestamp))
+	})
+})
@@ -0,0 +1,131 @@
+package main_test
+
+import (
+	. "erc20"
+	. "erc20/helpers"
+	"erc20/lib/erc20holders"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Holders", func() {
+	const (
+		txID = `test-holders-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubHolders", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+
+	//every transaction has its own ID, the holder count deltas are keyed by it
+	invoker := &TxInvoker{Prefix: txID}
+	invoke := func(args ...string) (string, string) {
+		return invoker.Invoke(mockStub, nil, args...)
+	}
+	//GetHolders is called with a stub implementing the pagination of the peer
+	getHolders := func(args ...string) erc20holders.HoldersPage {
+		page, err := sampleToken.GetHolders(&PaginatedStub{MockStub: mockStub}, args)
+		Expect(err).To(BeNil())
+		return *page
+	}
+	getHolderCount := func() string {
+		count, message := invoke("GetHolderCount")
+		Expect(message).To(BeEmpty())
+		return count
+	}
+
+	It("Initializes the token as owner, the only holder", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "holders", "symbol": "HD", "decimals": "0"}`)}).Message).To(BeEmpty())
+		Expect(getHolderCount()).To(Equal("1"))
+	})
+
+	It("Should not count activated accounts without tokens", func() {
+		for _, accountID := range []string{fromID, toID} {
+			_, message := invoke("Activate", accountID)
+			Expect(message).To(BeEmpty())
+		}
+		Expect(getHolderCount()).To(Equal("1"))
+	})
+
+	It("Should count the receivers of transfers", func() {
+		_, message := invoke("Transfer", fromID, "100")
+		Expect(message).To(BeEmpty())
+		_, message = invoke("Transfer", toID, "50")
+		Expect(message).To(BeEmpty())
+		Expect(getHolderCount()).To(Equal("3"))
+	})
+
+	It("Should page through the holders by account ID", func() {
+		page := getHolders("2")
+		Expect(page.Holders).To(HaveLen(2))
+		Expect(page.Holders[0].Account).To(Equal(fromID))
+		Expect(page.Holders[0].Balance.String()).To(Equal("100"))
+		Expect(page.Holders[1].Account).To(Equal(toID))
+		Expect(page.Bookmark).NotTo(BeEmpty())
+
+		page = getHolders("2", page.Bookmark)
+		Expect(page.Holders).To(HaveLen(1))
+		Expect(page.Holders[0].Account).To(Equal(ownerID))
+		Expect(page.Bookmark).To(BeEmpty())
+	})
+
+	It("Should filter the holders by minimum balance & MSP", func() {
+		page := getHolders("", "", "100")
+		Expect(page.Holders).To(HaveLen(2))
+		Expect(page.Holders[0].Account).To(Equal(fromID))
+		Expect(page.Holders[1].Account).To(Equal(ownerID))
+
+		page = getHolders("", "", "", toOrg)
+		Expect(page.Holders).To(HaveLen(1))
+		Expect(page.Holders[0].Account).To(Equal(toID))
+	})
+
+	It("Should reject invalid page sizes", func() {
+		_, message := invoke("GetHolders", "0")
+		Expect(message).To(ContainSubstring("page size"))
+	})
+
+	It("Should stop counting the accounts burning all their tokens", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		_, message := invoke("Burn", "100")
+		Expect(message).To(BeEmpty())
+		Expect(getHolderCount()).To(Equal("2"))
+
+		page := getHolders()
+		Expect(page.Holders).To(HaveLen(2))
+		Expect(page.Holders[0].Account).To(Equal(toID))
+	})
+
+	It("Should compact the holder count without changing it", func() {
+		count, message := invoke("CompactHolderCount")
+		Expect(message).To(BeEmpty())
+		Expect(count).To(Equal("2"))
+		Expect(getHolderCount()).To(Equal("2"))
+
+		prefix, err := mockStub.CreateCompositeKey(HolderCountDeltaObjectType, []string{})
+		Expect(err).To(BeNil())
+		for key := range mockStub.State {
+			Expect(key).NotTo(HavePrefix(prefix))
+		}
+	})
+})
@@ -0,0 +1,180 @@
+package main_test
+
+import (
+	"encoding/json"
+	. "erc20"
+	. "erc20/helpers"
+	"erc20/lib/erc20holders"
+	. "erc20/testutils"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hot accounts", func() {
+	const (
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubHotAccounts", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+
+	//every transaction has its own ID, the deltas are keyed by it
+	invoker := &TxInvoker{Prefix: "test-hot-account"}
+	invoke := func(args ...string) (string, string) {
+		return invoker.Invoke(mockStub, nil, args...)
+	}
+	asOwner := func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+	}
+	asFrom := func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+	}
+	deltaCount := func(accountID string) int {
+		prefix, err := mockStub.CreateCompositeKey(DeltaObjectType, []string{accountID})
+		Expect(err).To(BeNil())
+		count := 0
+		for key := range mockStub.State {
+			if strings.HasPrefix(key, prefix) {
+				count++
+			}
+		}
+		return count
+	}
+	getAccount := func(accountID string) Account {
+		payload, message := invoke("GetAccount", accountID)
+		Expect(message).To(BeEmpty())
+		account := Account{}
+		Expect(json.Unmarshal([]byte(payload), &account)).To(BeNil())
+		return account
+	}
+
+	It("Initializes the token & makes the owner's account hot", func() {
+		asOwner()
+		Expect(mockStub.MockInit("test-hot-account-init", [][]byte{[]byte(`{"name": "hot", "symbol": "HOT", "decimals": "0", "initialSupply": "1000"}`)}).Message).To(BeEmpty())
+		_, message := invoke("Activate", fromID)
+		Expect(message).To(BeEmpty())
+
+		asFrom()
+		_, message = invoke("SetHotAccount", ownerID, "true")
+		Expect(message).To(ContainSubstring("ADMIN"))
+
+		asOwner()
+		_, message = invoke("SetHotAccount", ownerID, "true")
+		Expect(message).To(BeEmpty())
+		Expect(invoke("IsHotAccount", ownerID)).To(Equal("true"))
+		Expect(invoke("IsHotAccount", fromID)).To(Equal("false"))
+		_, message = invoke("SetHotAccount", ownerID, "true")
+		Expect(message).To(ContainSubstring("already"))
+	})
+
+	It("Should debit hot accounts from their record", func() {
+		_, message := invoke("Transfer", fromID, "100")
+		Expect(message).To(BeEmpty())
+		Expect(invoke("GetBalanceOf", ownerID)).To(Equal("900"))
+		Expect(invoke("GetHolderCount")).To(Equal("2"))
+	})
+
+	It("Should credit hot accounts with deltas", func() {
+		asFrom()
+		for i := 0; i < 2; i++ {
+			_, message := invoke("Transfer", ownerID, "10")
+			Expect(message).To(BeEmpty())
+		}
+
+		Expect(deltaCount(ownerID)).To(Equal(2))
+		Expect(getAccount(ownerID).Balance.String()).To(Equal("900"))
+		Expect(invoke("GetBalanceOf", ownerID)).To(Equal("920"))
+		Expect(invoke("GetBalanceOf", fromID)).To(Equal("80"))
+
+		page, err := sampleToken.GetHolders(&PaginatedStub{MockStub: mockStub}, []string{})
+		Expect(err).To(BeNil())
+		Expect(page.Holders).To(ContainElement(erc20holders.Holder{Account: ownerID, Balance: big.NewInt(920)}))
+	})
+
+	It("Should not overdraw hot accounts", func() {
+		asOwner()
+		_, message := invoke("Transfer", fromID, "921")
+		Expect(message).To(ContainSubstring("transfer amount should be less than balance of sender"))
+	})
+
+	It("Should compact the deltas of hot accounts on debits", func() {
+		_, message := invoke("Transfer", fromID, "920")
+		Expect(message).To(BeEmpty())
+
+		Expect(deltaCount(ownerID)).To(BeZero())
+		owner := getAccount(ownerID)
+		Expect(owner.Balance.String()).To(Equal("0"))
+		//the mint, 2 debits & 2 credits
+		Expect(owner.Nonce).To(Equal(uint64(5)))
+		//hot accounts count as holders while they are hot
+		Expect(invoke("GetHolderCount")).To(Equal("2"))
+	})
+
+	It("Should compact the deltas on demand", func() {
+		asFrom()
+		_, message := invoke("Transfer", ownerID, "30")
+		Expect(message).To(BeEmpty())
+		Expect(deltaCount(ownerID)).To(Equal(1))
+
+		Expect(invoke("CompactBalance", ownerID)).To(Equal("30"))
+		Expect(deltaCount(ownerID)).To(BeZero())
+		Expect(getAccount(ownerID).Balance.String()).To(Equal("30"))
+		Expect(invoke("GetBalanceOf", ownerID)).To(Equal("30"))
+	})
+
+	It("Should not close hot accounts", func() {
+		asOwner()
+		_, message := invoke("Deactivate", fromID)
+		Expect(message).To(ContainSubstring("is a hot account"))
+		Expect(invoke("GetBalanceOf", ownerID)).To(Equal("30"))
+	})
+
+	It("Should compact the deltas when an account stops being hot", func() {
+		_, message := invoke("Transfer", fromID, "30")
+		Expect(message).To(BeEmpty())
+		asFrom()
+		_, message = invoke("Transfer", ownerID, "5")
+		Expect(message).To(BeEmpty())
+
+		asOwner()
+		_, message = invoke("SetHotAccount", ownerID, "false")
+		Expect(message).To(BeEmpty())
+		Expect(deltaCount(ownerID)).To(BeZero())
+		Expect(getAccount(ownerID).Balance.String()).To(Equal("5"))
+
+		//the credits are read-modify-writes again
+		asFrom()
+		_, message = invoke("Transfer", ownerID, "5")
+		Expect(message).To(BeEmpty())
+		Expect(deltaCount(ownerID)).To(BeZero())
+		Expect(getAccount(ownerID).Balance.String()).To(Equal("10"))
+	})
+
+	It("Should stop counting empty accounts as holders when they stop being hot", func() {
+		asOwner()
+		_, message := invoke("SetHotAccount", ownerID, "true")
+		Expect(message).To(BeEmpty())
+		_, message = invoke("Transfer", fromID, "10")
+		Expect(message).To(BeEmpty())
+		Expect(invoke("GetHolderCount")).To(Equal("2"))
+
+		_, message = invoke("SetHotAccount", ownerID, "false")
+		Expect(message).To(BeEmpty())
+		Expect(invoke("GetHolderCount")).To(Equal("1"))
+	})
+})
@@ -0,0 +1,154 @@
+package main_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	. "erc20"
+	. "erc20/helpers"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Idemix callers", func() {
+	const (
+		txID = `test-idemix-id`
+
+		//attributes matches the credential in /testutils
+		idemixOrg = `idemixMSPID2`
+		idemixOU  = `org1.department1`
+
+		ownerIssuer  = `Org1`
+		ownerOrg     = `sampleOrgMSP`
+		ownerSubject = `Org1-child1`
+	)
+
+	ownerID := ownerOrg + "," + ownerIssuer + "," + ownerSubject
+
+	pseudonym := func(scheme string, value string) string {
+		hash := sha256.Sum256([]byte(value))
+		return idemixOrg + ",idemix:" + scheme + "," + hex.EncodeToString(hash[:])
+	}
+
+	//the credential in /testutils only discloses the `ou` & `role` read by cid, `disclose` reads the per-user attributes of an MSP disclosing them
+	disclose := func(attrs map[string]string) {
+		GetIdemixAttribute = func(stub cid.ChaincodeStubInterface, attrName string) (string, bool, error) {
+			if value, ok := attrs[attrName]; ok {
+				return value, true, nil
+			}
+			return cid.GetAttributeValue(stub, attrName)
+		}
+	}
+	AfterEach(func() {
+		GetIdemixAttribute = cid.GetAttributeValue
+	})
+
+	When("The default `enrollmentID` scheme is used", func() {
+		sampleToken := NewSampleToken()
+		var mockStub *shim.MockStub = shim.NewMockStub("mockStubIdemix", sampleToken)
+
+		It("Initializes the token as owner", func() {
+			_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "idemix", "symbol": "IDX", "decimals": "0"}`)}).Message).To(BeEmpty())
+		})
+
+		It("Should reject the callers not disclosing their enrollment ID", func() {
+			_, err := SetIdemixMockStubWithAttrs(mockStub, idemixOrg)
+			Expect(err).To(BeNil())
+
+			_, err = GetCallerID(mockStub)
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring("Idemix attribute enrollmentID is not disclosed"))
+
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(ownerID), []byte("4")})
+			Expect(res.Message).To(ContainSubstring("is not disclosed"))
+		})
+
+		It("Derives a pseudonymous ID from the enrollment ID with every ID scheme", func() {
+			disclose(map[string]string{IdemixIDByEnrollmentID: "member1"})
+			_, err := SetIdemixMockStubWithAttrs(mockStub, idemixOrg)
+			Expect(err).To(BeNil())
+
+			callerID, err := GetCallerID(mockStub)
+			Expect(err).To(BeNil())
+			Expect(callerID).To(Equal(pseudonym(IdemixIDByEnrollmentID, "member1")))
+			Expect(callerID).NotTo(ContainSubstring("member1"))
+
+			for _, scheme := range []string{LegacyIDScheme, EscapedIDScheme, PublicKeyHashIDScheme, AddressIDScheme} {
+				resolver, err := GetIdentityResolver(scheme)
+				Expect(err).To(BeNil())
+				resolvedID, err := resolver.ResolveID(mockStub)
+				Expect(err).To(BeNil())
+				Expect(resolvedID).To(Equal(callerID))
+			}
+		})
+
+		It("Should send & receive tokens as an Idemix caller", func() {
+			memberID := pseudonym(IdemixIDByEnrollmentID, "member1")
+			_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(memberID)}).Message).To(BeEmpty())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(memberID), []byte("10")}).Message).To(BeEmpty())
+
+			disclose(map[string]string{IdemixIDByEnrollmentID: "member1"})
+			_, err = SetIdemixMockStubWithAttrs(mockStub, idemixOrg)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(ownerID), []byte("4")}).Message).To(BeEmpty())
+
+			balance, err := sampleToken.GetBalanceOf(mockStub, []string{memberID})
+			Expect(err).To(BeNil())
+			Expect(balance.String()).To(Equal("6"))
+		})
+
+		It("Should not let another member of the OU spend the balance", func() {
+			disclose(map[string]string{IdemixIDByEnrollmentID: "member2"})
+			_, err := SetIdemixMockStubWithAttrs(mockStub, idemixOrg)
+			Expect(err).To(BeNil())
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(ownerID), []byte("4")})
+			Expect(res.Message).To(ContainSubstring(pseudonym(IdemixIDByEnrollmentID, "member2")))
+
+			balance, err := sampleToken.GetBalanceOf(mockStub, []string{pseudonym(IdemixIDByEnrollmentID, "member1")})
+			Expect(err).To(BeNil())
+			Expect(balance.String()).To(Equal("6"))
+		})
+	})
+
+	When("The `ou` scheme is used", func() {
+		sampleToken := NewSampleToken()
+		var mockStub *shim.MockStub = shim.NewMockStub("mockStubIdemixOU", sampleToken)
+
+		It("Initializes the token with the `ou` scheme", func() {
+			_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "idemix", "symbol": "IDX", "decimals": "0", "idemixIDScheme": "ou"}`)}).Message).To(BeEmpty())
+		})
+
+		It("Gives every member of the OU the same ID", func() {
+			for _, enrollmentID := range []string{"member1", "member2"} {
+				disclose(map[string]string{IdemixIDByEnrollmentID: enrollmentID})
+				_, err := SetIdemixMockStubWithAttrs(mockStub, idemixOrg)
+				Expect(err).To(BeNil())
+
+				callerID, err := GetCallerID(mockStub)
+				Expect(err).To(BeNil())
+				Expect(callerID).To(Equal(pseudonym(IdemixIDByOU, idemixOU)))
+			}
+		})
+	})
+
+	When("The scheme is invalid", func() {
+		sampleToken := NewSampleToken()
+		var mockStub *shim.MockStub = shim.NewMockStub("mockStubIdemixInvalid", sampleToken)
+
+		It("Should not initialize the token", func() {
+			_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			res := mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "idemix", "symbol": "IDX", "decimals": "0", "idemixIDScheme": "nym"}`)})
+			Expect(res.Message).To(ContainSubstring("unknown Idemix ID scheme"))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package main_test
+
+import (
+	. "erc20"
+	. "erc20/helpers"
+	"erc20/lib/erc20roles"
+	. "erc20/testutils"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Account ID schemes", func() {
+	const (
+		txID = `test-idscheme-id`
+
+		ownerOrg = `sampleOrgMSP`
+		fromOrg  = `clientOrg1MSP`
+	)
+
+	initWith := func(scheme string) (*shim.MockStub, *SampleToken) {
+		sampleToken := NewSampleToken()
+		mockStub := shim.NewMockStub("mockStubIDScheme-"+scheme, sampleToken)
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		config := fmt.Sprintf(`{"name": "scheme", "symbol": "SC", "decimals": "0", "idScheme": "%s"}`, scheme)
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(config)}).Message).To(BeEmpty())
+		return mockStub, sampleToken
+	}
+
+	It("Should derive an Ethereum-style address with the `address` scheme", func() {
+		mockStub, sampleToken := initWith(AddressIDScheme)
+
+		ownerID, err := ResolveCallerID(mockStub)
+		Expect(err).To(BeNil())
+		Expect(ownerID).To(MatchRegexp(`^sampleOrgMSP,0x[0-9a-f]{40}$`))
+
+		owner, err := sampleToken.GetOwner(mockStub)
+		Expect(err).To(BeNil())
+		Expect(owner).To(Equal(ownerID))
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("HasRole"), []byte(erc20roles.MINTER), []byte(ownerID)})
+		Expect(string(res.Payload)).To(Equal("true"))
+	})
+
+	It("Should derive a public key hash with the `pubkeyHash` scheme", func() {
+		mockStub, _ := initWith(PublicKeyHashIDScheme)
+
+		ownerID, err := ResolveCallerID(mockStub)
+		Expect(err).To(BeNil())
+		Expect(ownerID).To(MatchRegexp(`^sampleOrgMSP,[0-9a-f]{64}$`))
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		fromID, err := ResolveCallerID(mockStub)
+		Expect(err).To(BeNil())
+		Expect(fromID).NotTo(Equal(ownerID))
+	})
+
+	It("Should keep the legacy ID with the `escaped` scheme when no escaping is needed", func() {
+		mockStub, _ := initWith(EscapedIDScheme)
+
+		ownerID, err := ResolveCallerID(mockStub)
+		Expect(err).To(BeNil())
+		Expect(ownerID).To(Equal("sampleOrgMSP,Org1,Org1-child1"))
+	})
+
+	It("Should reject unknown schemes", func() {
+		sampleToken := NewSampleToken()
+		mockStub := shim.NewMockStub("mockStubIDSchemeUnknown", sampleToken)
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "scheme", "symbol": "SC", "decimals": "0", "idScheme": "email"}`)})
+		Expect(res.Message).To(ContainSubstring("unknown ID scheme"))
+	})
+})
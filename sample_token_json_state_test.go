@@ -0,0 +1,233 @@
+package main_test
+
+import (
+	"encoding/json"
+	. "erc20"
+	. "erc20/helpers"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSON state documents", func() {
+	const (
+		txID = `test-json-state-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubJSONState", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+
+	getDocument := func(document interface{}, objectType string, attributes ...string) {
+		key, err := mockStub.CreateCompositeKey(objectType, attributes)
+		Expect(err).To(BeNil())
+		Expect(json.Unmarshal(mockStub.State[key], document)).To(BeNil())
+	}
+
+	It("Initializes the token in the `json` state mode", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "documents", "symbol": "DC", "decimals": "0", "initialSupply": "1000", "stateMode": "json"}`)})
+		Expect(res.Message).To(BeEmpty())
+
+		config := ConfigDocument{}
+		getDocument(&config, ConfigObjectType, "symbol")
+		Expect(config).To(Equal(ConfigDocument{DocType: ConfigDocType, Name: "symbol", Value: "DC"}))
+
+		account := Account{}
+		getDocument(&account, BalanceObjectType, ownerID)
+		Expect(account.DocType).To(Equal(AccountDocType))
+		Expect(account.MSP).To(Equal(ownerOrg))
+		Expect(account.Balance.String()).To(Equal("1000"))
+	})
+
+	It("Should store balances as JSON numbers", func() {
+		key, err := mockStub.CreateCompositeKey(BalanceObjectType, []string{ownerID})
+		Expect(err).To(BeNil())
+		Expect(string(mockStub.State[key])).To(ContainSubstring(`"balance":1000,`))
+		//the default token has an empty class, queried with the indexes on the class
+		Expect(string(mockStub.State[key])).To(ContainSubstring(`"class":""`))
+	})
+
+	It("Should transfer, approve & attach memos with documents", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(toID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("100"), []byte("invoice 42")}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(toID), []byte("20")}).Message).To(BeEmpty())
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOf"), []byte(fromID)})
+		Expect(string(res.Payload)).To(Equal("100"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetAllowance"), []byte(ownerID), []byte(toID)})
+		Expect(string(res.Payload)).To(Equal("20"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetMemo"), []byte(fromID)})
+		Expect(string(res.Payload)).To(Equal("invoice 42"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetTotalSupply")})
+		Expect(string(res.Payload)).To(Equal("1000"))
+
+		allowance := AllowanceDocument{}
+		getDocument(&allowance, AllowanceObjectType, ownerID, toID)
+		Expect(allowance.DocType).To(Equal(AllowanceDocType))
+		Expect(allowance.Spender).To(Equal(toID))
+		Expect(allowance.Amount.String()).To(Equal("20"))
+
+		memo := MemoDocument{}
+		getDocument(&memo, MemoObjectType, fromID)
+		Expect(memo).To(Equal(MemoDocument{DocType: MemoDocType, Account: fromID, Memo: "invoice 42"}))
+	})
+
+	It("Should flag the documents of frozen accounts", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("FreezeAccount"), []byte(fromID), []byte("SANCTIONS")}).Message).To(BeEmpty())
+		account := Account{}
+		getDocument(&account, BalanceObjectType, fromID)
+		Expect(account.Frozen).To(BeTrue())
+		Expect(account.Balance.String()).To(Equal("100"))
+
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("UnfreezeAccount"), []byte(fromID), []byte("CLEARED")}).Message).To(BeEmpty())
+		getDocument(&account, BalanceObjectType, fromID)
+		Expect(account.Frozen).To(BeFalse())
+	})
+
+	It("Should record the class of the documents of a class", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("CreateClass"), []byte("points"), []byte(`{"name": "points", "symbol": "PT", "decimals": "0", "initialSupply": "5"}`)})
+		Expect(res.Message).To(BeEmpty())
+
+		key, err := mockStub.CreateCompositeKey("class", []string{"points", BalanceObjectType, ownerID})
+		Expect(err).To(BeNil())
+		account := Account{}
+		Expect(json.Unmarshal(mockStub.State[key], &account)).To(BeNil())
+		Expect(account.Class).To(Equal("points"))
+		Expect(account.Balance.String()).To(Equal("5"))
+	})
+
+	It("Should only accept selectors on the account fields", func() {
+		for _, selector := range []string{
+			`{"docType": "allowance"}`,
+			`{"balance": {"$regex": "^1"}}`,
+			`{"balance": "100"}`,
+			`{"frozen": {"$in": [true, 1]}}`,
+			`{"$or": {"frozen": true}}`,
+			`{"$gte": 100}`,
+			`not a selector`,
+		} {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("QueryAccounts"), []byte(selector)})
+			Expect(res.Message).To(ContainSubstring("invalid selector"), selector)
+		}
+	})
+
+	It("Should run valid selectors on the state database", func() {
+		//the mock stub has no query engine
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("QueryAccounts"), []byte(`{"$or": [{"balance": {"$gte": 100}}, {"frozen": true, "msp": {"$in": ["` + toOrg + `"]}}]}`)})
+		Expect(res.Message).To(ContainSubstring("rich queries are not supported"))
+	})
+
+	It("Should keep account records in the `raw` state mode", func() {
+		rawStub := shim.NewMockStub("mockStubRawState", sampleToken)
+		_, err := SetCurrentCaller(rawStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(rawStub.MockInit(txID, [][]byte{[]byte(`{"name": "raw", "symbol": "RW", "decimals": "0"}`)}).Message).To(BeEmpty())
+		res := rawStub.MockInvoke(txID, [][]byte{[]byte("QueryAccounts"), []byte(`{"frozen": true}`)})
+		Expect(res.Message).To(ContainSubstring("rich queries are not supported"))
+
+		res = rawStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOf"), []byte(ownerID)})
+		Expect(string(res.Payload)).To(Equal("1000000000"))
+
+		symbolKey, err := rawStub.CreateCompositeKey(ConfigObjectType, []string{"symbol"})
+		Expect(err).To(BeNil())
+		Expect(string(rawStub.State[symbolKey])).To(Equal("RW"))
+
+		//memos are not decoded as documents outside of the `json` state mode
+		Expect(rawStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+		Expect(rawStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("1"), []byte(`{"docType":"account"}`)}).Message).To(BeEmpty())
+		res = rawStub.MockInvoke(txID, [][]byte{[]byte("GetMemo"), []byte(fromID)})
+		Expect(string(res.Payload)).To(Equal(`{"docType":"account"}`))
+	})
+
+	It("Should convert the state when an upgrade changes the state mode", func() {
+		upgradeStub := shim.NewMockStub("mockStubUpgradeState", sampleToken)
+		_, err := SetCurrentCaller(upgradeStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(upgradeStub.MockInit(txID, [][]byte{[]byte(`{"name": "upgraded", "symbol": "UP", "decimals": "0"}`)}).Message).To(BeEmpty())
+		Expect(upgradeStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(toID), []byte("20")}).Message).To(BeEmpty())
+		Expect(upgradeStub.MockInvoke(txID, [][]byte{[]byte("UpdateApproval"), []byte(fromID), []byte("5")}).Message).To(BeEmpty())
+		forgedMemo := `{"docType":"memo","memo":"forged"}`
+		Expect(upgradeStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+		Expect(upgradeStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("1"), []byte(forgedMemo)}).Message).To(BeEmpty())
+		Expect(upgradeStub.MockInvoke(txID, [][]byte{[]byte("CreateClass"), []byte("points"), []byte(`{"name": "points", "symbol": "PT", "decimals": "0"}`)}).Message).To(BeEmpty())
+
+		res := upgradeStub.MockInit(txID, [][]byte{[]byte(`{"stateMode": "xml"}`)})
+		Expect(res.Message).To(ContainSubstring("unknown state mode"))
+		res = upgradeStub.MockInit(txID, [][]byte{[]byte(`{"confidential": "maybe"}`)})
+		Expect(res.Message).To(ContainSubstring("invalid confidential mode"))
+
+		//the conversion is run by MigrateSchema, `batchSize` keys per transaction, the token can't be used until it's done
+		convert := func(mode string) int {
+			calls := 0
+			for string(upgradeStub.MockInvoke(txID, [][]byte{[]byte("GetStateMode")}).Payload) != mode {
+				Expect(calls).To(BeNumerically("<", 10))
+				Expect(upgradeStub.MockInvoke(txID, [][]byte{[]byte("MigrateSchema"), []byte(`{"migrations": {"convertStateMode": {"batchSize": 1}}}`)}).Message).To(BeEmpty())
+				calls++
+			}
+			return calls
+		}
+
+		Expect(upgradeStub.MockInit(txID, [][]byte{[]byte(`{"stateMode": "json", "confidential": "TRUE"}`)}).Message).To(BeEmpty())
+		res = upgradeStub.MockInvoke(txID, [][]byte{[]byte("GetAllowance"), []byte(ownerID), []byte(toID)})
+		Expect(res.Message).To(ContainSubstring("is being converted to the json state mode"))
+		res = upgradeStub.MockInit(txID, [][]byte{[]byte(`{"stateMode": "raw"}`)})
+		Expect(res.Message).To(ContainSubstring("finishes the conversion with MigrateSchema first"))
+		//the 2 allowances of the default token take 2 batches, its memo 1, then the class's allowances & memos 1 each and the config 1
+		Expect(convert(JSONStateMode)).To(Equal(6))
+		bookmark, err := GetConfigState(upgradeStub, "migrationBookmark")
+		Expect(err).To(BeNil())
+		Expect(bookmark).To(BeEmpty())
+
+		config := ConfigDocument{}
+		symbolKey, err := upgradeStub.CreateCompositeKey(ConfigObjectType, []string{"symbol"})
+		Expect(err).To(BeNil())
+		Expect(json.Unmarshal(upgradeStub.State[symbolKey], &config)).To(BeNil())
+		Expect(config).To(Equal(ConfigDocument{DocType: ConfigDocType, Name: "symbol", Value: "UP"}))
+		classSymbolKey, err := upgradeStub.CreateCompositeKey("class", []string{"points", ConfigObjectType, "symbol"})
+		Expect(err).To(BeNil())
+		Expect(json.Unmarshal(upgradeStub.State[classSymbolKey], &config)).To(BeNil())
+		Expect(config).To(Equal(ConfigDocument{DocType: ConfigDocType, Class: "points", Name: "symbol", Value: "PT"}))
+
+		res = upgradeStub.MockInvoke(txID, [][]byte{[]byte("GetAllowance"), []byte(ownerID), []byte(toID)})
+		Expect(string(res.Payload)).To(Equal("20"))
+		res = upgradeStub.MockInvoke(txID, [][]byte{[]byte("GetMemo"), []byte(fromID)})
+		Expect(string(res.Payload)).To(Equal(forgedMemo))
+		enabled, err := IsConfidentialMode(upgradeStub)
+		Expect(err).To(BeNil())
+		Expect(enabled).To(BeTrue())
+
+		Expect(upgradeStub.MockInit(txID, [][]byte{[]byte(`{"stateMode": "raw"}`)}).Message).To(BeEmpty())
+		Expect(convert(RawStateMode)).To(Equal(6))
+		Expect(string(upgradeStub.State[symbolKey])).To(Equal("UP"))
+		Expect(string(upgradeStub.State[classSymbolKey])).To(Equal("PT"))
+		res = upgradeStub.MockInvoke(txID, [][]byte{[]byte("GetMemo"), []byte(fromID)})
+		Expect(string(res.Payload)).To(Equal(forgedMemo))
+	})
+
+	It("Should reject unknown state modes", func() {
+		otherStub := shim.NewMockStub("mockStubUnknownState", sampleToken)
+		_, err := SetCurrentCaller(otherStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		res := otherStub.MockInit(txID, [][]byte{[]byte(`{"name": "other", "symbol": "OT", "decimals": "0", "stateMode": "xml"}`)})
+		Expect(res.Message).To(ContainSubstring("unknown state mode"))
+	})
+})
@@ -0,0 +1,144 @@
+package main_test
+
+import (
+	. "erc20"
+	"erc20/lib/erc20roles"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Namespaced keys", func() {
+	const (
+		txID       = `test-key-namespace-id`
+		legacyTxID = `test-legacy-ledger-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubKeyNamespace", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+
+	It("Writes a ledger with bare keys", func() {
+		mockStub.MockTransactionStart(legacyTxID)
+		defer mockStub.MockTransactionEnd(legacyTxID)
+
+		legacyState := map[string]string{
+			"owner":       ownerID,
+			"name":        "legacy",
+			"symbol":      "LG",
+			"decimals":    "0",
+			"totalSupply": "1000",
+			ownerID:       "900",
+			fromID:        "100",
+			//an allowance written before allowances were indexed
+			ownerID + "-" + fromID: "50",
+			//and one with the former index
+			fromID + "-" + toID: "5",
+		}
+		for key, value := range legacyState {
+			Expect(mockStub.PutState(key, []byte(value))).To(BeNil())
+		}
+		indexKey, err := mockStub.CreateCompositeKey("Allowance", []string{fromID, toID})
+		Expect(err).To(BeNil())
+		Expect(mockStub.PutState(indexKey, []byte{0x00})).To(BeNil())
+	})
+
+	It("Should only be upgraded by the owner", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "legacy", "symbol": "LG", "decimals": "0"}`)})
+		Expect(res.Message).To(ContainSubstring("only accessible to token owner"))
+	})
+
+	It("Should not guess whether an unindexed key is a balance or an allowance", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "legacy", "symbol": "LG", "decimals": "0"}`)}).Message).To(BeEmpty())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateSchema")})
+		Expect(res.Message).To(ContainSubstring("bare key " + ownerID + "-" + fromID + " may be a balance or an allowance"))
+	})
+
+	It("Converts the bare keys on upgrade", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		upgradeArgs := `{"migrations": {"namespaceKeys": {"allowances": [["` + ownerID + `", "` + fromID + `"]]}}}`
+		_, err = MigrateSchema(mockStub, txID, upgradeArgs, "7")
+		Expect(err).To(BeNil())
+
+		for _, key := range []string{"owner", "decimals", "totalSupply", ownerID, fromID, ownerID + "-" + fromID, fromID + "-" + toID} {
+			Expect(mockStub.State[key]).To(BeNil())
+		}
+		indexKey, err := mockStub.CreateCompositeKey("Allowance", []string{fromID, toID})
+		Expect(err).To(BeNil())
+		Expect(mockStub.State[indexKey]).To(BeNil())
+
+		owner, err := sampleToken.GetOwner(mockStub)
+		Expect(err).To(BeNil())
+		Expect(owner).To(Equal(ownerID))
+		name, err := sampleToken.GetName(mockStub)
+		Expect(err).To(BeNil())
+		Expect(name).To(Equal("legacy"))
+		totalSupply, err := sampleToken.GetTotalSupply(mockStub)
+		Expect(err).To(BeNil())
+		Expect(totalSupply.String()).To(Equal("1000"))
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("100"))
+		allowance, err := sampleToken.GetAllowance(mockStub, []string{ownerID, fromID})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("50"))
+		allowance, err = sampleToken.GetAllowance(mockStub, []string{fromID, toID})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("5"))
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("HasRole"), []byte(erc20roles.ADMIN), []byte(ownerID)})
+		Expect(string(res.Payload)).To(Equal("true"))
+	})
+
+	It("Should spend the converted allowances", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("TransferFrom"), []byte(ownerID), []byte(fromID), []byte("20")}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{ownerID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("880"))
+	})
+
+	It("Should not let an account named after a token attribute collide with it", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		mockStub.MockTransactionStart(txID)
+		defer mockStub.MockTransactionEnd(txID)
+		Expect(sampleToken.Activate(mockStub, []string{"totalSupply"}, sampleToken.GetBalanceOf)).To(BeNil())
+
+		totalSupply, err := sampleToken.GetTotalSupply(mockStub)
+		Expect(err).To(BeNil())
+		Expect(totalSupply.String()).To(Equal("1000"))
+	})
+
+	It("Should not convert the keys twice", func() {
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "legacy", "symbol": "LG", "decimals": "0"}`)}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("120"))
+	})
+})
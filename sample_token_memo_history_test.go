@@ -0,0 +1,147 @@
+package main_test
+
+import (
+	. "erc20"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memo history", func() {
+	const (
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubMemoHistory", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+
+	//every memo record is keyed by its transaction ID
+	invoker := &TxInvoker{Prefix: "test-memo-history"}
+	invoke := func(args ...string) (string, string) {
+		return invoker.Invoke(mockStub, nil, args...)
+	}
+	//the paged queries are called with a stub implementing the pagination of the peer
+	queries := map[string]func(shim.ChaincodeStubInterface, []string) (*MemosPage, error){
+		"GetMemos":            sampleToken.GetMemos,
+		"GetMemosByReference": sampleToken.GetMemosByReference,
+	}
+	getPage := func(args ...string) MemosPage {
+		page, err := queries[args[0]](&PaginatedStub{MockStub: mockStub}, args[1:])
+		Expect(err).To(BeNil())
+		return *page
+	}
+
+	It("Initializes the token & activates the accounts", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit("test-memo-history-init", [][]byte{[]byte(`{"name": "history", "symbol": "HS", "decimals": "0", "initialSupply": "1000"}`)}).Message).To(BeEmpty())
+		for _, accountID := range []string{fromID, toID} {
+			_, message := invoke("Activate", accountID)
+			Expect(message).To(BeEmpty())
+		}
+	})
+
+	It("Should record every memo with its sender, amount & reference", func() {
+		_, message := invoke("Transfer", fromID, "10", "rent", "INV-1")
+		Expect(message).To(BeEmpty())
+		_, message = invoke("Transfer", fromID, "20", "groceries")
+		Expect(message).To(BeEmpty())
+		_, message = invoke("Transfer", fromID, "5", "", "INV-1")
+		Expect(message).To(BeEmpty())
+
+		//the last memo is kept for GetMemo
+		memo, message := invoke("GetMemo", fromID)
+		Expect(message).To(BeEmpty())
+		Expect(memo).To(Equal("groceries"))
+
+		page := getPage("GetMemos", fromID)
+		Expect(page.Bookmark).To(BeEmpty())
+		Expect(page.Memos).To(HaveLen(3))
+		Expect(page.Memos[0].TxID).To(Equal("test-memo-history-03"))
+		Expect(page.Memos[0].From).To(Equal(ownerID))
+		Expect(page.Memos[0].To).To(Equal(fromID))
+		Expect(page.Memos[0].Amount.String()).To(Equal("10"))
+		Expect(page.Memos[0].Memo).To(Equal("rent"))
+		Expect(page.Memos[0].Reference).To(Equal("INV-1"))
+		Expect(page.Memos[0].Timestamp).NotTo(BeZero())
+		Expect(page.Memos[1].Memo).To(Equal("groceries"))
+		Expect(page.Memos[1].Reference).To(BeEmpty())
+		Expect(page.Memos[2].Memo).To(BeEmpty())
+		Expect(page.Memos[2].Amount.String()).To(Equal("5"))
+
+		//the sender keeps the memos it sent
+		Expect(getPage("GetMemos", ownerID).Memos).To(Equal(page.Memos))
+	})
+
+	It("Should not record the transfers without memo nor reference", func() {
+		_, message := invoke("Transfer", fromID, "1")
+		Expect(message).To(BeEmpty())
+		Expect(getPage("GetMemos", fromID).Memos).To(HaveLen(3))
+	})
+
+	It("Should page through the memos", func() {
+		page := getPage("GetMemos", fromID, "2")
+		Expect(page.Memos).To(HaveLen(2))
+		Expect(page.Memos[1].Memo).To(Equal("groceries"))
+		Expect(page.Bookmark).NotTo(BeEmpty())
+
+		page = getPage("GetMemos", fromID, "2", page.Bookmark)
+		Expect(page.Memos).To(HaveLen(1))
+		Expect(page.Memos[0].Amount.String()).To(Equal("5"))
+		Expect(page.Bookmark).To(BeEmpty())
+
+		Expect(getPage("GetMemos", toID).Memos).To(BeEmpty())
+	})
+
+	It("Should record the memos of TransferFrom from the token owner", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		_, message := invoke("UpdateApproval", toID, "5")
+		Expect(message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		_, message = invoke("TransferFrom", fromID, toID, "3", "refund", "INV-1")
+		Expect(message).To(BeEmpty())
+
+		page := getPage("GetMemos", toID)
+		Expect(page.Memos).To(HaveLen(1))
+		Expect(page.Memos[0].From).To(Equal(fromID))
+		Expect(page.Memos[0].To).To(Equal(toID))
+		Expect(page.Memos[0].Amount.String()).To(Equal("3"))
+		Expect(page.Memos[0].Memo).To(Equal("refund"))
+		Expect(getPage("GetMemos", fromID).Memos).To(HaveLen(4))
+	})
+
+	It("Should find the memos by reference", func() {
+		page := getPage("GetMemosByReference", "INV-1")
+		Expect(page.Bookmark).To(BeEmpty())
+		Expect(page.Memos).To(HaveLen(3))
+		Expect(page.Memos[0].Memo).To(Equal("rent"))
+		Expect(page.Memos[1].Amount.String()).To(Equal("5"))
+		Expect(page.Memos[2].Memo).To(Equal("refund"))
+
+		page = getPage("GetMemosByReference", "INV-1", "1")
+		Expect(page.Memos).To(HaveLen(1))
+		Expect(page.Bookmark).NotTo(BeEmpty())
+
+		Expect(getPage("GetMemosByReference", "INV-2").Memos).To(BeEmpty())
+		_, message := invoke("GetMemosByReference", "")
+		Expect(message).To(ContainSubstring("the reference is required"))
+	})
+})
@@ -0,0 +1,92 @@
+package main_test
+
+import (
+	"encoding/json"
+	. "erc20"
+	"erc20/lib/erc20msplist"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MSP allowlist & denylist", func() {
+	const (
+		txID = `test-msplist-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubMSPList", sampleToken)
+
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+
+	It("Initializes the token as owner & activates `fromID`", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "msplist", "symbol": "ML", "decimals": "0"}`)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+	})
+
+	When("Only the owner's MSP is allowed", func() {
+		It("Sets up the allowlist", func() {
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("AddMSP"), []byte(ownerOrg)}).Message).To(BeEmpty())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("SetMSPListMode"), []byte(erc20msplist.ALLOW)}).Message).To(BeEmpty())
+
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetMSPList")})
+			Expect(res.Message).To(BeEmpty())
+			list := erc20msplist.MSPList{}
+			Expect(json.Unmarshal(res.Payload, &list)).To(BeNil())
+			Expect(list.Mode).To(Equal(erc20msplist.ALLOW))
+			Expect(list.MSPIDs).To(ConsistOf(ownerOrg))
+		})
+
+		It("Should not send to other MSPs", func() {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("10")})
+			Expect(res.Message).To(ContainSubstring("MSP " + fromOrg + " are not allowed"))
+		})
+
+		It("Should not activate accounts of other MSPs", func() {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(toID)})
+			Expect(res.Message).To(ContainSubstring("MSP " + toOrg + " are not allowed"))
+		})
+
+		It("Should send once the MSP is allowed", func() {
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("AddMSP"), []byte(fromOrg)}).Message).To(BeEmpty())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("10")}).Message).To(BeEmpty())
+		})
+	})
+
+	When("An MSP is denied", func() {
+		It("Sets up the denylist", func() {
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RemoveMSP"), []byte(ownerOrg)}).Message).To(BeEmpty())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("SetMSPListMode"), []byte(erc20msplist.DENY)}).Message).To(BeEmpty())
+		})
+
+		It("Should not let the denied MSP send", func() {
+			_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+
+			ownerID := ownerOrg + ",Org1,Org1-child1"
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(ownerID), []byte("1")})
+			Expect(res.Message).To(ContainSubstring("MSP " + fromOrg + " are not allowed"))
+		})
+
+		It("Should not let anyone else than an admin change the list", func() {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("RemoveMSP"), []byte(fromOrg)})
+			Expect(res.Message).To(ContainSubstring("role ADMIN"))
+		})
+	})
+})
@@ -0,0 +1,96 @@
+package main_test
+
+import (
+	"encoding/json"
+	. "erc20"
+	"erc20/lib/erc20multisig"
+	. "erc20/testutils"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multi-signature approval", func() {
+	const (
+		txID         = `test-multisig-id`
+		proposalTxID = `test-multisig-proposal-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		ownerIssuer  = `Org1`
+		ownerOrg     = `sampleOrgMSP`
+		ownerSubject = `Org1-child1`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubMultiSig", sampleToken)
+
+	ownerID := ownerOrg + "," + ownerIssuer + "," + ownerSubject
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+
+	It("Initializes the token and sets a 2-of-2 policy", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "multisig", "symbol": "MS", "decimals": "0"}`)}).Message).To(BeEmpty())
+
+		policy := fmt.Sprintf(`{"signers": ["%s", "%s"], "threshold": 2}`, ownerID, fromID)
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("SetMultiSigPolicy"), []byte(policy)}).Message).To(BeEmpty())
+	})
+
+	It("Should not allow minting directly", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("Mint"), []byte(ownerID), []byte("10")})
+		Expect(res.Message).To(ContainSubstring("requires an approved multi-signature proposal"))
+	})
+
+	It("Should not allow proposing functions that are not owner-only", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("Propose"), []byte("Transfer"), []byte(fromID), []byte("10")})
+		Expect(res.Message).To(ContainSubstring("can not be proposed"))
+	})
+
+	It("Proposes a Mint", func() {
+		res := mockStub.MockInvoke(proposalTxID, [][]byte{[]byte("Propose"), []byte("Mint"), []byte(ownerID), []byte("10")})
+		Expect(res.Message).To(BeEmpty())
+		Expect(string(res.Payload)).To(Equal(proposalTxID))
+	})
+
+	It("Should not execute with 1 of 2 approvals", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("Execute"), []byte(proposalTxID)})
+		Expect(res.Message).To(ContainSubstring("1 of 2 required approvals"))
+	})
+
+	It("Should execute once the second signer approves", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Approve"), []byte(proposalTxID)}).Message).To(BeEmpty())
+
+		totalSupply, err := sampleToken.GetTotalSupply(mockStub)
+		Expect(err).To(BeNil())
+
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Execute"), []byte(proposalTxID)}).Message).To(BeEmpty())
+
+		currentTotalSupply, err := sampleToken.GetTotalSupply(mockStub)
+		Expect(err).To(BeNil())
+		Expect(currentTotalSupply.Int64()).To(Equal(totalSupply.Int64() + 10))
+	})
+
+	It("Should report the proposal as executed", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetProposal"), []byte(proposalTxID)})
+		Expect(res.Message).To(BeEmpty())
+
+		proposal := erc20multisig.Proposal{}
+		Expect(json.Unmarshal(res.Payload, &proposal)).To(BeNil())
+		Expect(proposal.Status).To(Equal(erc20multisig.EXECUTED))
+		Expect(proposal.Approvals).To(ConsistOf(ownerID, fromID))
+	})
+
+	It("Should not execute the same proposal twice", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("Execute"), []byte(proposalTxID)})
+		Expect(res.Message).To(ContainSubstring("already executed"))
+	})
+})
@@ -3,11 +3,6 @@ package main_test
 import (
 	. "erc20"
 	. "erc20/helpers"
-	"erc20/lib/erc20burnable"
-	"erc20/lib/erc20detailed"
-	"erc20/lib/erc20mintable"
-	"erc20/lib/erc20ownable"
-	"erc20/lib/erc20pausable"
 	. "erc20/testutils"
 	"fmt"
 	"math/big"
@@ -37,17 +32,10 @@ var _ = Describe("token - normal cert without attrs", func() {
 		ownerSubject = `Org1-child1`
 	)
 
-	sampleToken := SampleToken{
-		&CustomBasicToken{},
-		&erc20ownable.Token{},
-		&erc20detailed.Token{},
-		&erc20mintable.Token{},
-		&erc20burnable.Token{},
-		&erc20pausable.Token{},
-	}
+	sampleToken := NewSampleToken()
 
 	var err error
-	var mockStub *shim.MockStub = shim.NewMockStub("mockStubNormal", &sampleToken)
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubNormal", sampleToken)
 	var initialTotalSupply *big.Int = Mul(big.NewInt(InitialMintAmount), Pow(10, StringToInt(tokenDecimals)))
 
 	ownerID := ownerOrg + "," + ownerIssuer + "," + ownerSubject
@@ -398,7 +386,7 @@ var _ = Describe("token - normal cert without attrs", func() {
 
 	Describe("Integrated Token pause functionalities...", func() {
 		It("Pauses the token state", func() {
-			err := sampleToken.Pause(mockStub, sampleToken.GetOwner)
+			err := sampleToken.Pause(mockStub, sampleToken.HasRole)
 			Expect(err).To(BeNil())
 		})
 
@@ -410,7 +398,7 @@ var _ = Describe("token - normal cert without attrs", func() {
 		})
 
 		It("Unpauses the token state", func() {
-			err := sampleToken.Unpause(mockStub, sampleToken.GetOwner)
+			err := sampleToken.Unpause(mockStub, sampleToken.HasRole)
 			Expect(err).To(BeNil())
 		})
 
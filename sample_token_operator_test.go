@@ -0,0 +1,163 @@
+package main_test
+
+import (
+	. "erc20"
+	"erc20/lib/erc20roles"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Operators", func() {
+	const (
+		txID = `test-operator-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubOperator", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	//the custodian sending & burning on behalf of `fromID`
+	operatorID := toOrg + "," + issuer + "," + toSubject
+
+	It("Initializes the token as owner & funds `fromID`", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "operator", "symbol": "OP", "decimals": "0"}`)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Transfer"), []byte(fromID), []byte("100")}).Message).To(BeEmpty())
+	})
+
+	It("Should not send for holders that did not authorize the operator", func() {
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("OperatorSend"), []byte(fromID), []byte(ownerID), []byte("10")})
+		Expect(res.Message).To(ContainSubstring("is not an operator for"))
+	})
+
+	It("Should only authorize activated operators", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("AuthorizeOperator"), []byte(operatorID)})
+		Expect(res.Message).To(ContainSubstring("is not registered"))
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("AuthorizeOperator"), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("can not authorize itself"))
+	})
+
+	It("Authorizes the operator", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(operatorID)}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("AuthorizeOperator"), []byte(operatorID)}).Message).To(BeEmpty())
+
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("IsOperatorFor"), []byte(operatorID), []byte(fromID)})
+		Expect(string(res.Payload)).To(Equal("true"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("IsOperatorFor"), []byte(fromID), []byte(operatorID)})
+		Expect(string(res.Payload)).To(Equal("false"))
+	})
+
+	It("Should send on behalf of the holder without an allowance", func() {
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("OperatorSend"), []byte(fromID), []byte(ownerID), []byte("30")}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("70"))
+	})
+
+	It("Should only burn on behalf of the holder as a member of the BURNER role", func() {
+		totalSupply, err := sampleToken.GetTotalSupply(mockStub)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("OperatorBurn"), []byte(fromID), []byte("20")})
+		Expect(res.Message).To(ContainSubstring("role BURNER"))
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("GrantRole"), []byte(erc20roles.BURNER), []byte(operatorID)}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("OperatorBurn"), []byte(fromID), []byte("20")}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("50"))
+
+		newTotalSupply, err := sampleToken.GetTotalSupply(mockStub)
+		Expect(err).To(BeNil())
+		Expect(newTotalSupply.Int64()).To(Equal(totalSupply.Int64() - 20))
+	})
+
+	It("Should not send more than the holder's balance", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("OperatorSend"), []byte(fromID), []byte(ownerID), []byte("51")})
+		Expect(res.Message).To(ContainSubstring("less than balance of holder"))
+	})
+
+	It("Should only send & burn integer amounts greater than 0", func() {
+		for _, amount := range []string{"1.5", "1e3", "NaN", "0", "-1"} {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("OperatorSend"), []byte(fromID), []byte(ownerID), []byte(amount)})
+			Expect(res.Message).To(ContainSubstring("invalid send amount " + amount))
+			res = mockStub.MockInvoke(txID, [][]byte{[]byte("OperatorBurn"), []byte(fromID), []byte(amount)})
+			Expect(res.Message).To(ContainSubstring("invalid burn amount " + amount))
+		}
+	})
+
+	It("Should not send when token is paused", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Pause")}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("OperatorSend"), []byte(fromID), []byte(ownerID), []byte("1")})
+		Expect(res.Message).To(ContainSubstring("not allowed when token is paused"))
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Unpause")}).Message).To(BeEmpty())
+	})
+
+	It("Should not send once the operator is revoked", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RevokeOperator"), []byte(operatorID)}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("OperatorBurn"), []byte(fromID), []byte("1")})
+		Expect(res.Message).To(ContainSubstring("is not an operator for"))
+	})
+
+	It("Should revoke the operators of a closed account", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("AuthorizeOperator"), []byte(operatorID)}).Message).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("CloseAccount"), []byte(fromID), []byte(ownerID)}).Message).To(BeEmpty())
+
+		isOperator, err := sampleToken.IsOperatorFor(mockStub, []string{operatorID, fromID})
+		Expect(err).To(BeNil())
+		Expect(isOperator).To(BeFalse())
+	})
+})
@@ -0,0 +1,170 @@
+package main_test
+
+import (
+	. "erc20"
+	"erc20/lib/erc20roles"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Two-step ownership transfer", func() {
+	const (
+		txID = `test-ownership-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerIssuer  = `Org1`
+		ownerOrg     = `sampleOrgMSP`
+		ownerSubject = `Org1-child1`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubOwnership", sampleToken)
+
+	ownerID := ownerOrg + "," + ownerIssuer + "," + ownerSubject
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+
+	It("Initializes the token as owner", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "ownership", "symbol": "OW", "decimals": "0"}`)}).Message).To(BeEmpty())
+	})
+
+	It("Should not be offered nor canceled by an ADMIN that is not the owner", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("GrantRole"), []byte(erc20roles.ADMIN), []byte(toID)}).Message).To(BeEmpty())
+
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("TransferOwnership"), []byte(toID)}).Message).To(ContainSubstring("token owner"))
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("CancelOwnershipTransfer")}).Message).To(ContainSubstring("token owner"))
+
+		pendingOwner, err := sampleToken.GetPendingOwner(mockStub)
+		Expect(err).To(BeNil())
+		Expect(pendingOwner).To(BeEmpty())
+
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RevokeRole"), []byte(erc20roles.ADMIN), []byte(toID)}).Message).To(BeEmpty())
+	})
+
+	It("Should keep the owner until the transfer is accepted", func() {
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("TransferOwnership"), []byte(fromID)}).Message).To(BeEmpty())
+
+		owner, err := sampleToken.GetOwner(mockStub)
+		Expect(err).To(BeNil())
+		Expect(owner).To(Equal(ownerID))
+
+		pendingOwner, err := sampleToken.GetPendingOwner(mockStub)
+		Expect(err).To(BeNil())
+		Expect(pendingOwner).To(Equal(fromID))
+	})
+
+	It("Should not be accepted by anyone else than the pending owner", func() {
+		_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("AcceptOwnership")}).Message).To(ContainSubstring("pending owner"))
+	})
+
+	It("Should transfer the ownership when accepted by the pending owner", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("AcceptOwnership")}).Message).To(BeEmpty())
+
+		owner, err := sampleToken.GetOwner(mockStub)
+		Expect(err).To(BeNil())
+		Expect(owner).To(Equal(fromID))
+
+		pendingOwner, err := sampleToken.GetPendingOwner(mockStub)
+		Expect(err).To(BeNil())
+		Expect(pendingOwner).To(BeEmpty())
+	})
+
+	It("Should move the roles of the previous owner to the new owner", func() {
+		for _, role := range erc20roles.AllRoles {
+			isMember, err := sampleToken.HasRole(mockStub, []string{role, fromID})
+			Expect(err).To(BeNil())
+			Expect(isMember).To(BeTrue())
+
+			isMember, err = sampleToken.HasRole(mockStub, []string{role, ownerID})
+			Expect(err).To(BeNil())
+			Expect(isMember).To(BeFalse())
+		}
+
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Mint"), []byte(fromID), []byte("100")}).Message).To(ContainSubstring("role MINTER"))
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("TransferOwnership"), []byte(ownerID)}).Message).To(ContainSubstring("token owner"))
+	})
+
+	It("Should cancel a pending transfer", func() {
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("TransferOwnership"), []byte("typo")}).Message).To(BeEmpty())
+		Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("CancelOwnershipTransfer")}).Message).To(BeEmpty())
+
+		pendingOwner, err := sampleToken.GetPendingOwner(mockStub)
+		Expect(err).To(BeNil())
+		Expect(pendingOwner).To(BeEmpty())
+	})
+
+	When("Ownership is renounced", func() {
+		It("Should only be renounced by the owner", func() {
+			_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RenounceOwnership")}).Message).To(ContainSubstring("token owner"))
+
+			_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RenounceOwnership")}).Message).To(ContainSubstring("last member of role ADMIN"))
+			owner, err := sampleToken.GetOwner(mockStub)
+			Expect(err).To(BeNil())
+			Expect(owner).To(Equal(fromID))
+
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("GrantRole"), []byte(erc20roles.ADMIN), []byte(toID)}).Message).To(BeEmpty())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RenounceOwnership")}).Message).To(BeEmpty())
+
+			owner, err = sampleToken.GetOwner(mockStub)
+			Expect(err).To(BeNil())
+			Expect(owner).To(BeEmpty())
+		})
+
+		It("Should revoke the roles of the former owner", func() {
+			for _, role := range erc20roles.AllRoles {
+				isMember, err := sampleToken.HasRole(mockStub, []string{role, fromID})
+				Expect(err).To(BeNil())
+				Expect(isMember).To(BeFalse())
+			}
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Mint"), []byte(fromID), []byte("100")}).Message).To(ContainSubstring("role MINTER"))
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("GrantRole"), []byte(erc20roles.ADMIN), []byte(fromID)}).Message).To(ContainSubstring("role ADMIN"))
+		})
+
+		It("Should not offer the ownership again", func() {
+			_, err := SetCurrentCaller(mockStub, toOrg, Client2Cert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("TransferOwnership"), []byte(toID)}).Message).To(ContainSubstring("renounced"))
+
+			owner, err := sampleToken.GetOwner(mockStub)
+			Expect(err).To(BeNil())
+			Expect(owner).To(BeEmpty())
+		})
+
+		It("Should not re-initialize the token on upgrade", func() {
+			Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "hijack", "symbol": "HJ", "decimals": "0"}`)}).Message).To(ContainSubstring("renounced"))
+
+			name, err := sampleToken.GetName(mockStub)
+			Expect(err).To(BeNil())
+			Expect(name).To(Equal("ownership"))
+		})
+	})
+})
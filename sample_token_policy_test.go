@@ -0,0 +1,146 @@
+package main_test
+
+import (
+	. "erc20"
+	. "erc20/testutils"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Method policies", func() {
+	const (
+		txID         = `test-policy-id`
+		proposalTxID = `test-policy-proposal-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		ownerIssuer  = `Org1`
+		ownerOrg     = `sampleOrgMSP`
+		ownerSubject = `Org1-child1`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubPolicy", sampleToken)
+
+	ownerID := ownerOrg + "," + ownerIssuer + "," + ownerSubject
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+
+	//certs of the owner's identity carrying a NodeOU & CA attributes, AdminCert carries none
+	newOwnerCert := func(ous []string, attrs map[string]string) string {
+		cert, err := NewCert(ownerIssuer, ownerSubject, ous, attrs)
+		Expect(err).To(BeNil())
+		return cert
+	}
+	minterCert := newOwnerCert([]string{"admin"}, map[string]string{"token.role": "minter"})
+	auditorCert := newOwnerCert([]string{"client"}, map[string]string{"token.role": "auditor"})
+
+	invokeAs := func(cert string, args ...string) string {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, cert)
+		Expect(err).To(BeNil())
+		input := [][]byte{}
+		for _, arg := range args {
+			input = append(input, []byte(arg))
+		}
+		return mockStub.MockInvoke(txID, input).Message
+	}
+
+	It("Initializes the token as owner", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "policy", "symbol": "PL", "decimals": "0"}`)}).Message).To(BeEmpty())
+	})
+
+	It("Should restrict a method to callers carrying an attribute", func() {
+		Expect(invokeAs(AdminCert, "SetMethodPolicy", "Mint", `{"attributes": {"token.role": "minter"}}`)).To(BeEmpty())
+
+		Expect(invokeAs(AdminCert, "Mint", ownerID, "10")).To(ContainSubstring("requires the certificate attribute token.role=minter"))
+		Expect(invokeAs(auditorCert, "Mint", ownerID, "10")).To(ContainSubstring("requires the certificate attribute token.role=minter"))
+		Expect(invokeAs(minterCert, "Mint", ownerID, "10")).To(BeEmpty())
+	})
+
+	It("Should match any value of an attribute with `*`", func() {
+		Expect(invokeAs(AdminCert, "SetMethodPolicy", "Mint", `{"attributes": {"token.role": "*"}}`)).To(BeEmpty())
+
+		Expect(invokeAs(AdminCert, "Mint", ownerID, "10")).To(ContainSubstring("requires the certificate attribute"))
+		Expect(invokeAs(auditorCert, "Mint", ownerID, "10")).To(BeEmpty())
+	})
+
+	It("Should restrict a method to callers of a NodeOU", func() {
+		Expect(invokeAs(AdminCert, "SetMethodPolicy", "Pause", `{"nodeOUs": ["admin"]}`)).To(BeEmpty())
+
+		Expect(invokeAs(auditorCert, "Pause")).To(ContainSubstring("requires one of the NodeOUs [admin]"))
+		Expect(invokeAs(minterCert, "Pause")).To(BeEmpty())
+		Expect(invokeAs(minterCert, "Unpause")).To(BeEmpty())
+	})
+
+	It("Should lift a policy", func() {
+		Expect(invokeAs(AdminCert, "RemoveMethodPolicy", "Pause")).To(BeEmpty())
+
+		Expect(invokeAs(auditorCert, "Pause")).To(BeEmpty())
+		Expect(invokeAs(auditorCert, "Unpause")).To(BeEmpty())
+	})
+
+	It("Should restrict a method to members of a role", func() {
+		Expect(invokeAs(AdminCert, "SetMethodPolicy", "BurnFrom", `{"roles": ["burner"]}`)).To(ContainSubstring("unknown role burner"))
+		Expect(invokeAs(AdminCert, "SetMethodPolicy", "BurnFrom", `{"roles": ["BURNER"]}`)).To(BeEmpty())
+
+		//the allowance authorises BurnFrom and is reduced by it, the policy only adds the role
+		Expect(invokeAs(AdminCert, "UpdateApproval", ownerID, "10")).To(BeEmpty())
+		Expect(invokeAs(AdminCert, "BurnFrom", ownerID, "4")).To(BeEmpty())
+		allowance, err := sampleToken.GetAllowance(mockStub, []string{ownerID, ownerID})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("6"))
+
+		Expect(invokeAs(AdminCert, "RevokeRole", "BURNER", ownerID)).To(BeEmpty())
+		Expect(invokeAs(AdminCert, "BurnFrom", ownerID, "4")).To(ContainSubstring("requires a member of one of the roles [BURNER]"))
+
+		Expect(invokeAs(AdminCert, "RemoveMethodPolicy", "BurnFrom")).To(BeEmpty())
+		Expect(invokeAs(AdminCert, "BurnFrom", ownerID, "4")).To(BeEmpty())
+		Expect(invokeAs(AdminCert, "BurnFrom", ownerID, "4")).To(ContainSubstring("approved spending amount"))
+		Expect(invokeAs(AdminCert, "GrantRole", "BURNER", ownerID)).To(BeEmpty())
+	})
+
+	It("Should not restrict the policy administration, which would lock the admins out", func() {
+		Expect(invokeAs(minterCert, "SetMethodPolicy", "SetMethodPolicy", `{"nodeOUs": ["peer"]}`)).To(ContainSubstring("can not be restricted"))
+		Expect(invokeAs(minterCert, "SetMethodPolicy", "RemoveMethodPolicy", `{"nodeOUs": ["peer"]}`)).To(ContainSubstring("can not be restricted"))
+		//a mistyped method would be protected by nothing
+		Expect(invokeAs(AdminCert, "SetMethodPolicy", "mint", `{"nodeOUs": ["peer"]}`)).To(ContainSubstring("not an Invoke method"))
+
+		//an admin carrying none of the attributes can still lift a policy
+		Expect(invokeAs(AdminCert, "SetMethodPolicy", "Burn", `{"nodeOUs": ["peer"]}`)).To(BeEmpty())
+		Expect(invokeAs(AdminCert, "RemoveMethodPolicy", "Burn")).To(BeEmpty())
+	})
+
+	When("A method runs through a multi-signature proposal", func() {
+		It("Sets a 2-of-2 policy & proposes a Mint", func() {
+			policy := fmt.Sprintf(`{"signers": ["%s", "%s"], "threshold": 2}`, ownerID, fromID)
+			Expect(invokeAs(AdminCert, "SetMultiSigPolicy", policy)).To(BeEmpty())
+
+			res := mockStub.MockInvoke(proposalTxID, [][]byte{[]byte("Propose"), []byte("Mint"), []byte(ownerID), []byte("10")})
+			Expect(res.Message).To(BeEmpty())
+
+			_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Approve"), []byte(proposalTxID)}).Message).To(BeEmpty())
+		})
+
+		It("Should apply the policy of the proposed method to the executor", func() {
+			Expect(invokeAs(AdminCert, "Execute", proposalTxID)).To(ContainSubstring("Calling Mint requires the certificate attribute"))
+
+			totalSupply, err := sampleToken.GetTotalSupply(mockStub)
+			Expect(err).To(BeNil())
+			Expect(invokeAs(auditorCert, "Execute", proposalTxID)).To(BeEmpty())
+
+			currentTotalSupply, err := sampleToken.GetTotalSupply(mockStub)
+			Expect(err).To(BeNil())
+			Expect(currentTotalSupply.Int64()).To(Equal(totalSupply.Int64() + 10))
+		})
+	})
+})
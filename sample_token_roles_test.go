@@ -0,0 +1,109 @@
+package main_test
+
+import (
+	. "erc20"
+	"erc20/lib/erc20roles"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Role based access control", func() {
+	const (
+		txID = `test-roles-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		ownerIssuer  = `Org1`
+		ownerOrg     = `sampleOrgMSP`
+		ownerSubject = `Org1-child1`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubRoles", sampleToken)
+
+	ownerID := ownerOrg + "," + ownerIssuer + "," + ownerSubject
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+
+	It("Initializes the token as owner", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{"name": "roles", "symbol": "RL", "decimals": "2"}`)}).Message).To(BeEmpty())
+	})
+
+	It("Should grant every role to the owner", func() {
+		for _, role := range erc20roles.AllRoles {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("HasRole"), []byte(role), []byte(ownerID)})
+			Expect(res.Message).To(BeEmpty())
+			Expect(string(res.Payload)).To(Equal("true"))
+		}
+	})
+
+	It("Should not allow revoking the last admin", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("RevokeRole"), []byte(erc20roles.ADMIN), []byte(ownerID)})
+		Expect(res.Message).To(ContainSubstring("last member"))
+	})
+
+	It("Should reject unknown roles", func() {
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GrantRole"), []byte("SUPERUSER"), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("unknown role"))
+	})
+
+	When("Caller is not a minter", func() {
+		It("Change the invoker to `Client1Cert`", func() {
+			_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+		})
+
+		It("Should not be able to mint", func() {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("Mint"), []byte(fromID), []byte("10")})
+			Expect(res.Message).To(ContainSubstring("role MINTER"))
+		})
+
+		It("Should not be able to grant roles", func() {
+			res := mockStub.MockInvoke(txID, [][]byte{[]byte("GrantRole"), []byte(erc20roles.MINTER), []byte(fromID)})
+			Expect(res.Message).To(ContainSubstring("role ADMIN"))
+		})
+	})
+
+	When("MINTER role is granted by owner", func() {
+		It("Grants MINTER to `fromID`", func() {
+			_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Activate"), []byte(fromID)}).Message).To(BeEmpty())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("GrantRole"), []byte(erc20roles.MINTER), []byte(fromID)}).Message).To(BeEmpty())
+		})
+
+		It("Lists `fromID` as member of MINTER", func() {
+			members, err := sampleToken.GetRoleMembers(mockStub, []string{erc20roles.MINTER})
+			Expect(err).To(BeNil())
+			Expect(members).To(ConsistOf(ownerID, fromID))
+		})
+
+		It("Should now be able to mint as `fromID`", func() {
+			_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Mint"), []byte(fromID), []byte("10")}).Message).To(BeEmpty())
+
+			balance, err := sampleToken.GetBalanceOf(mockStub, []string{fromID})
+			Expect(err).To(BeNil())
+			Expect(balance.String()).To(Equal("10"))
+		})
+
+		It("Should not be able to mint after MINTER is revoked", func() {
+			_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("RevokeRole"), []byte(erc20roles.MINTER), []byte(fromID)}).Message).To(BeEmpty())
+
+			_, err = SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+			Expect(err).To(BeNil())
+			Expect(mockStub.MockInvoke(txID, [][]byte{[]byte("Mint"), []byte(fromID), []byte("10")}).Message).NotTo(BeEmpty())
+		})
+	})
+})
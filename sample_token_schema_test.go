@@ -0,0 +1,175 @@
+package main_test
+
+import (
+	. "erc20"
+	. "erc20/helpers"
+	"erc20/lib/erc20roles"
+	. "erc20/testutils"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schema migrations", func() {
+	const (
+		txID       = `test-schema-id`
+		legacyTxID = `test-legacy-ledger-id`
+
+		//attributes matches the certs in /testutils
+		issuer      = `Org1-child1`
+		fromOrg     = `clientOrg1MSP`
+		fromSubject = `Org1-child1-client1`
+
+		toOrg     = `clientOrg2MSP`
+		toSubject = `Org1-child1-client2`
+
+		ownerOrg = `sampleOrgMSP`
+	)
+
+	sampleToken := NewSampleToken()
+
+	var mockStub *shim.MockStub = shim.NewMockStub("mockStubSchema", sampleToken)
+
+	ownerID := ownerOrg + ",Org1,Org1-child1"
+	fromID := fromOrg + "," + issuer + "," + fromSubject
+	toID := toOrg + "," + issuer + "," + toSubject
+	//an account whose ID looks like an allowance of `fromID`
+	backupID := fromID + "-backup"
+
+	It("Should start new ledgers at the latest version", func() {
+		freshStub := shim.NewMockStub("mockStubSchemaFresh", sampleToken)
+		_, err := SetCurrentCaller(freshStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		Expect(freshStub.MockInit(txID, [][]byte{[]byte(`{"name": "schema", "symbol": "SC", "decimals": "0"}`)}).Message).To(BeEmpty())
+
+		res := freshStub.MockInvoke(txID, [][]byte{[]byte("GetSchemaVersion")})
+		Expect(res.Message).To(BeEmpty())
+		Expect(string(res.Payload)).To(Equal("7"))
+	})
+
+	It("Writes a ledger with bare keys & no version", func() {
+		mockStub.MockTransactionStart(legacyTxID)
+		defer mockStub.MockTransactionEnd(legacyTxID)
+
+		legacyState := map[string]string{
+			"owner":       ownerID,
+			"name":        "legacy",
+			"symbol":      "LG",
+			"decimals":    "0",
+			"totalSupply": "1000",
+			ownerID:       "900",
+			fromID:        "70",
+			backupID:      "30",
+			toID:          "0",
+			//a zero allowance, dropped by the upgrade
+			ownerID + "-" + toID: "0",
+		}
+		for key, value := range legacyState {
+			Expect(mockStub.PutState(key, []byte(value))).To(BeNil())
+		}
+
+		//the activation of `fromID` & the freeze of `backupID`, kept in the account records
+		activationKey, err := mockStub.CreateCompositeKey("Activation", []string{fromID})
+		Expect(err).To(BeNil())
+		activation := `{"account":"` + fromID + `","approvedBy":"` + ownerID + `","approvedAt":1234,"txID":"` + legacyTxID + `"}`
+		Expect(mockStub.PutState(activationKey, []byte(activation))).To(BeNil())
+		frozenKey, err := mockStub.CreateCompositeKey(FrozenObjectType, []string{backupID})
+		Expect(err).To(BeNil())
+		Expect(mockStub.PutState(frozenKey, []byte(`{"action":"freeze"}`))).To(BeNil())
+	})
+
+	It("Should reject malformed migration parameters", func() {
+		_, err := SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		res := mockStub.MockInit(txID, [][]byte{[]byte(`{"migrations": ["namespaceKeys"]}`)})
+		Expect(res.Message).To(ContainSubstring("invalid upgrade args"))
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetSchemaVersion")})
+		Expect(string(res.Payload)).To(Equal("0"))
+	})
+
+	It("Runs the pending migrations with the owner's parameters, a step or batch per transaction", func() {
+		upgradeArgs := `{"migrations": {"namespaceKeys": {"balances": ["` + backupID + `"], "allowances": [["` + ownerID + `", "` + toID + `"]], "batchSize": 2}, ` +
+			`"countHolders": {"batchSize": 3}, "pruneZeroAllowances": {"batchSize": 1}, "accountRecords": {"batchSize": 3}, "accountClass": {"batchSize": 3}}}`
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(upgradeArgs)}).Message).To(BeEmpty())
+
+		//the upgrade ran grantOwnerRoles, the token waits for the other steps
+		res := mockStub.MockInvoke(txID, [][]byte{[]byte("GetSchemaVersion")})
+		Expect(string(res.Payload)).To(Equal("1"))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOf"), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("the ledger is being migrated"))
+
+		_, err := SetCurrentCaller(mockStub, fromOrg, Client1Cert)
+		Expect(err).To(BeNil())
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("MigrateSchema"), []byte(upgradeArgs)})
+		Expect(res.Message).To(ContainSubstring("only accessible to token owner"))
+
+		//namespaceKeys moves the 5 bare keys in 3 batches, countHolders, accountRecords & accountClass go through the 4 balances in 2 batches each
+		//and pruneZeroAllowances through the allowance in 1
+		_, err = SetCurrentCaller(mockStub, ownerOrg, AdminCert)
+		Expect(err).To(BeNil())
+		calls, err := MigrateSchema(mockStub, txID, upgradeArgs, "3")
+		Expect(err).To(BeNil())
+		Expect(calls).To(Equal(5))
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetBalanceOf"), []byte(fromID)})
+		Expect(res.Message).To(ContainSubstring("the ledger is being migrated"))
+
+		calls, err = MigrateSchema(mockStub, txID, upgradeArgs, "7")
+		Expect(err).To(BeNil())
+		Expect(calls).To(Equal(6))
+		bookmark, err := GetConfigState(mockStub, "migrationBookmark")
+		Expect(err).To(BeNil())
+		Expect(bookmark).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{backupID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("30"))
+		//the records of the default token store their empty class
+		key, err := mockStub.CreateCompositeKey(BalanceObjectType, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(string(mockStub.State[key])).To(ContainSubstring(`"class":""`))
+		account, err := sampleToken.GetAccount(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(account.Balance.String()).To(Equal("70"))
+		Expect(account.ActivatedBy).To(Equal(ownerID))
+		Expect(account.ActivatedAt).To(Equal(int64(1234)))
+		Expect(account.Frozen).To(BeFalse())
+		account, err = sampleToken.GetAccount(mockStub, []string{backupID})
+		Expect(err).To(BeNil())
+		Expect(account.Frozen).To(BeTrue())
+		allowance, err := sampleToken.GetAllowance(mockStub, []string{fromID, "backup"})
+		Expect(err).To(BeNil())
+		Expect(allowance.String()).To(Equal("0"))
+
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("HasRole"), []byte(erc20roles.ADMIN), []byte(ownerID)})
+		Expect(string(res.Payload)).To(Equal("true"))
+
+		//the owner, `fromID` & `backupID`, `toID` holds nothing
+		res = mockStub.MockInvoke(txID, [][]byte{[]byte("GetHolderCount")})
+		Expect(string(res.Payload)).To(Equal("3"))
+
+		page, err := sampleToken.GetApprovalsFor(&PaginatedStub{MockStub: mockStub}, []string{toID})
+		Expect(err).To(BeNil())
+		Expect(page.Allowances).To(BeEmpty())
+	})
+
+	It("Should not run the migrations twice", func() {
+		Expect(mockStub.MockInit(txID, [][]byte{[]byte(`{}`)}).Message).To(BeEmpty())
+
+		balance, err := sampleToken.GetBalanceOf(mockStub, []string{fromID})
+		Expect(err).To(BeNil())
+		Expect(balance.String()).To(Equal("70"))
+	})
+
+	It("Should not downgrade a ledger written by a newer chaincode", func() {
+		mockStub.MockTransactionStart(legacyTxID)
+		versionKey, err := mockStub.CreateCompositeKey("config", []string{"schemaVersion"})
+		Expect(err).To(BeNil())
+		Expect(mockStub.PutState(versionKey, []byte("99"))).To(BeNil())
+		mockStub.MockTransactionEnd(legacyTxID)
+
+		res := mockStub.MockInit(txID, [][]byte{[]byte(`{}`)})
+		Expect(res.Message).To(ContainSubstring("newer than the chaincode's"))
+	})
+})
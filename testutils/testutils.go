@@ -1,12 +1,25 @@
 package testutils
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
 	"github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric/protos/peer"
 )
 
 const (
@@ -70,6 +83,37 @@ func SetCurrentCaller(stub *shim.MockStub, mspID string, cert string) (*shim.Moc
 	return stub, nil
 }
 
+//attributesOID is the extension of the Fabric CA attributes in enrollment certificates
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+//NewCert issues a PEM certificate for `subjectCN` by the CA `issuerCN`, with the NodeOUs `ous` & the Fabric CA attributes `attrs`,
+//for the callers the certs above don't cover
+func NewCert(issuerCN string, subjectCN string, ous []string, attrs map[string]string) (string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: subjectCN, OrganizationalUnit: ous},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if len(attrs) != 0 {
+		value, err := json.Marshal(map[string]map[string]string{"attrs": attrs})
+		if err != nil {
+			return "", err
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: attributesOID, Value: value}}
+	}
+	issuer := &x509.Certificate{Subject: pkix.Name{CommonName: issuerCN}}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), nil
+}
+
 func SetIdemixMockStubWithAttrs(stub *shim.MockStub, mspID string) (*shim.MockStub, error) {
 	idBytes, err := base64.StdEncoding.DecodeString(IdemixCred)
 	if err != nil {
@@ -107,3 +151,97 @@ func WithAllowanceOf(value *big.Int) func(shim.ChaincodeStubInterface, []string)
 		return value, nil
 	}
 }
+
+//PaginatedStub implements the paginated queries of the peer the MockStub lacks, the paged methods are called with it directly
+type PaginatedStub struct {
+	*shim.MockStub
+}
+
+//GetStateByPartialCompositeKeyWithPagination returns `pageSize` keys starting at `bookmark`, and the bookmark of the next key, empty after the last one
+func (s *PaginatedStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string,
+	pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	iterator, err := s.MockStub.GetStateByPartialCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iterator.Close()
+
+	page, metadata := &pageIterator{}, &pb.QueryResponseMetadata{}
+	for iterator.HasNext() {
+		queryResult, err := iterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if queryResult.Key < bookmark {
+			continue
+		}
+		if len(page.results) == int(pageSize) {
+			metadata.Bookmark = queryResult.Key
+			break
+		}
+		page.results = append(page.results, queryResult)
+	}
+	metadata.FetchedRecordsCount = int32(len(page.results))
+	return page, metadata, nil
+}
+
+type pageIterator struct {
+	results []*queryresult.KV
+}
+
+func (i *pageIterator) HasNext() bool {
+	return len(i.results) != 0
+}
+
+func (i *pageIterator) Next() (*queryresult.KV, error) {
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+func (i *pageIterator) Close() error {
+	return nil
+}
+
+//MigrateSchema invokes MigrateSchema with the upgrade `args` until the schema version of the ledger is `version`,
+//and returns the number of calls it took
+func MigrateSchema(stub *shim.MockStub, txID string, args string, version string) (int, error) {
+	for calls := 0; calls < 100; calls++ {
+		res := stub.MockInvoke(txID, [][]byte{[]byte("GetSchemaVersion")})
+		if string(res.Payload) == version {
+			return calls, nil
+		}
+		res = stub.MockInvoke(txID, [][]byte{[]byte("MigrateSchema"), []byte(args)})
+		if res.Message != "" {
+			return calls, errors.New(res.Message)
+		}
+	}
+	return 0, fmt.Errorf("the ledger is not at version %v after 100 calls of MigrateSchema", version)
+}
+
+//TxInvoker invokes MockStubs under a transaction ID of its own for every call, `[Prefix]-[count]` with the count padded to 2 digits,
+//for the state keyed by transaction ID (deltas, memo records, histories)
+type TxInvoker struct {
+	Prefix string
+	count  int
+}
+
+//Invoke invokes `args` on `stub` with the `transient` entries under the next transaction ID, and returns the payload & message of the response
+func (i *TxInvoker) Invoke(stub *shim.MockStub, transient map[string][]byte, args ...string) (string, string) {
+	i.count++
+	stub.TransientMap = map[string][]byte{}
+	for key, value := range transient {
+		stub.TransientMap[key] = value
+	}
+	input := [][]byte{}
+	for _, arg := range args {
+		input = append(input, []byte(arg))
+	}
+	res := stub.MockInvoke(i.TxID(), input)
+	return string(res.Payload), res.Message
+}
+
+//TxID returns the ID of the last transaction invoked
+func (i *TxInvoker) TxID() string {
+	return fmt.Sprintf("%v-%02d", i.Prefix, i.count)
+}